@@ -0,0 +1,162 @@
+package bundle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/paths"
+)
+
+// Dir is the folder, in %PRGS2%, where bundles are extracted on import
+const Dir = "_bundle"
+
+// Export writes in one archive (zip or tar.gz, from its name) the configs,
+// the lockfile and the cached archives of the locked programs of a root,
+// all of them if no name is given, for an offline root to Import them.
+// The lockfile is exported whole: Import keeps only the entries
+// whose archive is in the bundle.
+func Export(root, file *paths.Path, names []string) ([]*lockfile.Entry, error) {
+	format := paths.FormatOf(file)
+	if format == "" {
+		return nil, fmt.Errorf("unsupported bundle format for '%v' (.zip or .tar.gz)", file)
+	}
+	lf, err := lockfile.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		names = lf.Names()
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no program in lockfile '%v' (see 'senvgo lock')", lf)
+	}
+	c := cache.Default(root)
+	include := []string{"configs/**", lockfile.FileName}
+	res := []*lockfile.Entry{}
+	for _, name := range names {
+		e := lf.Get(name)
+		if e == nil {
+			return nil, fmt.Errorf("'%s' is not in lockfile '%v' (see 'senvgo lock')", name, lf)
+		}
+		if !c.Folder(name).Add(e.Archive).Exists() {
+			return nil, fmt.Errorf("archive '%s' of '%s' not in cache '%v'", e.Archive, name, c.Folder(name))
+		}
+		include = append(include, cache.Dir+"/"+globEscape(name)+"/"+globEscape(e.Archive))
+		res = append(res, e)
+	}
+	if _, err = root.Compress(file, format, &paths.CompressOptions{Include: include}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// globEscape escapes the glob characters of a name (see paths.Glob)
+func globEscape(name string) string {
+	for _, c := range []string{`\`, "*", "?", "["} {
+		name = strings.Replace(name, c, `\`+c, -1)
+	}
+	return name
+}
+
+// Import extracts a bundle (see Export) in a root: the checksum of each
+// archive is verified against the bundle lockfile before any archive is
+// added to the cache. Then the bundle configs replace the ones with the same
+// name, and the imported entries are set in the root lockfile,
+// for 'install --locked' to install them with no network access.
+func Import(root, file *paths.Path) ([]*lockfile.Entry, error) {
+	tmp := root.Add(Dir).Add(strconv.FormatInt(time.Now().UnixNano(), 36)).SetDir()
+	defer func() {
+		if err := tmp.Purge(); err != nil {
+			godbg.Pdbgf("Unable to clean '%v': %v", tmp, err)
+		}
+		root.Add(Dir).Remove()
+	}()
+	if err := file.Extract(tmp); err != nil {
+		return nil, fmt.Errorf("unable to extract bundle '%v': %v", file, err)
+	}
+	blf, err := lockfile.Load(tmp)
+	if err != nil {
+		return nil, err
+	}
+	bcache := cache.New(tmp.Add(cache.Dir).SetDir())
+	res := []*lockfile.Entry{}
+	for _, name := range blf.Names() {
+		e := blf.Get(name)
+		archive := bcache.Folder(name).Add(e.Archive)
+		if !archive.Exists() {
+			godbg.Pdbgf("'%s' not in bundle '%v': archive '%s' not found", name, file, e.Archive)
+			continue
+		}
+		if err = verify(archive, e); err != nil {
+			return nil, fmt.Errorf("invalid bundle '%v': %v", file, err)
+		}
+		res = append(res, e)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no locked archive in bundle '%v'", file)
+	}
+	c := cache.Default(root)
+	for _, e := range res {
+		cached, err := c.Add(e.Name, bcache.Folder(e.Name).Add(e.Archive))
+		if err != nil {
+			return nil, err
+		}
+		// an archive already cached must be the locked one
+		if err = verify(cached, e); err != nil {
+			return nil, err
+		}
+	}
+	if err = importConfigs(tmp.Add("configs").SetDir(), root.Add("configs").SetDir()); err != nil {
+		return nil, err
+	}
+	lf, err := lockfile.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range res {
+		lf.Set(e)
+	}
+	return res, lf.Save()
+}
+
+// verify checks the checksum of an archive is the locked one
+func verify(archive *paths.Path, e *lockfile.Entry) error {
+	sum, err := lockfile.Checksum(archive)
+	if err != nil {
+		return fmt.Errorf("unable to checksum '%v': %v", archive, err)
+	}
+	if sum != e.Checksum {
+		return fmt.Errorf("checksum mismatch for '%s' of '%s': '%s' instead of the locked '%s'", e.Archive, e.Name, sum, e.Checksum)
+	}
+	return nil
+}
+
+// importConfigs moves the config files of a bundle in the configs folder
+func importConfigs(from, to *paths.Path) error {
+	if !from.Exists() {
+		return nil
+	}
+	if err := to.Mkdir(); err != nil {
+		return err
+	}
+	for _, fi := range from.GetFiles("") {
+		if fi.IsDir() {
+			continue
+		}
+		dst := to.Add(fi.Name())
+		if dst.Exists() {
+			if err := dst.Remove(); err != nil {
+				return err
+			}
+		}
+		if err := from.Add(fi.Name()).Rename(dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
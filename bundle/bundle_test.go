@@ -0,0 +1,100 @@
+package bundle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// locked caches an archive for a program, and locks the program to it
+func locked(root *paths.Path, name, archive, content string) {
+	folder := root.Add(cache.Dir).Add(name).SetDir()
+	folder.Mkdir()
+	ioutil.WriteFile(folder.Add(archive).String(), []byte(content), 0644)
+	lf, _ := lockfile.Load(root)
+	sum, _ := lockfile.Checksum(folder.Add(archive))
+	lf.Set(&lockfile.Entry{Name: name, Archive: archive, Folder: name + "-1.0", Version: "1.0", Checksum: sum})
+	lf.Save()
+}
+
+func TestBundle(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "senvgo_bundle")
+	defer os.RemoveAll(dir)
+	src := paths.NewPathDir(filepath.Join(dir, "src"))
+	dst := paths.NewPathDir(filepath.Join(dir, "dst"))
+	src.Mkdir()
+	dst.Mkdir()
+	src.Add("configs").SetDir().Mkdir()
+	ioutil.WriteFile(src.Add("configs").Add("go").String(), []byte("[go]\n  test bin/go.exe\n"), 0644)
+	locked(src, "go", "go1.0.zip", "go archive")
+	locked(src, "git", "git[1.0].zip", "git archive")
+	locked(src, "svn", "svn-1.0.zip", "svn archive")
+	bundle := paths.NewPath(filepath.Join(dir, "prgs.zip"))
+
+	Convey("Configs, lockfile and locked archives are exported in a bundle", t, func() {
+		SetBuffers(nil)
+		entries, err := Export(src, bundle, []string{"go", "git"})
+		So(err, ShouldBeNil)
+		So(len(entries), ShouldEqual, 2)
+		list, _ := bundle.List()
+		names := []string{}
+		for _, e := range list {
+			if !e.IsDir {
+				names = append(names, e.Name)
+			}
+		}
+		So(names, ShouldResemble, []string{"_cache/git/git[1.0].zip", "_cache/go/go1.0.zip", "configs/go", lockfile.FileName})
+
+		Convey("A bundle seeds the cache, configs and lockfile of a root", func() {
+			entries, err := Import(dst, bundle)
+			So(err, ShouldBeNil)
+			So(len(entries), ShouldEqual, 2)
+			So(dst.Add(cache.Dir).Add("go").Add("go1.0.zip").Exists(), ShouldBeTrue)
+			So(dst.Add(cache.Dir).Add("git").Add("git[1.0].zip").Exists(), ShouldBeTrue)
+			So(dst.Add("configs").Add("go").Exists(), ShouldBeTrue)
+			So(dst.Add(Dir).Exists(), ShouldBeFalse)
+			lf, _ := lockfile.Load(dst)
+			So(lf.Names(), ShouldResemble, []string{"git", "go"})
+			So(ErrString(), ShouldContainSubstring, "'svn' not in bundle")
+		})
+	})
+
+	Convey("A bundle archive must match its lockfile checksum", t, func() {
+		SetBuffers(nil)
+		ioutil.WriteFile(src.Add(cache.Dir).Add("svn").Add("svn-1.0.zip").String(), []byte("tampered"), 0644)
+		tampered := paths.NewPath(filepath.Join(dir, "svn.tar.gz"))
+		_, err := Export(src, tampered, []string{"svn"})
+		So(err, ShouldBeNil)
+		_, err = Import(dst, tampered)
+		So(err.Error(), ShouldStartWith, "invalid bundle '"+tampered.String()+"': checksum mismatch for 'svn-1.0.zip' of 'svn': ")
+		So(dst.Add(cache.Dir).Add("svn").Exists(), ShouldBeFalse)
+	})
+
+	Convey("Bundle errors are reported", t, func() {
+		SetBuffers(nil)
+		_, err := Export(src, paths.NewPath(filepath.Join(dir, "prgs.7z")), nil)
+		So(err.Error(), ShouldStartWith, "unsupported bundle format for '")
+		_, err = Export(src, bundle, []string{"hg"})
+		So(err.Error(), ShouldStartWith, "'hg' is not in lockfile '")
+		_, err = Export(dst.Add("empty").SetDir(), bundle, nil)
+		So(err.Error(), ShouldStartWith, "no program in lockfile '")
+		os.Remove(src.Add(cache.Dir).Add("go").Add("go1.0.zip").String())
+		_, err = Export(src, bundle, []string{"go"})
+		So(err.Error(), ShouldStartWith, "archive 'go1.0.zip' of 'go' not in cache '")
+
+		_, err = Import(dst, paths.NewPath(filepath.Join(dir, "none.zip")))
+		So(err.Error(), ShouldStartWith, "unable to extract bundle '")
+		empty := paths.NewPath(filepath.Join(dir, "empty.zip"))
+		src.Add("configs").Compress(empty, paths.Zip, nil)
+		_, err = Import(dst, empty)
+		So(err.Error(), ShouldEqual, "no locked archive in bundle '"+empty.String()+"'")
+	})
+}
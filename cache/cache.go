@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/VonC/senvgo/paths"
+)
+
+// Cache is a disk cache of archives, with one folder per program
+type Cache struct {
+	dir *paths.Path
+}
+
+// Dir is the cache folder name, in %PRGS2%
+const Dir = "_cache"
+
+// New returns a cache in a folder, which is created when needed
+func New(dir *paths.Path) *Cache {
+	return &Cache{dir: dir.SetDir()}
+}
+
+// Default returns the cache of a programs root (%PRGS2%/_cache)
+func Default(root *paths.Path) *Cache {
+	return New(root.Add(Dir))
+}
+
+// Folder returns the cache folder of a program
+func (c *Cache) Folder(name string) *paths.Path {
+	return c.dir.Add(name).SetDir()
+}
+
+// Add copies a file in the cache folder of a program,
+// unless it already is there, and returns its cached path.
+func (c *Cache) Add(name string, file *paths.Path) (*paths.Path, error) {
+	folder := c.Folder(name)
+	cached := folder.Add(file.Base())
+	if cached.Exists() {
+		return cached, nil
+	}
+	if err := folder.Mkdir(); err != nil {
+		return nil, err
+	}
+	if err := file.Copy(cached); err != nil {
+		cached.Remove()
+		return nil, fmt.Errorf("unable to cache '%v' for '%s': %v", file, name, err)
+	}
+	return cached, nil
+}
+
+// Portable returns the portable archive built from an installer (.exe, .msi)
+// archive of a program, nil if there is none in the cache.
+func (c *Cache) Portable(name, archive string) *paths.Path {
+	base := paths.NewPath(archive).Base()
+	ext := strings.ToLower(filepath.Ext(base))
+	if ext != ".exe" && ext != ".msi" {
+		return nil
+	}
+	base = base[:len(base)-len(ext)]
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		p := c.Folder(name).Add(base + ext)
+		if p.Exists() {
+			return p
+		}
+	}
+	return nil
+}
+
+// Get returns the archive to install for a program archive:
+// its portable archive if there is one, else the cached archive itself,
+// nil if not in cache.
+func (c *Cache) Get(name, archive string) *paths.Path {
+	if p := c.Portable(name, archive); p != nil {
+		return p
+	}
+	p := c.Folder(name).Add(paths.NewPath(archive).Base())
+	if p.Exists() {
+		return p
+	}
+	return nil
+}
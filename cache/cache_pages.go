@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VonC/senvgo/paths"
+)
+
+// ErrNotInCache is the error of a page or an archive which is needed
+// but is not in cache (and cannot, or must not, be downloaded)
+var ErrNotInCache = errors.New("not in cache")
+
+// Pages is the number of pages of an url kept in the cache of a program
+var Pages = 3
+
+var fnow func() time.Time
+
+func init() {
+	fnow = time.Now
+}
+
+// pagePrefix is the name prefix of the cached pages of an url,
+// in the cache folder of a program: '<name>_<url sha1>_'
+func pagePrefix(name, url string) string {
+	sum := sha1.Sum([]byte(url))
+	return name + "_" + hex.EncodeToString(sum[:]) + "_"
+}
+
+// pages returns the cached pages of an url, oldest first:
+// they are named after the time they were cached.
+func (c *Cache) pages(name, url string) []*paths.Path {
+	prefix := pagePrefix(name, url)
+	names := []string{}
+	for _, fi := range c.Folder(name).GetFiles("") {
+		if !fi.IsDir() && strings.HasPrefix(fi.Name(), prefix) {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	res := []*paths.Path{}
+	for _, n := range names {
+		res = append(res, c.Folder(name).Add(n))
+	}
+	return res
+}
+
+// Page returns the newest cached page of an url for a program,
+// nil if there is none.
+func (c *Cache) Page(name, url string) *paths.Path {
+	pages := c.pages(name, url)
+	if len(pages) == 0 {
+		return nil
+	}
+	return pages[len(pages)-1]
+}
+
+// AddPage caches the content of an url page for a program,
+// as '<name>_<url sha1>_<date>_<time>', unless its newest cached page
+// has the same content, and returns its cached page.
+// Only the Pages newest pages of the url are kept.
+func (c *Cache) AddPage(name, url string, content []byte) (*paths.Path, error) {
+	if last := c.Page(name, url); last != nil {
+		if data, err := last.Content(); err == nil && data == string(content) {
+			return last, nil
+		}
+	}
+	folder := c.Folder(name)
+	if err := folder.Mkdir(); err != nil {
+		return nil, err
+	}
+	page := folder.Add(pagePrefix(name, url) + fnow().Format("20060102_150405.000000000"))
+	if err := page.WriteFile(content); err != nil {
+		page.Remove()
+		return nil, err
+	}
+	pages := c.pages(name, url)
+	for i := 0; i < len(pages)-Pages; i++ {
+		pages[i].Remove()
+	}
+	return page, nil
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPages(t *testing.T) {
+
+	Convey("Pages of an url are cached per program, newest last", t, func() {
+		SetBuffers(nil)
+		c := Default(paths.NewPathFS(paths.NewMemFS(), filepath.FromSlash("/prgs/")))
+		now := time.Date(2015, 3, 14, 9, 26, 53, 0, time.UTC)
+		fnow = func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}
+		defer func() { fnow = time.Now }()
+		url := "https://github.com/msysgit/msysgit/releases"
+		So(c.Page("git", url), ShouldBeNil)
+		p1, err := c.AddPage("git", url, []byte("v1"))
+		So(err, ShouldBeNil)
+		So(p1.Base(), ShouldStartWith, "git_")
+		So(p1.Base(), ShouldEndWith, "_20150314_092654.000000000")
+		So(c.Page("git", url).String(), ShouldEqual, p1.String())
+		So(c.Page("git", url+"/latest"), ShouldBeNil)
+
+		same, _ := c.AddPage("git", url, []byte("v1"))
+		So(same.String(), ShouldEqual, p1.String())
+		for _, v := range []string{"v2", "v3", "v4"} {
+			c.AddPage("git", url, []byte(v))
+		}
+		So(c.Page("git", url).FileContent(), ShouldEqual, "v4")
+		So(len(c.pages("git", url)), ShouldEqual, Pages)
+		So(p1.Exists(), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCache(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "cache")
+	defer os.RemoveAll(dir)
+	c := Default(paths.NewPathDir(dir))
+	download := filepath.Join(dir, "python-2.7.9.amd64.msi")
+	ioutil.WriteFile(download, []byte("msi"), 0644)
+
+	Convey("Archives are cached per program", t, func() {
+		SetBuffers(nil)
+		So(c.Folder("python2").String(), ShouldEqual, paths.NewPathDir(filepath.Join(dir, Dir, "python2")).String())
+		So(c.Get("python2", "python-2.7.9.amd64.msi"), ShouldBeNil)
+		cached, err := c.Add("python2", paths.NewPath(download))
+		So(err, ShouldBeNil)
+		So(cached.FileContent(), ShouldEqual, "msi")
+		So(c.Get("python2", "python-2.7.9.amd64.msi").String(), ShouldEqual, cached.String())
+		again, err := c.Add("python2", paths.NewPath(download))
+		So(err, ShouldBeNil)
+		So(again.String(), ShouldEqual, cached.String())
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("A portable archive is preferred to its installer", func() {
+			So(c.Portable("python2", "python-2.7.9.amd64.msi"), ShouldBeNil)
+			zip := filepath.Join(dir, "python-2.7.9.amd64.zip")
+			ioutil.WriteFile(zip, []byte("zip"), 0644)
+			portable, err := c.Add("python2", paths.NewPath(zip))
+			So(err, ShouldBeNil)
+			So(c.Portable("python2", "python-2.7.9.amd64.msi").String(), ShouldEqual, portable.String())
+			So(c.Get("python2", "python-2.7.9.amd64.msi").String(), ShouldEqual, portable.String())
+			So(c.Portable("python2", "python-2.7.9.amd64.zip"), ShouldBeNil)
+		})
+
+		Convey("A missing file cannot be cached", func() {
+			_, err := c.Add("python2", paths.NewPath(filepath.Join(dir, "none.exe")))
+			So(err.Error(), ShouldStartWith, "unable to cache")
+			So(c.Folder("python2").Add("none.exe").Exists(), ShouldBeFalse)
+		})
+	})
+
+	Convey("A cache can be on any FS", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+		download := root.Add("go1.4.2.windows-amd64.zip")
+		So(root.Mkdir(), ShouldBeNil)
+		f, _ := download.OpenFile(false)
+		f.Write([]byte("zip"))
+		f.Close()
+		c := Default(root)
+		fs.Fail("write", c.Folder("go").Add(download.Base()).String(), os.ErrPermission)
+		_, err := c.Add("go", download)
+		So(err.Error(), ShouldStartWith, "unable to cache")
+		So(err.Error(), ShouldContainSubstring, "(permission denied)")
+		fs.Fail("write", c.Folder("go").Add(download.Base()).String(), nil)
+		cached, err := c.Add("go", download)
+		So(err, ShouldBeNil)
+		So(cached.FileContent(), ShouldEqual, "zip")
+		So(NoOutput(), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,134 @@
+package cmds
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/VonC/senvgo/paths"
+)
+
+// Vars are the values of a Template placeholders:
+//   - '@FILE@': File (an archive to install, or an uninstaller)
+//   - '@DEST@': Dest (the installation folder)
+//   - '@FILENS@', '@DESTNS@': the same, with their non-substed path
+type Vars struct {
+	File *paths.Path
+	Dest *paths.Path
+}
+
+// Template is a command ('invoke', 'uninstcmd') split in arguments,
+// each one possibly including placeholders.
+type Template struct {
+	raw  string
+	args []string
+}
+
+// Executor runs a command, given as argv, returning its combined output
+type Executor func(argv []string) ([]byte, error)
+
+// OS runs a command directly, without any shell interpretation
+var OS Executor = func(argv []string) ([]byte, error) {
+	return exec.Command(argv[0], argv[1:]...).CombinedOutput()
+}
+
+var placeholderRx = regexp.MustCompile(`@[A-Z]+@`)
+
+var placeholders = map[string]bool{"@FILE@": true, "@FILENS@": true, "@DEST@": true, "@DESTNS@": true}
+
+// operators are the shell characters a command cannot use unquoted:
+// it is run without shell, so they would be passed as arguments.
+const operators = "&|<>"
+
+// builtins are cmd commands, which are not programs to run
+var builtins = map[string]bool{
+	"assoc": true, "call": true, "cd": true, "chdir": true, "cls": true, "copy": true,
+	"del": true, "dir": true, "echo": true, "erase": true, "for": true, "if": true,
+	"md": true, "mkdir": true, "mklink": true, "move": true, "rd": true, "ren": true,
+	"rename": true, "rmdir": true, "set": true, "start": true, "type": true,
+}
+
+// Parse splits a command in arguments, separated by spaces.
+// Double quotes group an argument with spaces, and are removed.
+// Within double quotes, no character is special.
+// Outside, '\' or '%' are kept as is, but shell operators ('&', '|', '<', '>')
+// are rejected, as is a cmd builtin ('mkdir', 'copy', ...): a command is run
+// without shell (use a Go installer, 'go: <name>', instead).
+func Parse(cmd string) (*Template, error) {
+	t := &Template{raw: cmd}
+	arg, inArg, quoted := "", false, false
+	for _, r := range cmd {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case unicode.IsSpace(r) && !quoted:
+			if inArg {
+				t.args = append(t.args, arg)
+			}
+			arg, inArg = "", false
+		case strings.ContainsRune(operators, r) && !quoted:
+			return nil, fmt.Errorf("shell operator '%c' in '%s': commands are run without shell", r, cmd)
+		default:
+			arg = arg + string(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated '\"' in '%s'", cmd)
+	}
+	if inArg {
+		t.args = append(t.args, arg)
+	}
+	if len(t.args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	if name := strings.ToLower(t.args[0]); builtins[name] {
+		return nil, fmt.Errorf("cmd builtin '%s' in '%s': commands are run without shell", name, cmd)
+	}
+	for _, p := range placeholderRx.FindAllString(cmd, -1) {
+		if !placeholders[p] {
+			return nil, fmt.Errorf("unknown placeholder '%s' in '%s'", p, cmd)
+		}
+	}
+	return t, nil
+}
+
+// Argv returns the arguments of the command, with their placeholders replaced.
+// An argument stays one argument, whatever its value (spaces, '&', ...).
+func (t *Template) Argv(v *Vars) []string {
+	values := map[string]string{}
+	if v.File != nil {
+		values["@FILE@"] = v.File.String()
+		values["@FILENS@"] = v.File.NoSubst().String()
+	}
+	if v.Dest != nil {
+		values["@DEST@"] = v.Dest.String()
+		values["@DESTNS@"] = v.Dest.NoSubst().String()
+	}
+	res := []string{}
+	for _, arg := range t.args {
+		res = append(res, placeholderRx.ReplaceAllStringFunc(arg, func(p string) string {
+			if value, ok := values[p]; ok {
+				return value
+			}
+			return p
+		}))
+	}
+	return res
+}
+
+// Run runs the command with its placeholders replaced, through an executor.
+func (t *Template) Run(v *Vars, exec Executor) error {
+	argv := t.Argv(v)
+	if out, err := exec(argv); err != nil {
+		return fmt.Errorf("'%s' failed: %v\n%s", strings.Join(argv, "' '"), err, out)
+	}
+	return nil
+}
+
+func (t *Template) String() string {
+	return t.raw
+}
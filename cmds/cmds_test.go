@@ -0,0 +1,75 @@
+package cmds
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var argvs [][]string
+
+func testExecutor(argv []string) ([]byte, error) {
+	argvs = append(argvs, argv)
+	if argv[0] == "fail" {
+		return []byte("denied"), fmt.Errorf("exit status 2")
+	}
+	return nil, nil
+}
+
+func TestCmds(t *testing.T) {
+
+	file := paths.NewPath("/tmp/a & b/node-v0.12.0-x64.msi")
+	dest := paths.NewPathDir("/prgs/node/node v0.12.0")
+	vars := &Vars{File: file, Dest: dest}
+
+	Convey("A command is split in arguments", t, func() {
+		SetBuffers(nil)
+		tpl, err := Parse(`C:\WINDOWS\system32\msiexec.exe  /x "@FILENS@" /l @DESTNS@_uninst.log /qn`)
+		So(err, ShouldBeNil)
+		So(tpl.args, ShouldResemble, []string{`C:\WINDOWS\system32\msiexec.exe`, "/x", "@FILENS@", "/l", "@DESTNS@_uninst.log", "/qn"})
+		tpl, err = Parse(`"C:\Program Files\x.exe" "" a"b c"d`)
+		So(err, ShouldBeNil)
+		So(tpl.args, ShouldResemble, []string{`C:\Program Files\x.exe`, "", "ab cd"})
+
+		Convey("Placeholders are replaced per argument", func() {
+			tpl, _ := Parse(`@FILE@ /S /D=@DEST@ /LOG=@DESTNS@..\uninst.log "a & b"`)
+			argv := tpl.Argv(vars)
+			So(argv, ShouldResemble, []string{file.String(), "/S", "/D=" + dest.String(),
+				`/LOG=` + dest.NoSubst().String() + `..\uninst.log`, "a & b"})
+			So(len(argv), ShouldEqual, 5)
+			if runtime.GOOS != "windows" {
+				So(argv[0], ShouldEqual, "/tmp/a & b/node-v0.12.0-x64.msi")
+			}
+		})
+
+		Convey("Invalid commands are rejected", func() {
+			_, err := Parse("  ")
+			So(err.Error(), ShouldEqual, "empty command")
+			_, err = Parse(`"a b`)
+			So(err.Error(), ShouldEqual, `unterminated '"' in '"a b'`)
+			_, err = Parse(`x @FOLDER@`)
+			So(err.Error(), ShouldEqual, "unknown placeholder '@FOLDER@' in 'x @FOLDER@'")
+			_, err = Parse(`setup.exe /S & del @FILENS@`)
+			So(err.Error(), ShouldEqual, "shell operator '&' in 'setup.exe /S & del @FILENS@': commands are run without shell")
+			_, err = Parse(`setup.exe /S > log`)
+			So(err.Error(), ShouldStartWith, "shell operator '>' in ")
+			_, err = Parse(`MKDIR @DESTNS@`)
+			So(err.Error(), ShouldEqual, "cmd builtin 'mkdir' in 'MKDIR @DESTNS@': commands are run without shell")
+		})
+
+		Convey("A command runs through an executor", func() {
+			argvs = nil
+			tpl, _ := Parse(`setup.exe /D=@DEST@`)
+			So(tpl.Run(vars, testExecutor), ShouldBeNil)
+			So(argvs, ShouldResemble, [][]string{{"setup.exe", "/D=" + dest.String()}})
+			tpl, _ = Parse(`fail @FILE@`)
+			err := tpl.Run(vars, testExecutor)
+			So(err.Error(), ShouldEqual, "'fail' '"+file.String()+"' failed: exit status 2\ndenied")
+			So(tpl.String(), ShouldEqual, "fail @FILE@")
+		})
+	})
+}
@@ -0,0 +1,120 @@
+package envs
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// PathList is an immutable list of directories, as found in PATH.
+// It has no empty entry and no duplicate: on Windows, two entries
+// differing only by their case (or a trailing separator) are the same.
+// Methods changing a PathList return a new one.
+type PathList struct {
+	dirs []string
+}
+
+// PathListSeparator separates entries of PATH: ';' on Windows, ':' elsewhere
+var PathListSeparator = string(os.PathListSeparator)
+
+var caseInsensitive = runtime.GOOS == "windows"
+
+var fisdir func(dir string) bool
+
+func ifisdir(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
+
+func init() {
+	fisdir = ifisdir
+}
+
+// Path returns the current PATH environment variable as a PathList.
+// It is read again on each call, and so reflects any change made to PATH.
+func Path() *PathList {
+	return ParsePathList(envGetterFunc("PATH"))
+}
+
+// ParsePathList splits a PATH value with the OS list separator.
+func ParsePathList(path string) *PathList {
+	return NewPathList(strings.Split(path, PathListSeparator)...)
+}
+
+// NewPathList builds a PathList from directories, dropping empty or duplicate ones.
+func NewPathList(dirs ...string) *PathList {
+	return (&PathList{}).Add(dirs...)
+}
+
+// Add returns a new PathList with dirs appended, if not already present.
+func (pl *PathList) Add(dirs ...string) *PathList {
+	res := &PathList{dirs: append([]string{}, pl.dirs...)}
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" || res.Contains(dir) {
+			continue
+		}
+		res.dirs = append(res.dirs, dir)
+	}
+	return res
+}
+
+// Remove returns a new PathList without dirs.
+func (pl *PathList) Remove(dirs ...string) *PathList {
+	removed := NewPathList(dirs...)
+	res := &PathList{}
+	for _, dir := range pl.dirs {
+		if !removed.Contains(dir) {
+			res.dirs = append(res.dirs, dir)
+		}
+	}
+	return res
+}
+
+// Contains checks if a directory is part of the PathList.
+func (pl *PathList) Contains(dir string) bool {
+	key := pathKey(dir)
+	for _, d := range pl.dirs {
+		if pathKey(d) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Dirs returns a copy of the PathList directories, in order.
+func (pl *PathList) Dirs() []string {
+	return append([]string{}, pl.dirs...)
+}
+
+// Len returns the number of directories of the PathList.
+func (pl *PathList) Len() int {
+	return len(pl.dirs)
+}
+
+// Missing returns the directories of the PathList which do not exist.
+func (pl *PathList) Missing() []string {
+	res := []string{}
+	for _, dir := range pl.dirs {
+		if !fisdir(dir) {
+			res = append(res, dir)
+		}
+	}
+	return res
+}
+
+// String renders the PathList as a PATH value, with the OS list separator.
+func (pl *PathList) String() string {
+	return strings.Join(pl.dirs, PathListSeparator)
+}
+
+func pathKey(dir string) string {
+	dir = strings.TrimSpace(dir)
+	if len(dir) > 1 {
+		dir = strings.TrimRight(dir, `/\`)
+	}
+	if caseInsensitive {
+		dir = strings.ToLower(dir)
+	}
+	return dir
+}
@@ -0,0 +1,77 @@
+package envs
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testPath = ""
+
+func testPathEnvGetter(key string) string {
+	if key == "PATH" {
+		return testPath
+	}
+	return ""
+}
+
+func testfisdir(dir string) bool {
+	return dir != "b"
+}
+
+func TestPathList(t *testing.T) {
+
+	Convey("PATH is parsed with the OS list separator", t, func() {
+		SetBuffers(nil)
+		envGetterFunc = testPathEnvGetter
+		sep := PathListSeparator
+		PathListSeparator = ":"
+		testPath = "a:b: :a:c"
+		pl := Path()
+		So(pl.Dirs(), ShouldResemble, []string{"a", "b", "c"})
+		So(pl.String(), ShouldEqual, "a:b:c")
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("PATH is read again after a change", func() {
+			testPath = "d"
+			So(Path().Dirs(), ShouldResemble, []string{"d"})
+		})
+
+		Convey("A PathList is never modified in place", func() {
+			pl2 := pl.Add("d", "a").Remove("b")
+			So(pl2.String(), ShouldEqual, "a:c:d")
+			So(pl.String(), ShouldEqual, "a:b:c")
+			dirs := pl.Dirs()
+			dirs[0] = "x"
+			So(pl.Dirs()[0], ShouldEqual, "a")
+			So(NewPathList().Len(), ShouldEqual, 0)
+		})
+
+		Convey("Non-existent directories are flagged", func() {
+			fisdir = testfisdir
+			So(pl.Missing(), ShouldResemble, []string{"b"})
+			fisdir = ifisdir
+			So(NewPathList(os.TempDir(), "xxx_no_dir").Missing(), ShouldResemble, []string{"xxx_no_dir"})
+		})
+
+		PathListSeparator = sep
+		envGetterFunc = os.Getenv
+	})
+
+	Convey("Duplicates are case-insensitive on Windows only", t, func() {
+		SetBuffers(nil)
+		ci := caseInsensitive
+		caseInsensitive = true
+		pl := NewPathList(`C:\Go\bin`, `c:\go\BIN\`, `C:\git`)
+		So(pl.Dirs(), ShouldResemble, []string{`C:\Go\bin`, `C:\git`})
+		So(pl.Contains(`C:\GIT`), ShouldBeTrue)
+		So(pl.Remove(`c:\go\bin`).Dirs(), ShouldResemble, []string{`C:\git`})
+		caseInsensitive = false
+		pl = NewPathList("/usr/bin", "/usr/BIN", "/usr/bin/")
+		So(pl.Dirs(), ShouldResemble, []string{"/usr/bin", "/usr/BIN"})
+		caseInsensitive = ci
+		So(NoOutput(), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,154 @@
+package envs
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+)
+
+// Root is the folder where programs are installed,
+// with the source it was found from.
+type Root struct {
+	Path   *paths.Path
+	Source string
+}
+
+func (r *Root) String() string {
+	return fmt.Sprintf("'%v' (from %s)", r.Path, r.Source)
+}
+
+// ConfName is the name of the optional config file, next to the senvgo binary,
+// which can define the root with a 'root=' line
+const ConfName = "senvgo.conf"
+
+var rootFlag string
+var _root *Root
+
+// SetRootFlag sets the root given on the command line (--root),
+// which takes precedence over any other source.
+func SetRootFlag(root string) {
+	rootFlag = root
+	_root = nil
+}
+
+var fexecutable func() (string, error)
+var fuserhome func() (string, error)
+var fosstat func(name string) (os.FileInfo, error)
+var fwritable func(dir string) error
+
+func ifwritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".senvgo")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func init() {
+	fexecutable = os.Executable
+	fuserhome = os.UserHomeDir
+	fosstat = os.Stat
+	fwritable = ifwritable
+}
+
+// FindRoot returns the root, from the first source defining it:
+//   - the --root flag (see SetRootFlag)
+//   - the PRGS2 environment variable
+//   - a 'root=' line in senvgo.conf, next to the senvgo binary
+//   - the per-user default 'prgs' folder, in the home directory (created if needed)
+//
+// The root must be an existing and writable folder. The result is cached.
+func FindRoot() (*Root, error) {
+	if _root != nil {
+		return _root, nil
+	}
+	root, err := findRoot()
+	if err != nil {
+		return nil, err
+	}
+	if err = root.validate(); err != nil {
+		return nil, err
+	}
+	godbg.Pdbgf("root %v", root)
+	_root = root
+	return _root, nil
+}
+
+func findRoot() (*Root, error) {
+	if rootFlag != "" {
+		return &Root{Path: paths.NewPathDir(rootFlag), Source: "flag --root"}, nil
+	}
+	if r := envGetterFunc(Prgsenvname); r != "" {
+		return &Root{Path: paths.NewPathDir(r), Source: "env " + Prgsenvname}, nil
+	}
+	exe, err := fexecutable()
+	if err == nil {
+		conf := filepath.Join(filepath.Dir(exe), ConfName)
+		r, err := confRoot(conf)
+		if err != nil {
+			return nil, err
+		}
+		if r != "" {
+			if !filepath.IsAbs(r) {
+				r = filepath.Join(filepath.Dir(exe), r)
+			}
+			return &Root{Path: paths.NewPathDir(r), Source: "config " + conf}, nil
+		}
+	}
+	home, err := fuserhome()
+	if err != nil {
+		return nil, fmt.Errorf("no root: --root, %s and %s not set, and no home folder: %v", Prgsenvname, ConfName, err)
+	}
+	root := &Root{Path: paths.NewPathDir(filepath.Join(home, "prgs")), Source: "default"}
+	if _, err := fosstat(root.Path.NoSep().String()); os.IsNotExist(err) {
+		if err = os.MkdirAll(root.Path.NoSep().String(), 0755); err != nil {
+			return nil, fmt.Errorf("root %v: unable to create: %v", root, err)
+		}
+	}
+	return root, nil
+}
+
+// confRoot returns the 'root=' value of a config file,
+// empty if there is no such file or line.
+func confRoot(conf string) (string, error) {
+	f, err := os.Open(conf)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read '%s': %v", conf, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "root=") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "root=")), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+func (r *Root) validate() error {
+	fi, err := fosstat(r.Path.NoSep().String())
+	if os.IsNotExist(err) {
+		return fmt.Errorf("root %v does not exist", r)
+	}
+	if err != nil {
+		return fmt.Errorf("root %v: %v", r, err)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("root %v is not a folder", r)
+	}
+	if err = fwritable(r.Path.NoSep().String()); err != nil {
+		return fmt.Errorf("root %v is not writable: %v", r, err)
+	}
+	return nil
+}
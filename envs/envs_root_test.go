@@ -0,0 +1,91 @@
+package envs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testfwritable(dir string) error {
+	return fmt.Errorf("read-only")
+}
+
+func TestRoot(t *testing.T) {
+
+	tmp, _ := ioutil.TempDir("", "root")
+	defer os.RemoveAll(tmp)
+	exe := filepath.Join(tmp, "bin", "senvgo")
+	home := filepath.Join(tmp, "home")
+	os.MkdirAll(filepath.Join(tmp, "bin"), 0755)
+	os.MkdirAll(filepath.Join(tmp, "conf"), 0755)
+	os.MkdirAll(home, 0755)
+	fexecutable = func() (string, error) { return exe, nil }
+	fuserhome = func() (string, error) { return home, nil }
+	envGetterFunc = testPrgsEnvGetter
+
+	find := func() (*Root, error) {
+		_root = nil
+		return FindRoot()
+	}
+
+	Convey("The root is found from the first defined source", t, func() {
+		SetBuffers(nil)
+
+		Convey("Without any source, a per-user default is created", func() {
+			r, err := find()
+			So(err, ShouldBeNil)
+			So(r.Source, ShouldEqual, "default")
+			So(r.Path.NoSep().String(), ShouldEqual, filepath.Join(home, "prgs"))
+			So(r.Path.Exists(), ShouldBeTrue)
+		})
+
+		Convey("A senvgo.conf next to the binary comes before the default", func() {
+			conf := filepath.Join(tmp, "bin", ConfName)
+			ioutil.WriteFile(conf, []byte("# senvgo\nroot=../conf\n"), 0644)
+			defer os.Remove(conf)
+			r, err := find()
+			So(err, ShouldBeNil)
+			So(r.Source, ShouldEqual, "config "+conf)
+			So(r.Path.NoSep().String(), ShouldEqual, filepath.Join(tmp, "conf"))
+
+			Convey("The env variable comes before senvgo.conf", func() {
+				prgsenvtest = home
+				r, err := find()
+				So(err, ShouldBeNil)
+				So(r.Source, ShouldEqual, "env PRGS2")
+
+				Convey("The --root flag comes first", func() {
+					SetRootFlag(tmp)
+					r, err := find()
+					So(err, ShouldBeNil)
+					So(r.String(), ShouldEqual, "'"+tmp+string(os.PathSeparator)+"' (from flag --root)")
+					SetRootFlag("")
+				})
+				prgsenvtest = ""
+			})
+		})
+
+		Convey("The root must be a writable folder", func() {
+			SetRootFlag(exe)
+			ioutil.WriteFile(exe, []byte("exe"), 0755)
+			_, err := find()
+			So(err.Error(), ShouldEndWith, "(from flag --root) is not a folder")
+			SetRootFlag(tmp)
+			fwritable = testfwritable
+			_, err = find()
+			So(err.Error(), ShouldEndWith, "(from flag --root) is not writable: read-only")
+			fwritable = ifwritable
+			SetRootFlag("")
+		})
+	})
+
+	_root = nil
+	fexecutable = os.Executable
+	fuserhome = os.UserHomeDir
+	envGetterFunc = os.Getenv
+}
@@ -5,89 +5,63 @@ import (
 	"testing"
 
 	. "github.com/VonC/godbg"
-	"github.com/VonC/senvgo/paths"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
-func testEnvGetter(key string) string {
-	return "a;b"
-}
-
-func TestMain(t *testing.T) {
-
-	Convey("Envs can return env variable PATH segments", t, func() {
-		SetBuffers(nil)
-		envGetterFunc = testEnvGetter
-		paths := PathSegments()
-		So(len(paths), ShouldEqual, 2)
-		So(NoOutput(), ShouldBeTrue)
-		envGetterFunc = os.Getenv
-	})
-
-}
-
 func TestPrgsEnv(t *testing.T) {
 
 	Convey("Envs can returns PRGS environment variable", t, func() {
 
-		Convey("Non-set PRGS env variable means panic", func() {
-			SetBuffers(nil)
-			envGetterFunc = testPrgsEnvGetter
-			prgsenvtest = "notset"
-			var p *paths.Path
-			defer func() {
-				recover()
-				So(p, ShouldBeNil)
-				So(OutString(), ShouldBeEmpty)
-				So(ErrString(), ShouldEqualNL, `  [Prgsenv:47] (func.003:49)
-    no env variable 'PRGS2' defined`)
-				envGetterFunc = os.Getenv
-				prgsenvtest = ""
-			}()
-			p = Prgsenv()
-		})
-
 		Convey("Set PRGS env variable means Path dir", func() {
 			SetBuffers(nil)
+			_root = nil
 			envGetterFunc = testPrgsEnvGetter
-			prgsenvtest = "set"
-			p := Prgsenv()
-			So(p, ShouldNotBeNil)
-			So(p.String(), ShouldEqual, `set\`)
+			dir := os.TempDir()
+			prgsenvtest = dir
+			p, err := Prgsenv()
+			So(err, ShouldBeNil)
+			So(p.NoSep().String(), ShouldEqual, dir)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `  [Prgsenv:52] (func.004:57)
-    PRGS2='set\'`)
+			So(ErrString(), ShouldContainSubstring, "(from env PRGS2)")
 			envGetterFunc = os.Getenv
 			prgsenvtest = ""
 		})
 
 		Convey("Set PRGS env get twice means cached", func() {
 			SetBuffers(nil)
-			_prgsenv = nil
+			_root = nil
 			envGetterFunc = testPrgsEnvGetter
-			prgsenvtest = "set"
-			So(_prgsenv, ShouldBeNil)
-			p := Prgsenv()
+			prgsenvtest = os.TempDir()
+			p, _ := Prgsenv()
 			So(p, ShouldNotBeNil)
-			So(p.String(), ShouldEqual, `set\`)
-			p1 := Prgsenv()
+			prgsenvtest = "xxx_no_dir"
+			p1, err := Prgsenv()
+			So(err, ShouldBeNil)
 			So(p, ShouldEqual, p1)
+			_root = nil
 			envGetterFunc = os.Getenv
 			prgsenvtest = ""
 		})
 
+		Convey("Invalid PRGS env variable means error, not panic", func() {
+			SetBuffers(nil)
+			_root = nil
+			envGetterFunc = testPrgsEnvGetter
+			prgsenvtest = "xxx_no_dir"
+			p, err := Prgsenv()
+			So(p, ShouldBeNil)
+			So(err.Error(), ShouldEqual, `root 'xxx_no_dir`+string(os.PathSeparator)+`' (from env PRGS2) does not exist`)
+			envGetterFunc = os.Getenv
+			prgsenvtest = ""
+		})
 	})
-
 }
 
 var prgsenvtest = ""
 
 func testPrgsEnvGetter(key string) string {
-	if prgsenvtest == "notset" {
-		return ""
-	}
-	if prgsenvtest == "set" {
-		return "set"
+	if key == Prgsenvname {
+		return prgsenvtest
 	}
-	return "tbd"
+	return ""
 }
@@ -1,12 +1,26 @@
 package installer
 
 import (
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/paths"
 	"github.com/VonC/senvgo/prgs"
 )
 
+// fprgsenv returns the programs root (see envs.Prgsenv):
+// the installer reads and writes through its FS.
+var fprgsenv func() (*paths.Path, error)
+
+func init() {
+	fprgsenv = envs.Prgsenv
+}
+
 // inst is an program installer
 type inst struct {
-	p prgs.Prg
+	p      prgs.Prg
+	locked *lockfile.Entry
 }
 
 // Inst defines what kind of service a program installer has to provide
@@ -17,6 +31,12 @@ type Inst interface {
 	IsInstalled() bool
 	// HasFailed checks if a program has failed to install locally
 	HasFailed() bool
+	// Uninstall removes an installed program, leaving no trace of it
+	Uninstall() error
+	// Status compares the installed release with the latest available one
+	Status() (*Status, error)
+	// Resolve returns the release Install would install, to lock it
+	Resolve() (*lockfile.Entry, error)
 }
 
 // New returns a new installer instance for a given program
@@ -24,13 +44,26 @@ func New(p prgs.Prg) Inst {
 	return &inst{p: p}
 }
 
+// IsInstalled checks the test file of the program in its 'latest' folder
 func (i *inst) IsInstalled() bool {
-	return false
+	root, err := fprgsenv()
+	if err != nil {
+		return false
+	}
+	return i.hasTest(root.Add(i.p.Dir()).Add("latest").SetDir())
 }
 func (i *inst) HasFailed() bool {
 	return true
 }
 
-func (i *inst) Install() error {
-	return nil
+// record appends an action on the program, with its outcome, to the journal
+// of a programs root. Failing to write the journal does not fail the action.
+func (i *inst) record(root *paths.Path, action, folder, version string, err error) {
+	e := &journal.Event{Program: i.p.Name(), Folder: folder, Version: version, Action: action, Outcome: journal.OK}
+	if err != nil {
+		e.Outcome, e.Error = journal.Failed, err.Error()
+	}
+	if jerr := journal.Open(root).Record(e); jerr != nil {
+		godbg.Pdbgf("%v", jerr)
+	}
 }
@@ -0,0 +1,50 @@
+package installer
+
+import (
+	"fmt"
+
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+)
+
+func init() {
+	Register("InstallExe", installExe)
+	Register("InstallKitty", installKitty)
+}
+
+// installExe installs a program which is a single .exe,
+// by copying it in the program folder as its test file ('kitty.exe').
+func installExe(folder, archive *paths.Path, p prgs.Prg) error {
+	if p.Test() == "" {
+		return fmt.Errorf("no test file to copy '%v' as", archive)
+	}
+	if err := folder.Mkdir(); err != nil {
+		return err
+	}
+	return archive.Copy(folder.Add(p.Test()))
+}
+
+// installKitty installs kitty.exe, with the sessions and the kitty.ini
+// shared by all its versions, in the program folder:
+// 'Sessions' is linked, 'kitty.ini' is copied if there is one.
+func installKitty(folder, archive *paths.Path, p prgs.Prg) error {
+	if err := installExe(folder, archive, p); err != nil {
+		return err
+	}
+	root, err := fprgsenv()
+	if err != nil {
+		return err
+	}
+	shared := root.Add(p.Dir()).SetDir()
+	sessions := shared.Add("Sessions").SetDir()
+	if err = sessions.Mkdir(); err != nil {
+		return err
+	}
+	if err = folder.Add("Sessions").NoSep().Symlink(sessions.NoSep().String()); err != nil {
+		return fmt.Errorf("unable to link Sessions of '%s': %v", p.Name(), err)
+	}
+	if ini := shared.Add("kitty.ini"); ini.Exists() {
+		return ini.Copy(folder.Add("kitty.ini"))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package installer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExe(t *testing.T) {
+
+	root, _ := ioutil.TempDir("", "inst_exe")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+
+	Convey("A single .exe is installed by copying it as the test file", t, func() {
+		SetBuffers(nil)
+		cached(root, "wintab", "wintab_2.5.exe", 0, []byte("exe"))
+		p := &testUninstPrg{name: "wintab", values: map[string]string{"test": "WindowTabs.exe", "invoke": "go: InstallExe"}}
+		So(New(p).Install(), ShouldBeNil)
+		data, _ := ioutil.ReadFile(filepath.Join(root, "wintab", "wintab_2.5", "WindowTabs.exe"))
+		So(string(data), ShouldEqual, "exe")
+		So(exists(root, "_cache", "wintab", "wintab_2.5.zip"), ShouldBeFalse)
+
+		p = &testUninstPrg{name: "wintab", values: map[string]string{"invoke": "go: InstallExe"}}
+		err := installExe(nil, paths.NewPath(filepath.Join(root, "_cache", "wintab", "wintab_2.5.exe")), p)
+		So(err.Error(), ShouldStartWith, "no test file to copy '")
+	})
+
+	Convey("kitty shares its sessions and kitty.ini between versions", t, func() {
+		SetBuffers(nil)
+		cached(root, "kitty", "kitty_0.64.exe", 0, []byte("kitty"))
+		os.MkdirAll(filepath.Join(root, "kitty"), 0755)
+		ioutil.WriteFile(filepath.Join(root, "kitty", "kitty.ini"), []byte("[KiTTY]"), 0644)
+		p := &testUninstPrg{name: "kitty", values: map[string]string{"test": "kitty.exe", "invoke": "go: InstallKitty"}}
+		So(New(p).Install(), ShouldBeNil)
+		So(exists(root, "kitty", "kitty_0.64", "kitty.exe"), ShouldBeTrue)
+		So(exists(root, "kitty", "kitty_0.64", "kitty.ini"), ShouldBeTrue)
+		ioutil.WriteFile(filepath.Join(root, "kitty", "kitty_0.64", "Sessions", "Default"), []byte("s"), 0644)
+		So(exists(root, "kitty", "Sessions", "Default"), ShouldBeTrue)
+
+		cached(root, "kitty", "kitty_0.65.exe", -time.Hour, []byte("kitty"))
+		So(New(p).Install(), ShouldBeNil)
+		So(exists(root, "kitty", "kitty_0.65", "Sessions", "Default"), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,38 @@
+package installer
+
+import (
+	"fmt"
+
+	"github.com/VonC/senvgo/paths"
+)
+
+// extract uncompresses an archive in the version folder of a program,
+// flattening its single top-level folder unless 'strip false',
+// then checks its test file is where IsInstalled will look for it.
+func (i *inst) extract(archive, folder *paths.Path) error {
+	strip := i.p.Value("strip") != "false"
+	top, err := archive.Uncompress(folder, strip)
+	if err != nil {
+		return err
+	}
+	if i.hasTest(folder) {
+		return nil
+	}
+	hint := ""
+	switch {
+	case top != "" && strip:
+		hint = fmt.Sprintf(" (top folder '%s' stripped: 'strip false'?)", top)
+	case top != "":
+		hint = fmt.Sprintf(" (top folder '%s' kept: 'strip true'?)", top)
+	}
+	return fmt.Errorf("test file '%s' not found in '%v' after extracting '%v'%s", i.p.Test(), folder, archive, hint)
+}
+
+// hasTest checks if the test file of a program is in a folder
+// (no test file: the folder only needs to exist)
+func (i *inst) hasTest(folder *paths.Path) bool {
+	if i.p.Test() == "" {
+		return folder.NoSep().Exists()
+	}
+	return folder.Add(i.p.Test()).Exists()
+}
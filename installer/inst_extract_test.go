@@ -0,0 +1,71 @@
+package installer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExtract(t *testing.T) {
+
+	root, _ := ioutil.TempDir("", "extract")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+	wrapped := paths.NewPath(filepath.Join(root, "PortableGit-2.0.zip"))
+	ioutil.WriteFile(wrapped.String(), zipContent("PortableGit-2.0/bin/git.exe", "PortableGit-2.0/README"), 0644)
+	flat := paths.NewPath(filepath.Join(root, "PortableGit-1.9.zip"))
+	ioutil.WriteFile(flat.String(), zipContent("bin/git.exe", "README"), 0644)
+
+	Convey("An archive top folder is stripped by default", t, func() {
+		SetBuffers(nil)
+		p := &testUninstPrg{name: "git", values: map[string]string{"test": "bin/git.exe"}}
+		i := New(p).(*inst)
+		folder := paths.NewPathDir(filepath.Join(root, "git", "v2"))
+		So(folder.MkdirAll(), ShouldBeTrue)
+		So(i.extract(wrapped, folder), ShouldBeNil)
+		So(exists(folder.String(), "bin", "git.exe"), ShouldBeTrue)
+
+		Convey("IsInstalled looks for the test file in 'latest'", func() {
+			So(i.IsInstalled(), ShouldBeFalse)
+			os.Symlink(folder.NoSep().String(), filepath.Join(root, "git", "latest"))
+			So(i.IsInstalled(), ShouldBeTrue)
+			p.values["test"] = "bin/none.exe"
+			So(i.IsInstalled(), ShouldBeFalse)
+			delete(p.values, "test")
+			So(i.IsInstalled(), ShouldBeTrue)
+		})
+
+		Convey("A flat archive is extracted as is", func() {
+			folder := paths.NewPathDir(filepath.Join(root, "git", "v1"))
+			So(folder.MkdirAll(), ShouldBeTrue)
+			So(i.extract(flat, folder), ShouldBeNil)
+			So(exists(folder.String(), "bin", "git.exe"), ShouldBeTrue)
+		})
+
+		Convey("A test file not found after extraction is an error", func() {
+			p.values = map[string]string{"test": "bin/git.exe", "strip": "false"}
+			folder := paths.NewPathDir(filepath.Join(root, "git", "v3"))
+			So(folder.MkdirAll(), ShouldBeTrue)
+			err := i.extract(wrapped, folder)
+			So(err.Error(), ShouldStartWith, "test file 'bin/git.exe' not found in '"+folder.String()+"'")
+			So(err.Error(), ShouldEndWith, "(top folder 'PortableGit-2.0' kept: 'strip true'?)")
+			So(exists(folder.String(), "PortableGit-2.0", "bin", "git.exe"), ShouldBeTrue)
+		})
+
+		Convey("An extraction failure says why", func() {
+			notzip := paths.NewPath(filepath.Join(root, "PortableGit-3.0.zip"))
+			ioutil.WriteFile(notzip.String(), []byte("<html>"), 0644)
+			err := i.extract(notzip, paths.NewPathDir(filepath.Join(root, "git", "v4")))
+			So(errors.Is(err, paths.ErrNotArchive), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "(not an archive)")
+		})
+	})
+}
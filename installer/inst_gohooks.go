@@ -0,0 +1,54 @@
+package installer
+
+import (
+	"fmt"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cmds"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+)
+
+// GoInstaller is a Go-coded installer, used by a config with
+// 'invoke go: <name>' or 'buildZip go: <name>'.
+// It gets the program folder, the downloaded archive and the program.
+type GoInstaller func(folder, archive *paths.Path, p prgs.Prg) error
+
+var goInstallers = map[string]GoInstaller{}
+
+// Register makes a Go installer available to configs under a name.
+// It is meant to be called from an init() function,
+// and panics if the name is already registered.
+func Register(name string, gi GoInstaller) {
+	if _, ok := goInstallers[name]; ok {
+		panic(fmt.Sprintf("Go installer '%s' registered twice", name))
+	}
+	goInstallers[name] = gi
+	prgs.RegisterGoHook(name)
+}
+
+// hook runs the config key ('invoke', 'buildZip') of a program, either
+// a registered Go installer ('go: <name>'), or a command (see cmds.Template).
+// No key means nothing to run.
+func (i *inst) hook(key string, folder, archive *paths.Path) error {
+	value := i.p.Value(key)
+	if value == "" {
+		return nil
+	}
+	if name := prgs.GoHook(value); name != "" {
+		gi, ok := goInstallers[name]
+		if !ok {
+			return fmt.Errorf("unknown Go installer '%s' for '%s' of '%s'", name, key, i.p.Name())
+		}
+		godbg.Pdbgf("Go installer '%s' for '%s' of '%s'", name, key, i.p.Name())
+		if err := gi(folder, archive, i.p); err != nil {
+			return fmt.Errorf("%s '%s' of '%s': %v", key, name, i.p.Name(), err)
+		}
+		return nil
+	}
+	cmd, err := cmds.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s for '%s': %v", key, i.p.Name(), err)
+	}
+	return cmd.Run(&cmds.Vars{File: archive, Dest: folder}, fexec)
+}
@@ -0,0 +1,72 @@
+package installer
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cmds"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type hookCall struct {
+	folder, archive *paths.Path
+	name            string
+}
+
+var hookCalls []*hookCall
+
+func testGoInstaller(folder, archive *paths.Path, p prgs.Prg) error {
+	hookCalls = append(hookCalls, &hookCall{folder: folder, archive: archive, name: p.Name()})
+	if p.Name() == "fail" {
+		return fmt.Errorf("no tools.zip")
+	}
+	return nil
+}
+
+func TestGoHooks(t *testing.T) {
+
+	Convey("Go installers are registered by name", t, func() {
+		SetBuffers(nil)
+		So(goInstallers["InstallJDK"], ShouldNotBeNil)
+		So(goInstallers["InstallJDKsrc"], ShouldNotBeNil)
+		So(goInstallers["BuildZipJDK"], ShouldNotBeNil)
+		Register("TestInstaller", testGoInstaller)
+		defer delete(goInstallers, "TestInstaller")
+		So(func() { Register("TestInstaller", testGoInstaller) }, ShouldPanicWith, "Go installer 'TestInstaller' registered twice")
+
+		folder := paths.NewPathDir("/prgs/tools/tools-1.0")
+		archive := paths.NewPath("/prgs/tools/tools-1.0.exe")
+
+		Convey("A 'go:' hook calls its Go installer", func() {
+			hookCalls = nil
+			p := &testUninstPrg{name: "tools", values: map[string]string{"invoke": "go: TestInstaller"}}
+			err := New(p).(*inst).hook("invoke", folder, archive)
+			So(err, ShouldBeNil)
+			So(len(hookCalls), ShouldEqual, 1)
+			So(hookCalls[0].folder, ShouldEqual, folder)
+			So(hookCalls[0].archive, ShouldEqual, archive)
+			So(hookCalls[0].name, ShouldEqual, "tools")
+
+			p = &testUninstPrg{name: "fail", values: map[string]string{"buildZip": "go: TestInstaller"}}
+			err = New(p).(*inst).hook("buildZip", folder, archive)
+			So(err.Error(), ShouldEqual, "buildZip 'TestInstaller' of 'fail': no tools.zip")
+			p = &testUninstPrg{name: "x", values: map[string]string{"invoke": "go: Unknown"}}
+			err = New(p).(*inst).hook("invoke", folder, archive)
+			So(err.Error(), ShouldEqual, "unknown Go installer 'Unknown' for 'invoke' of 'x'")
+		})
+
+		Convey("Other hooks are commands", func() {
+			argvs = nil
+			fexec = testfexec
+			p := &testUninstPrg{name: "tools", values: map[string]string{"invoke": "@FILE@ /S /D=@DEST@"}}
+			err := New(p).(*inst).hook("invoke", folder, archive)
+			So(err, ShouldBeNil)
+			So(argvs, ShouldResemble, [][]string{{archive.String(), "/S", "/D=" + folder.String()}})
+			So(New(p).(*inst).hook("buildZip", folder, archive), ShouldBeNil)
+			fexec = cmds.OS
+		})
+	})
+}
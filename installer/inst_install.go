@@ -0,0 +1,261 @@
+package installer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	"github.com/VonC/senvgo/state"
+	"github.com/VonC/senvgo/upstream"
+	"github.com/VonC/senvgo/version"
+)
+
+// release is what to install for a program:
+// an archive, and the version folder to install it in
+type release struct {
+	archive *paths.Path
+	folder  string
+	version string
+	// url is the archive url, when resolved upstream
+	url string
+}
+
+// ErrNotInCache is the error of a program with no archive to install in cache
+// (see cache.ErrNotInCache), which cannot be downloaded: no extractor nor
+// locked url, or --offline (see upstream.SetOffline).
+// The cache can be seeded by 'senvgo bundle import', or by copying archives in it.
+var ErrNotInCache = cache.ErrNotInCache
+
+var fresolve func(p prgs.Prg, c *cache.Cache) (*release, error)
+
+func init() {
+	fresolve = iresolve
+}
+
+// archiveExts are the archives an installer knows how to install
+var archiveExts = []string{".zip", ".tar.gz", ".tgz", ".7z", ".exe", ".msi"}
+
+// iresolve returns the archive of a program in its cache folder with
+// the newest version (see prgs.VersionOf), or the most recent one among archives
+// with the same version, or no version.
+// Its version folder is the first group of 'folder.rx' (an archive not matching
+// it is ignored), or the archive name without extension.
+// 'folder.rx' only applies if the folder is extracted from the archive name
+// or url (see folderFromName), with its '_$arch_' expanded (see upstream.Expand).
+// With a 'version' pin, only archives of that version are candidates.
+// Portable archives (built from an .exe or .msi) and their '.files' lists
+// are not candidates: cache.Get returns them for their .exe or .msi.
+func iresolve(p prgs.Prg, c *cache.Cache) (*release, error) {
+	var rx *regexp.Regexp
+	if value := p.Value("folder.rx"); value != "" && folderFromName(p) {
+		var err error
+		if rx, err = regexp.Compile(upstream.Expand(p, value)); err != nil {
+			return nil, fmt.Errorf("invalid folder.rx for '%s': %v", p.Name(), err)
+		}
+	}
+	var pin *version.Version
+	if value := p.Value("version"); value != "" {
+		var err error
+		if pin, err = version.Parse(value); err != nil {
+			return nil, fmt.Errorf("invalid version for '%s': %v", p.Name(), err)
+		}
+	}
+	candidates := []*candidate{}
+	dir := c.Folder(p.Name())
+	for _, cand := range archives(p, dir, rx) {
+		if pin == nil || version.Compare(cand.v, pin) == 0 {
+			candidates = append(candidates, cand)
+		}
+	}
+	if len(candidates) == 0 && pin != nil {
+		return nil, fmt.Errorf("no archive of version '%v' to install for '%s': %w '%v'", pin, p.Name(), ErrNotInCache, dir)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no archive to install for '%s': %w '%v'", p.Name(), ErrNotInCache, dir)
+	}
+	return candidates[0].r, nil
+}
+
+// candidate is an archive of a program cache folder, with its version
+type candidate struct {
+	r *release
+	v *version.Version
+}
+
+// archives returns the archives of a program cache folder, newest version first
+// (the most recent first among the same version, or no version).
+// rx is the 'folder.rx' of the program, if any: see iresolve.
+func archives(p prgs.Prg, dir *paths.Path, rx *regexp.Regexp) []*candidate {
+	res := []*candidate{}
+	for _, fi := range dir.GetDateOrderedFiles("") {
+		name := fi.Name()
+		folder := archiveFolder(name)
+		if fi.IsDir() || folder == "" || isPortable(dir, name) {
+			continue
+		}
+		if rx != nil {
+			m := rx.FindStringSubmatch(name)
+			if len(m) < 2 || m[1] == "" {
+				continue
+			}
+			folder = m[1]
+		}
+		v := prgs.VersionOf(p, name)
+		r := &release{archive: dir.Add(name), folder: folder}
+		if v != nil {
+			r.version = v.String()
+		}
+		res = append(res, &candidate{r: r, v: v})
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return version.Compare(res[i].v, res[j].v) > 0
+	})
+	return res
+}
+
+// folderFromName checks if the version folder of a program is extracted
+// from its archive name or url (by its first 'folder.get'), or not extracted.
+func folderFromName(p prgs.Prg) bool {
+	for _, s := range p.Extractors() {
+		if s.Name == "folder.get" {
+			return s.Value == "_name" || s.Value == "_url"
+		}
+	}
+	return true
+}
+
+// archiveFolder is an archive name without its extension,
+// empty if not an archive
+func archiveFolder(name string) string {
+	lname := strings.ToLower(name)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lname, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return ""
+}
+
+// isPortable checks if an archive was built from an .exe or .msi next to it
+func isPortable(dir *paths.Path, name string) bool {
+	folder := archiveFolder(name)
+	return !strings.HasSuffix(strings.ToLower(name), ".exe") && !strings.HasSuffix(strings.ToLower(name), ".msi") &&
+		(dir.Add(folder+".exe").Exists() || dir.Add(folder+".msi").Exists())
+}
+
+// Install installs the archive of a program (see resolve), or the one
+// it is locked to (see NewLocked), in its version folder.
+// The archive is extracted (or its 'invoke' run) in a staging folder,
+// '<prg>/tmp/<id>', where its test file is checked, before it is renamed
+// as the version folder. Only then are the 'latest' link and the state updated:
+// an interrupted install never looks installed.
+// A version folder already installed is only switched to.
+// The install (or switch), successful or not, is recorded in the journal.
+// Once installed, the cache of the program is trimmed (see trim).
+func (i *inst) Install() error {
+	root, err := fprgsenv()
+	if err != nil {
+		return err
+	}
+	c := cache.Default(root)
+	r, err := i.resolve(c)
+	if err != nil {
+		i.record(root, journal.Install, "", "", err)
+		return err
+	}
+	folder := root.Add(i.p.Dir()).SetDir()
+	dst := folder.Add(r.folder).SetDir()
+	action := journal.Switch
+	if !i.hasTest(dst) {
+		action = journal.Install
+		err = i.stage(folder, dst, r, c)
+	}
+	if err == nil {
+		err = i.activate(root, folder, r)
+	}
+	i.record(root, action, r.folder, r.version, err)
+	if err == nil {
+		i.trim(root, c, r)
+	}
+	return err
+}
+
+// stage installs a release in a staging folder, then renames it as dst
+// (replacing any incomplete dst).
+// The staging folder is deleted if the install fails.
+func (i *inst) stage(folder, dst *paths.Path, r *release, c *cache.Cache) (err error) {
+	staging := folder.Add("tmp").SetDir()
+	// leftovers of interrupted installs
+	for _, fi := range staging.GetFiles("") {
+		if err := staging.Add(fi.Name()).Purge(); err != nil {
+			godbg.Pdbgf("Unable to clean '%v': %v", staging, err)
+		}
+	}
+	tmp := staging.Add(strconv.FormatInt(time.Now().UnixNano(), 36)).SetDir()
+	if err = tmp.Mkdir(); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Purge()
+		}
+		staging.Remove()
+	}()
+	archive := c.Get(i.p.Name(), r.archive.Base())
+	if archive == nil {
+		return fmt.Errorf("archive '%s' of '%s': %w", r.archive.Base(), i.p.Name(), ErrNotInCache)
+	}
+	portable := archive.Base() != r.archive.Base()
+	if i.p.Value("invoke") != "" && !portable {
+		godbg.Pdbgf("invoking '%v' for '%s' in '%v'", archive, i.p.Name(), tmp)
+		if err = i.hook("invoke", tmp, archive); err != nil {
+			return err
+		}
+		if !i.hasTest(tmp) {
+			return fmt.Errorf("test file '%s' not found in '%v' after invoking '%v'", i.p.Test(), tmp, archive)
+		}
+	} else if err = i.extract(archive, tmp); err != nil {
+		return err
+	}
+	if !portable {
+		if _, err := i.buildZip(tmp, archive, c); err != nil {
+			godbg.Pdbgf("Unable to build a portable archive for '%s': %v", i.p.Name(), err)
+		}
+	}
+	if err = dst.Delete(); err != nil {
+		return fmt.Errorf("unable to replace '%v': %v", dst, err)
+	}
+	return tmp.Rename(dst)
+}
+
+// activate links 'latest' to the version folder of a release
+// (a directory junction on Windows, see paths.FS),
+// and records it in the state.
+// If the link fails, the previous 'latest' is restored, and the state kept.
+func (i *inst) activate(root, folder *paths.Path, r *release) error {
+	latest := folder.Add("latest").NoSep()
+	previous, _ := latest.Readlink()
+	if err := removeIfExists(latest); err != nil {
+		return err
+	}
+	if err := latest.Symlink(r.folder); err != nil {
+		if previous != "" {
+			latest.Symlink(previous)
+		}
+		return fmt.Errorf("unable to link '%v' to '%s': %v", latest, r.folder, err)
+	}
+	st, err := state.Load(root)
+	if err != nil {
+		return err
+	}
+	st.Set(&state.Entry{Name: i.p.Name(), Folder: r.folder, Version: r.version, Archive: r.archive.Base(), Installed: time.Now()})
+	return st.Save()
+}
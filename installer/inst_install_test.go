@@ -0,0 +1,185 @@
+package installer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/state"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// cached puts an archive in the cache of a program, modified 'age' ago
+func cached(root, name, archive string, age time.Duration, content []byte) {
+	folder := filepath.Join(root, cache.Dir, name)
+	os.MkdirAll(folder, 0755)
+	file := filepath.Join(folder, archive)
+	ioutil.WriteFile(file, content, 0644)
+	t := time.Now().Add(-age)
+	os.Chtimes(file, t, t)
+}
+
+func TestInstall(t *testing.T) {
+
+	root, _ := ioutil.TempDir("", "inst")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+
+	Convey("An archive is installed through a staging folder", t, func() {
+		SetBuffers(nil)
+		cached(root, "git", "PortableGit-1.9.zip", time.Hour, zipContent("PortableGit-1.9/bin/git.exe"))
+		cached(root, "git", "PortableGit-2.0.zip", 0, zipContent("PortableGit-2.0/bin/git.exe"))
+		p := &testUninstPrg{name: "git", values: map[string]string{"test": "bin/git.exe"}}
+		i := New(p)
+		So(i.IsInstalled(), ShouldBeFalse)
+		So(i.Install(), ShouldBeNil)
+		So(i.IsInstalled(), ShouldBeTrue)
+		So(exists(root, "git", "PortableGit-2.0", "bin", "git.exe"), ShouldBeTrue)
+		So(exists(root, "git", "tmp"), ShouldBeFalse)
+		target, _ := os.Readlink(filepath.Join(root, "git", "latest"))
+		So(target, ShouldEqual, "PortableGit-2.0")
+		st, _ := state.Load(paths.NewPathDir(root))
+		So(st.Get("git").Folder, ShouldEqual, "PortableGit-2.0")
+		So(st.Get("git").Archive, ShouldEqual, "PortableGit-2.0.zip")
+		So(st.Get("git").Installed.IsZero(), ShouldBeFalse)
+
+		Convey("A failed install leaves the installed version untouched", func() {
+			cached(root, "git", "PortableGit-2.1.zip", -time.Hour, zipContent("PortableGit-2.1/README"))
+			err := i.Install()
+			So(err.Error(), ShouldStartWith, "test file 'bin/git.exe' not found in '"+filepath.Join(root, "git", "tmp"))
+			So(exists(root, "git", "PortableGit-2.1"), ShouldBeFalse)
+			So(exists(root, "git", "tmp"), ShouldBeFalse)
+			target, _ := os.Readlink(filepath.Join(root, "git", "latest"))
+			So(target, ShouldEqual, "PortableGit-2.0")
+			st, _ := state.Load(paths.NewPathDir(root))
+			So(st.Get("git").Folder, ShouldEqual, "PortableGit-2.0")
+			So(i.IsInstalled(), ShouldBeTrue)
+			events, _ := journal.Open(paths.NewPathDir(root)).Events("git")
+			So(len(events), ShouldEqual, 2)
+			So(events[0].Outcome, ShouldEqual, journal.OK)
+			So(events[1].Folder, ShouldEqual, "PortableGit-2.1")
+			So(events[1].Outcome, ShouldEqual, journal.Failed)
+			So(events[1].Error, ShouldStartWith, "test file 'bin/git.exe' not found")
+			os.Remove(filepath.Join(root, cache.Dir, "git", "PortableGit-2.1.zip"))
+		})
+	})
+
+	Convey("A failed link keeps the previous 'latest' and state", t, func() {
+		SetBuffers(nil)
+		fs := &linkFailFS{MemFS: paths.NewMemFS(), target: "hg-3.1"}
+		mroot := memRoot(fs)
+		defer func() { fprgsenv = envs.Prgsenv }()
+		memCached(fs.MemFS, mroot, "hg", "hg-3.0.zip", time.Hour, zipContent("hg-3.0/hg.exe"))
+		p := &testUninstPrg{name: "hg", values: map[string]string{"test": "hg.exe"}}
+		So(New(p).Install(), ShouldBeNil)
+		memCached(fs.MemFS, mroot, "hg", "hg-3.1.zip", 0, zipContent("hg-3.1/hg.exe"))
+		latest := mroot.Add("hg").Add("latest").NoSep()
+		err := New(p).Install()
+		So(err.Error(), ShouldEqual, "unable to link '"+latest.String()+"' to 'hg-3.1': unable to link '"+latest.String()+"': privilege not held")
+		So(mroot.Add("hg").Add("hg-3.1").Add("hg.exe").Exists(), ShouldBeTrue)
+		target, _ := latest.Readlink()
+		So(target, ShouldEqual, "hg-3.0")
+		st, _ := state.Load(mroot)
+		So(st.Get("hg").Folder, ShouldEqual, "hg-3.0")
+		events, _ := journal.Open(mroot).Events("hg")
+		So(events[1].Outcome, ShouldEqual, journal.Failed)
+		So(events[1].Action, ShouldEqual, journal.Install)
+
+		fs.target = ""
+		So(New(p).Install(), ShouldBeNil)
+		target, _ = latest.Readlink()
+		So(target, ShouldEqual, "hg-3.1")
+		events, _ = journal.Open(mroot).Events("hg")
+		So(events[2].Action, ShouldEqual, journal.Switch)
+	})
+
+	Convey("Interrupted installs are cleaned and replaced", t, func() {
+		SetBuffers(nil)
+		cached(root, "svn", "svn-1.8.zip", 0, zipContent("svn-1.8/bin/svn.exe"))
+		leftover := filepath.Join(root, "svn", "tmp", "abc", "bin")
+		os.MkdirAll(leftover, 0755)
+		partial := filepath.Join(root, "svn", "svn-1.8", "bin")
+		os.MkdirAll(partial, 0755)
+		ioutil.WriteFile(filepath.Join(partial, "partial.dll"), []byte("dll"), 0644)
+		p := &testUninstPrg{name: "svn", values: map[string]string{"test": "bin/svn.exe"}}
+		So(New(p).IsInstalled(), ShouldBeFalse)
+		So(New(p).Install(), ShouldBeNil)
+		paths.WaitPurges()
+		So(exists(root, "svn", "svn-1.8", "bin", "svn.exe"), ShouldBeTrue)
+		So(exists(root, "svn", "svn-1.8", "bin", "partial.dll"), ShouldBeFalse)
+		So(exists(root, "svn", "tmp"), ShouldBeFalse)
+		So(exists(root, "svn", "svn-1.8"+paths.TrashSuffix), ShouldBeFalse)
+		So(New(p).IsInstalled(), ShouldBeTrue)
+	})
+
+	Convey("The version folder can come from 'folder.rx'", t, func() {
+		SetBuffers(nil)
+		cached(root, "npp", "npp.6.7.Installer.zip", 0, zipContent("notepad++.exe"))
+		cached(root, "npp", "README.txt", -time.Hour, []byte("not an archive"))
+		p := &testUninstPrg{name: "npp", values: map[string]string{"test": "notepad++.exe", "folder.rx": `(npp\.[\d.]+\d)`}}
+		So(New(p).Install(), ShouldBeNil)
+		So(exists(root, "npp", "npp.6.7", "notepad++.exe"), ShouldBeTrue)
+		p.values["folder.rx"] = "(x"
+		So(New(p).Install().Error(), ShouldStartWith, "invalid folder.rx for 'npp': ")
+		p.values["folder.rx"] = "(x)"
+		err := New(p).Install()
+		So(err.Error(), ShouldStartWith, "no archive to install for 'npp': not in cache '")
+		So(errors.Is(err, ErrNotInCache), ShouldBeTrue)
+	})
+
+	Convey("The newest version is installed, whatever the archive dates", t, func() {
+		SetBuffers(nil)
+		cached(root, "go", "go1.10.windows-amd64.zip", time.Hour, zipContent("go1.10/bin/go.exe"))
+		cached(root, "go", "go1.9.windows-amd64.zip", 0, zipContent("go1.9/bin/go.exe"))
+		cached(root, "go", "go1.10rc1.windows-amd64.zip", -time.Hour, zipContent("go1.10rc1/bin/go.exe"))
+		cached(root, "go", "go-tip.zip", -2*time.Hour, zipContent("go-tip/bin/go.exe"))
+		p := &testUninstPrg{name: "go", values: map[string]string{"test": "bin/go.exe", "cache": "4"}}
+		r, err := iresolve(p, cache.Default(paths.NewPathDir(root)))
+		So(err, ShouldBeNil)
+		So(r.folder, ShouldEqual, "go1.10.windows-amd64")
+		So(r.version, ShouldEqual, "1.10")
+		So(New(p).Install(), ShouldBeNil)
+		st, _ := state.Load(paths.NewPathDir(root))
+		So(st.Get("go").Version, ShouldEqual, "1.10")
+
+		p.values["version.rx"] = `go(\d+\.\d+rc\d+)`
+		r, _ = iresolve(p, cache.Default(paths.NewPathDir(root)))
+		So(r.folder, ShouldEqual, "go1.10rc1.windows-amd64")
+		So(r.version, ShouldEqual, "1.10rc1")
+		p.values["version.rx"] = `(none)`
+		r, _ = iresolve(p, cache.Default(paths.NewPathDir(root)))
+		So(r.folder, ShouldEqual, "go-tip")
+		So(r.version, ShouldEqual, "")
+	})
+
+	Convey("An installer is invoked in the staging folder", t, func() {
+		SetBuffers(nil)
+		cached(root, "ag", "ag-1.0.exe", 0, []byte("exe"))
+		argvs = nil
+		old := fexec
+		fexec = func(argv []string) ([]byte, error) {
+			argvs = append(argvs, argv)
+			return nil, nil
+		}
+		p := &testUninstPrg{name: "ag", values: map[string]string{"test": "ag.exe", "invoke": "@FILE@ /S /D=@DEST@"}}
+		err := New(p).Install()
+		So(len(argvs), ShouldEqual, 1)
+		So(argvs[0][0], ShouldEqual, filepath.Join(root, cache.Dir, "ag", "ag-1.0.exe"))
+		So(argvs[0][2], ShouldStartWith, "/D="+filepath.Join(root, "ag", "tmp"))
+		So(err.Error(), ShouldStartWith, "test file 'ag.exe' not found in '"+filepath.Join(root, "ag", "tmp"))
+		So(exists(root, "ag"), ShouldBeTrue)
+		So(exists(root, "ag", "tmp"), ShouldBeFalse)
+		So(exists(root, "ag", "ag-1.0"), ShouldBeFalse)
+		So(exists(root, "ag", "latest"), ShouldBeFalse)
+		fexec = old
+	})
+}
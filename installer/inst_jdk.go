@@ -0,0 +1,99 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+)
+
+func init() {
+	Register("InstallJDK", installJDK)
+	Register("InstallJDKsrc", installJDKsrc)
+	Register("BuildZipJDK", buildZipJDK)
+}
+
+// installJDK extracts a JDK, then its nested tools.zip,
+// and unpacks its '.pack' files in '.jar' with its bin/unpack200.exe.
+func installJDK(folder, archive *paths.Path, p prgs.Prg) error {
+	tools := folder.Add("tools.zip")
+	if !tools.Exists() {
+		if err := archive.Extract(folder); err != nil {
+			return err
+		}
+	}
+	if !tools.Exists() {
+		return fmt.Errorf("no tools.zip in '%v'", archive)
+	}
+	if !folder.Add("LICENSE").Exists() {
+		if err := tools.Extract(folder); err != nil {
+			return err
+		}
+	}
+	unpack := folder.Add("bin").Add("unpack200.exe")
+	if !unpack.Exists() {
+		return fmt.Errorf("no bin/unpack200.exe in '%v'", folder)
+	}
+	match, _ := paths.Globs("*.pack")
+	packs := []string{}
+	err := folder.Walk(&paths.WalkOptions{Match: match}, func(rel string, fi os.FileInfo) error {
+		packs = append(packs, strings.TrimSuffix(rel, ".pack"))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, pack := range packs {
+		jar := folder.Add(pack + ".jar")
+		if jar.Exists() {
+			continue
+		}
+		src := folder.Add(pack + ".pack").String()
+		if out, err := fexec([]string{unpack.String(), src, jar.String()}); err != nil {
+			return fmt.Errorf("unable to unpack '%s': %v\n%s", src, err, out)
+		}
+	}
+	return nil
+}
+
+// installJDKsrc only extracts the src.zip of a JDK archive.
+func installJDKsrc(folder, archive *paths.Path, p prgs.Prg) error {
+	entries, err := archive.List()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir && path.Base(e.Name) == "src.zip" {
+			_, err = archive.ExtractFile(e.Name, folder)
+			return err
+		}
+	}
+	return fmt.Errorf("no src.zip in '%v'", archive)
+}
+
+// buildZipJDK builds, next to a JDK archive, a portable '.tar.gz'
+// of the installed JDK, listed in a '.files' file (see buildZip):
+// later installs extract it instead of invoking InstallJDK (see cache.Get).
+// Its tools.zip and '.pack' files, unpacked by installJDK, are left out.
+func buildZipJDK(folder, archive *paths.Path, p prgs.Prg) error {
+	targz := archive.NoExt().AddNoSep(".tar.gz")
+	if targz.Exists() {
+		return nil
+	}
+	if !folder.Add("lib").Add("tools.jar").Exists() {
+		return fmt.Errorf("lib/tools.jar not found in '%v'", folder)
+	}
+	files, err := folder.Compress(targz, paths.TarGz, &paths.CompressOptions{Exclude: []string{"tools.zip", "*.pack"}})
+	if err != nil {
+		return err
+	}
+	list := targz.AddNoSep(".files")
+	if err = list.WriteFile([]byte(strings.Join(files, "\n") + "\n")); err != nil {
+		targz.Remove()
+		return fmt.Errorf("unable to list files of '%v': %v", targz, err)
+	}
+	return nil
+}
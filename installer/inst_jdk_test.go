@@ -0,0 +1,170 @@
+package installer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/cmds"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// zipContent returns a zip with the given files, and their folders first
+func zipContent(files ...string) []byte {
+	b := new(bytes.Buffer)
+	zw := zip.NewWriter(b)
+	dirs := map[string]bool{}
+	for _, f := range files {
+		if dir := filepath.Dir(f); dir != "." && !dirs[dir] {
+			dirs[dir] = true
+			zw.Create(dir + "/")
+		}
+		w, _ := zw.Create(f)
+		if f == "tools.zip" {
+			w.Write(zipContent("LICENSE", "bin/unpack200.exe", "lib/tools.pack", "lib/rt.jar"))
+		} else {
+			w.Write([]byte(f))
+		}
+	}
+	zw.Close()
+	return b.Bytes()
+}
+
+func tarNames(targz string) []string {
+	res := []string{}
+	f, _ := os.Open(targz)
+	defer f.Close()
+	gr, _ := gzip.NewReader(f)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		res = append(res, hdr.Name)
+	}
+	return res
+}
+
+func TestJDK(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "jdk")
+	defer os.RemoveAll(dir)
+	archive := paths.NewPath(filepath.Join(dir, "jdk-8u40-windows-x64.zip"))
+	ioutil.WriteFile(archive.String(), zipContent("tools.zip", "src.zip"), 0644)
+
+	Convey("InstallJDK extracts the JDK nested tools.zip and unpacks its jars", t, func() {
+		SetBuffers(nil)
+		argvs = nil
+		fexec = testfexec
+		folder := paths.NewPathDir(filepath.Join(dir, "jdk8", "jdk1.8.0_40"))
+		So(folder.MkdirAll(), ShouldBeTrue)
+		err := goInstallers["InstallJDK"](folder, archive, nil)
+		So(err, ShouldBeNil)
+		So(folder.Add("LICENSE").Exists(), ShouldBeTrue)
+		So(argvs, ShouldResemble, [][]string{{folder.Add("bin").Add("unpack200.exe").String(),
+			filepath.Join(folder.NoSep().String(), "lib", "tools.pack"),
+			filepath.Join(folder.NoSep().String(), "lib", "tools.jar")}})
+		fexec = cmds.OS
+
+		Convey("InstallJDKsrc only keeps src.zip", func() {
+			src := paths.NewPathDir(filepath.Join(dir, "jdk8src"))
+			So(src.MkdirAll(), ShouldBeTrue)
+			err := goInstallers["InstallJDKsrc"](src, archive, nil)
+			So(err, ShouldBeNil)
+			files, _ := ioutil.ReadDir(src.String())
+			So(len(files), ShouldEqual, 1)
+			So(files[0].Name(), ShouldEqual, "src.zip")
+		})
+
+		Convey("BuildZipJDK builds a portable tar.gz of the installed JDK", func() {
+			err := goInstallers["BuildZipJDK"](folder, archive, nil)
+			So(err.Error(), ShouldStartWith, "lib/tools.jar not found in")
+			ioutil.WriteFile(folder.Add("lib").Add("tools.jar").String(), []byte("jar"), 0644)
+			err = goInstallers["BuildZipJDK"](folder, archive, nil)
+			So(err, ShouldBeNil)
+			So(tarNames(filepath.Join(dir, "jdk-8u40-windows-x64.tar.gz")), ShouldResemble, []string{"LICENSE", "bin/", "bin/unpack200.exe", "lib/", "lib/rt.jar", "lib/tools.jar", "src.zip"})
+			list, _ := ioutil.ReadFile(filepath.Join(dir, "jdk-8u40-windows-x64.tar.gz.files"))
+			So(string(list), ShouldEqual, "LICENSE\nbin/unpack200.exe\nlib/rt.jar\nlib/tools.jar\nsrc.zip\n")
+		})
+	})
+	Convey("InstallJDK works on any FS, and keeps the jars already there", t, func() {
+		SetBuffers(nil)
+		argvs = nil
+		fexec = testfexec
+		fs := paths.NewMemFS()
+		root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+		archive := root.Add("jdk-8u40-windows-x64.zip")
+		So(root.Mkdir(), ShouldBeNil)
+		f, _ := archive.OpenFile(false)
+		f.Write(zipContent("tools.zip"))
+		f.Close()
+		folder := root.Add("jdk8").Add("jdk1.8.0_40").SetDir()
+		So(folder.Add("lib").Mkdir(), ShouldBeNil)
+		f, _ = folder.Add("lib").Add("rt.pack").OpenFile(false)
+		f.Close()
+		err := goInstallers["InstallJDK"](folder, archive, nil)
+		So(err, ShouldBeNil)
+		So(argvs, ShouldResemble, [][]string{{folder.Add("bin").Add("unpack200.exe").String(),
+			folder.Add("lib").Add("tools.pack").String(), folder.Add("lib").Add("tools.jar").String()}})
+		So(paths.NewPath(root.String()).Exists(), ShouldBeFalse)
+		fexec = cmds.OS
+	})
+
+	Convey("A JDK installs again from the same cache, once its portable archive is built", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		root := memRoot(fs)
+		defer func() { fprgsenv = envs.Prgsenv }()
+		argvs = nil
+		fexec = func(argv []string) ([]byte, error) {
+			argvs = append(argvs, argv)
+			return nil, paths.NewPathFS(fs, argv[2]).WriteFile([]byte("jar"))
+		}
+		defer func() { fexec = cmds.OS }()
+		// the JDK .exe is extracted by 7z: its tools.zip is extracted here instead
+		install := goInstallers["InstallJDK"]
+		goInstallers["InstallJDK"] = func(folder, archive *paths.Path, p prgs.Prg) error {
+			asZip := paths.NewPathFS(fs, archive.String()+".zip")
+			archive.Copy(asZip)
+			defer asZip.Remove()
+			if err := asZip.Extract(folder); err != nil {
+				return err
+			}
+			return install(folder, archive, p)
+		}
+		defer func() { goInstallers["InstallJDK"] = install }()
+		memCached(fs, root, "jdk8", "jdk-8u40-windows-x64.exe", 0, zipContent("tools.zip", "src.zip"))
+		p := &testUninstPrg{name: "jdk8", values: map[string]string{
+			"test":     filepath.Join("lib", "tools.jar"),
+			"invoke":   "go: InstallJDK",
+			"buildZip": "go: BuildZipJDK"}}
+		i := New(p)
+		So(i.Install(), ShouldBeNil)
+		So(len(argvs), ShouldEqual, 1)
+		portable := root.Add(cache.Dir).Add("jdk8").Add("jdk-8u40-windows-x64.tar.gz")
+		So(portable.Exists(), ShouldBeTrue)
+
+		So(i.Uninstall(), ShouldBeNil)
+		So(i.IsInstalled(), ShouldBeFalse)
+		So(i.Install(), ShouldBeNil)
+		So(i.IsInstalled(), ShouldBeTrue)
+		So(len(argvs), ShouldEqual, 1)
+		folder := root.Add("jdk8").Add("jdk-8u40-windows-x64").SetDir()
+		So(folder.Add("lib").Add("tools.jar").FileContent(), ShouldEqual, "jar")
+		So(folder.Add("src.zip").Exists(), ShouldBeTrue)
+		So(folder.Add("tools.zip").Exists(), ShouldBeFalse)
+		So(paths.NewPath(root.String()).Exists(), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,101 @@
+package installer
+
+import (
+	"fmt"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/prgs"
+	"github.com/VonC/senvgo/upstream"
+)
+
+// NewLocked returns an installer which installs exactly
+// the release a program is locked to, instead of resolving it.
+func NewLocked(p prgs.Prg, e *lockfile.Entry) Inst {
+	return &inst{p: p, locked: e}
+}
+
+// Resolve returns the release of a program Install would install
+// (see resolve), with the checksum of its archive, and its url
+// when it is the latest release upstream (see iupstream).
+func (i *inst) Resolve() (*lockfile.Entry, error) {
+	root, err := fprgsenv()
+	if err != nil {
+		return nil, err
+	}
+	c := cache.Default(root)
+	r, err := i.resolve(c)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := lockfile.Checksum(r.archive)
+	if err != nil {
+		return nil, fmt.Errorf("unable to checksum '%v': %v", r.archive, err)
+	}
+	return &lockfile.Entry{Name: i.p.Name(), URL: r.url, Archive: r.archive.Base(), Folder: r.folder, Version: r.version, Checksum: sum}, nil
+}
+
+// resolve returns the release to install: the locked one, if any
+// (see lresolve), or else the latest one upstream, downloaded in cache
+// if needed (see fetch), for a program with extractors and no version pin.
+// Without it (no extractor, or no network), the release is the newest
+// one in cache (see iresolve).
+func (i *inst) resolve(c *cache.Cache) (*release, error) {
+	if i.locked != nil {
+		return i.lresolve(c)
+	}
+	if len(i.p.Extractors()) == 0 || i.p.Value("version") != "" {
+		return fresolve(i.p, c)
+	}
+	r, err := fupstream(i.p, c)
+	if err == nil {
+		err = i.fetch(r, c)
+	}
+	if err != nil {
+		godbg.Pdbgf("Installing '%s' from the cache: %v", i.p.Name(), err)
+		return fresolve(i.p, c)
+	}
+	return r, nil
+}
+
+// fetch downloads the archive of a release in cache, from its url,
+// unless it is already there (or its portable archive).
+// Offline, it fails with ErrNotInCache (see upstream.Download).
+func (i *inst) fetch(r *release, c *cache.Cache) error {
+	if c.Get(i.p.Name(), r.archive.Base()) != nil {
+		return nil
+	}
+	if r.url == "" {
+		return fmt.Errorf("archive '%s' of '%s': no url: %w", r.archive.Base(), i.p.Name(), ErrNotInCache)
+	}
+	godbg.Pdbgf("Downloading '%s' for '%s'", r.url, i.p.Name())
+	if err := upstream.Download(r.url, i.p.Value("cookie"), r.archive); err != nil {
+		return fmt.Errorf("archive '%s' of '%s': %w", r.archive.Base(), i.p.Name(), err)
+	}
+	return nil
+}
+
+// lresolve returns the locked release, whose archive must be in cache
+// with the locked checksum: if missing, it is downloaded from its locked
+// url, if any (see fetch).
+func (i *inst) lresolve(c *cache.Cache) (*release, error) {
+	e := i.locked
+	archive := c.Folder(e.Name).Add(e.Archive)
+	if !archive.Exists() {
+		if e.URL == "" {
+			return nil, fmt.Errorf("locked archive '%s' of '%s': %w '%v'", e.Archive, e.Name, ErrNotInCache, c.Folder(e.Name))
+		}
+		if err := i.fetch(&release{archive: archive, url: e.URL}, c); err != nil {
+			return nil, fmt.Errorf("locked %w", err)
+		}
+	}
+	sum, err := lockfile.Checksum(archive)
+	if err != nil {
+		return nil, fmt.Errorf("unable to checksum '%v': %v", archive, err)
+	}
+	if sum != e.Checksum {
+		return nil, fmt.Errorf("checksum mismatch for '%v': '%s' instead of the locked '%s'", archive, sum, e.Checksum)
+	}
+	return &release{archive: archive, folder: e.Folder, version: e.Version}, nil
+}
@@ -0,0 +1,98 @@
+package installer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	"github.com/VonC/senvgo/state"
+	"github.com/VonC/senvgo/upstream"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocked(t *testing.T) {
+
+	root, _ := ioutil.TempDir("", "inst_lock")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+	cached(root, "go", "go1.4.windows-amd64.zip", 0, zipContent("go1.4/bin/go.exe"))
+	cached(root, "go", "go1.5.windows-amd64.zip", time.Hour, zipContent("go1.5/bin/go.exe"))
+
+	Convey("A program resolves to the release it would install, with its checksum", t, func() {
+		SetBuffers(nil)
+		p := &testUninstPrg{name: "go", values: map[string]string{"test": "bin/go.exe"}}
+		e, err := New(p).Resolve()
+		So(err, ShouldBeNil)
+		So(e.Archive, ShouldEqual, "go1.5.windows-amd64.zip")
+		So(e.Folder, ShouldEqual, "go1.5.windows-amd64")
+		So(e.Version, ShouldEqual, "1.5")
+		So(e.Checksum, ShouldStartWith, "sha256:")
+
+		Convey("The url of the latest release upstream is locked", func() {
+			p.extractors = []*prgs.Setting{{Name: "url.get", Value: "dwnl"}}
+			defer func() { p.extractors, fupstream = nil, iupstream }()
+			url := "https://storage.googleapis.com/golang/go1.5.windows-amd64.zip"
+			fupstream = func(p prgs.Prg, c *cache.Cache) (*release, error) {
+				return &release{archive: c.Folder("go").Add(paths.NewPath(url).Base()), url: url}, nil
+			}
+			e, err := New(p).Resolve()
+			So(err, ShouldBeNil)
+			So(e.URL, ShouldEqual, url)
+
+			url = "https://storage.googleapis.com/golang/go1.6.windows-amd64.zip"
+			upstream.SetOffline(true)
+			e, _ = New(p).Resolve()
+			upstream.SetOffline(false)
+			So(e.Archive, ShouldEqual, "go1.5.windows-amd64.zip")
+			So(e.URL, ShouldBeEmpty)
+			So(ErrString(), ShouldContainSubstring, "archive 'go1.6.windows-amd64.zip' of 'go': '"+url+"' not downloaded (offline): not in cache")
+			fupstream = func(p prgs.Prg, c *cache.Cache) (*release, error) {
+				return nil, fmt.Errorf("no such host")
+			}
+			e, _ = New(p).Resolve()
+			So(e.URL, ShouldBeEmpty)
+			So(ErrString(), ShouldContainSubstring, "Installing 'go' from the cache: no such host")
+		})
+
+		Convey("A version can be pinned", func() {
+			p.values["version"] = "1.4"
+			e, err := New(p).Resolve()
+			So(err, ShouldBeNil)
+			So(e.Folder, ShouldEqual, "go1.4.windows-amd64")
+			p.values["version"] = "1.6"
+			_, err = New(p).Resolve()
+			So(err.Error(), ShouldStartWith, "no archive of version '1.6' to install for 'go': not in cache '")
+		})
+	})
+
+	Convey("A locked release is installed, whatever the latest one", t, func() {
+		SetBuffers(nil)
+		p := &testUninstPrg{name: "go", values: map[string]string{"test": "bin/go.exe"}}
+		e := &lockfile.Entry{Name: "go", Archive: "go1.4.windows-amd64.zip", Folder: "go1.4.windows-amd64", Version: "1.4"}
+		e.Checksum, _ = lockfile.Checksum(paths.NewPath(root).Add("_cache").Add("go").Add(e.Archive))
+		So(NewLocked(p, e).Install(), ShouldBeNil)
+		So(exists(root, "go", "go1.4.windows-amd64", "bin", "go.exe"), ShouldBeTrue)
+		So(exists(root, "go", "go1.5.windows-amd64"), ShouldBeFalse)
+		st, _ := state.Load(paths.NewPathDir(root))
+		So(st.Get("go").Version, ShouldEqual, "1.4")
+
+		Convey("A locked archive must be in cache, with its checksum", func() {
+			e.Checksum = "sha256:00"
+			err := NewLocked(p, e).Install()
+			So(err.Error(), ShouldStartWith, "checksum mismatch for '")
+			So(err.Error(), ShouldEndWith, "instead of the locked 'sha256:00'")
+			e.Archive = "go1.3.windows-amd64.zip"
+			err = NewLocked(p, e).Install()
+			So(err.Error(), ShouldStartWith, "locked archive 'go1.3.windows-amd64.zip' of 'go': not in cache '")
+		})
+	})
+}
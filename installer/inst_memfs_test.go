@@ -0,0 +1,136 @@
+package installer
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/shells"
+	"github.com/VonC/senvgo/state"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// memRoot makes a programs root on a FS the root of the installer
+// (until fprgsenv is reset to envs.Prgsenv)
+func memRoot(fs paths.FS) *paths.Path {
+	root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+	root.Mkdir()
+	fprgsenv = func() (*paths.Path, error) { return root, nil }
+	return root
+}
+
+// memCached puts an archive in the cache of a program, on a MemFS root,
+// modified 'age' ago
+func memCached(fs *paths.MemFS, root *paths.Path, name, archive string, age time.Duration, content []byte) {
+	folder := root.Add(cache.Dir).Add(name).SetDir()
+	folder.Mkdir()
+	file := folder.Add(archive)
+	file.WriteFile(content)
+	t := time.Now().Add(-age)
+	fs.Chtimes(file.String(), t, t)
+}
+
+// linkFailFS is a MemFS on which linking to target fails
+type linkFailFS struct {
+	*paths.MemFS
+	target string
+}
+
+func (fs *linkFailFS) Symlink(oldname, newname string) error {
+	if oldname == fs.target {
+		return errors.New("privilege not held")
+	}
+	return fs.MemFS.Symlink(oldname, newname)
+}
+
+func TestMemFS(t *testing.T) {
+
+	Convey("Cache, installer and env writers work without touching the disk", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+		download := root.Add("downloads").Add("PortableGit-2.0.zip")
+		So(download.Dir().Mkdir(), ShouldBeNil)
+		f, err := download.OpenFile(false)
+		So(err, ShouldBeNil)
+		f.Write(zipContent("PortableGit-2.0/bin/git.exe"))
+		f.Close()
+
+		c := cache.Default(root)
+		_, err = c.Add("git", download)
+		So(err, ShouldBeNil)
+		archive := c.Get("git", "PortableGit-2.0.zip")
+		So(archive, ShouldNotBeNil)
+
+		i := New(&testUninstPrg{name: "git", values: map[string]string{"test": "bin/git.exe"}}).(*inst)
+		folder := root.Add("git").Add("v2").SetDir()
+		So(i.extract(archive, folder), ShouldBeNil)
+		So(folder.Add("bin").Add("git.exe").FileContent(), ShouldEqual, "PortableGit-2.0/bin/git.exe")
+
+		shs, _ := shells.Parse("cmd")
+		err = shells.WriteScripts(&shells.Env{Path: []string{folder.Add("bin").String()}}, root, shs)
+		So(err, ShouldBeNil)
+		So(root.Add("env.bat").FileContent(), ShouldContainSubstring, folder.Add("bin").String())
+		So(paths.NewPath(root.String()).Exists(), ShouldBeFalse)
+		So(ErrString(), ShouldContainSubstring, "PortableGit-2.0")
+	})
+
+	Convey("Programs are installed and uninstalled without touching the disk", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		root := memRoot(fs)
+		defer func() { fprgsenv = envs.Prgsenv }()
+		memCached(fs, root, "git", "PortableGit-1.9.zip", time.Hour, zipContent("PortableGit-1.9/bin/git.exe"))
+		memCached(fs, root, "git", "PortableGit-2.0.zip", 0, zipContent("PortableGit-2.0/bin/git.exe"))
+		p := &testUninstPrg{name: "git", values: map[string]string{"test": "bin/git.exe"}}
+		i := New(p)
+		So(i.IsInstalled(), ShouldBeFalse)
+		So(i.Install(), ShouldBeNil)
+		So(i.IsInstalled(), ShouldBeTrue)
+		folder := root.Add("git").SetDir()
+		So(folder.Add("PortableGit-2.0").Add("bin").Add("git.exe").Exists(), ShouldBeTrue)
+		So(folder.Add("tmp").Exists(), ShouldBeFalse)
+		target, err := folder.Add("latest").Readlink()
+		So(err, ShouldBeNil)
+		So(target, ShouldEqual, "PortableGit-2.0")
+		st, _ := state.Load(root)
+		So(st.Get("git").Archive, ShouldEqual, "PortableGit-2.0.zip")
+		s, err := i.Status()
+		So(err, ShouldBeNil)
+		So(s.Current, ShouldEqual, "PortableGit-2.0")
+
+		root.Add("bin").Mkdir()
+		root.Add("bin").Add("git.bat").WriteFile([]byte("shim"))
+		So(i.Uninstall(), ShouldBeNil)
+		So(i.IsInstalled(), ShouldBeFalse)
+		So(folder.Add("PortableGit-2.0").Exists(), ShouldBeFalse)
+		paths.WaitPurges()
+		So(folder.Add("PortableGit-2.0"+paths.TrashSuffix).Exists(), ShouldBeFalse)
+		So(folder.Add("latest").Exists(), ShouldBeFalse)
+		So(root.Add("bin").Add("git.bat").Exists(), ShouldBeFalse)
+		st, _ = state.Load(root)
+		So(st.Get("git"), ShouldBeNil)
+		events, _ := journal.Open(root).Events("git")
+		So(len(events), ShouldEqual, 2)
+		So(events[1].Action, ShouldEqual, journal.Uninstall)
+		So(events[1].Outcome, ShouldEqual, journal.OK)
+		So(paths.NewPath(root.String()).Exists(), ShouldBeFalse)
+
+		Convey("A link which cannot be removed fails the uninstall", func() {
+			So(i.Install(), ShouldBeNil)
+			fs.Fail("remove", folder.Add("latest").NoSep().String(), errors.New("access denied"))
+			err := i.Uninstall()
+			So(err.Error(), ShouldStartWith, "unable to remove '"+folder.Add("latest").NoSep().String()+"': ")
+			So(err.Error(), ShouldEndWith, "access denied")
+			fs.Fail("remove", folder.Add("latest").NoSep().String(), nil)
+			st, _ = state.Load(root)
+			So(st.Get("git"), ShouldNotBeNil)
+		})
+	})
+}
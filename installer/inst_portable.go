@@ -0,0 +1,56 @@
+package installer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+)
+
+// buildZip builds, for a program installed with an installer (.exe, .msi),
+// a portable archive of its installed folder, and registers it in the cache:
+// later installs then use it instead of the installer (see cache.Get).
+// The archive is a '.zip', or a '.tar.gz' for a program with 'deps'.
+// The files it includes are listed in a '.files' file, next to it.
+// A 'buildZip' key (like 'go: BuildZipJDK') replaces that default builder.
+// A program installed by a Go installer ('invoke go: <name>') has none:
+// its Go installer is run again by the next install.
+func (i *inst) buildZip(folder, archive *paths.Path, c *cache.Cache) (*paths.Path, error) {
+	if i.p.Value("buildZip") != "" {
+		return nil, i.hook("buildZip", folder, archive)
+	}
+	if prgs.GoHook(i.p.Value("invoke")) != "" {
+		return nil, nil
+	}
+	base := archive.Base()
+	ext := strings.ToLower(filepath.Ext(base))
+	if ext != ".exe" && ext != ".msi" {
+		return nil, nil
+	}
+	if portable := c.Portable(i.p.Name(), base); portable != nil {
+		return portable, nil
+	}
+	format := paths.Zip
+	if i.p.Value("deps") != "" {
+		format = paths.TarGz
+	}
+	portable := archive.Dir().Add(base[:len(base)-len(ext)] + "." + string(format))
+	files, err := folder.Compress(portable, format, nil)
+	if err != nil {
+		return nil, err
+	}
+	godbg.Pdbgf("'%s': portable archive '%v' (%d files)", i.p.Name(), portable, len(files))
+	list := portable.AddNoSep(".files")
+	if err = list.WriteFile([]byte(strings.Join(files, "\n") + "\n")); err != nil {
+		portable.Remove()
+		return nil, fmt.Errorf("unable to list files of '%v': %v", portable, err)
+	}
+	if _, err = c.Add(i.p.Name(), list); err != nil {
+		return nil, err
+	}
+	return c.Add(i.p.Name(), portable)
+}
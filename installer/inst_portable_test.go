@@ -0,0 +1,110 @@
+package installer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBuildZip(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "portable")
+	defer os.RemoveAll(dir)
+	c := cache.Default(paths.NewPathDir(dir))
+	folder := paths.NewPathDir(filepath.Join(dir, "python2", "python-2.7.9.amd64"))
+	os.MkdirAll(filepath.Join(folder.String(), "Lib"), 0755)
+	ioutil.WriteFile(filepath.Join(folder.String(), "python.exe"), []byte("exe"), 0755)
+	ioutil.WriteFile(filepath.Join(folder.String(), "Lib", "os.py"), []byte("py"), 0644)
+	os.MkdirAll(c.Folder("python2").String(), 0755)
+	msi := c.Folder("python2").Add("python-2.7.9.amd64.msi")
+	ioutil.WriteFile(msi.String(), []byte("msi"), 0644)
+
+	Convey("An installed program can be repackaged in a portable archive", t, func() {
+		SetBuffers(nil)
+		i := New(&testUninstPrg{name: "python2"}).(*inst)
+		portable, err := i.buildZip(folder, msi, c)
+		So(err, ShouldBeNil)
+		So(portable.Base(), ShouldEqual, "python-2.7.9.amd64.zip")
+		So(c.Get("python2", msi.Base()).String(), ShouldEqual, portable.String())
+		So(paths.NewPath(portable.String()+".files").FileContent(), ShouldEqual, "Lib/os.py\npython.exe\n")
+
+		Convey("Once built, the portable archive is reused", func() {
+			again, err := i.buildZip(folder, msi, c)
+			So(err, ShouldBeNil)
+			So(again.String(), ShouldEqual, portable.String())
+		})
+
+		Convey("Programs with deps are repackaged in a tar.gz", func() {
+			i := New(&testUninstPrg{name: "python3", values: map[string]string{"deps": "peazip"}}).(*inst)
+			p, err := i.buildZip(folder, msi, c)
+			So(err, ShouldBeNil)
+			So(p.Base(), ShouldEqual, "python-2.7.9.amd64.tar.gz")
+			So(c.Folder("python3").Add("python-2.7.9.amd64.tar.gz.files").Exists(), ShouldBeTrue)
+		})
+
+		Convey("Portable archives are not repackaged", func() {
+			p, err := i.buildZip(folder, portable, c)
+			So(err, ShouldBeNil)
+			So(p, ShouldBeNil)
+		})
+	})
+
+	Convey("An installer is repackaged once invoked, later installs extract it instead", t, func() {
+		SetBuffers(nil)
+		root, _ := ioutil.TempDir("", "portable_install")
+		defer os.RemoveAll(root)
+		envs.SetRootFlag(root)
+		defer envs.SetRootFlag("")
+		invoked := 0
+		old := fexec
+		fexec = func(argv []string) ([]byte, error) {
+			invoked++
+			return nil, ioutil.WriteFile(filepath.Join(strings.TrimPrefix(argv[2], "/D="), "ag.exe"), []byte("ag"), 0755)
+		}
+		defer func() { fexec = old }()
+		os.MkdirAll(filepath.Join(root, cache.Dir, "ag"), 0755)
+		ioutil.WriteFile(filepath.Join(root, cache.Dir, "ag", "ag-0.29.exe"), []byte("installer"), 0755)
+		p := &testUninstPrg{name: "ag", values: map[string]string{"test": "ag.exe", "invoke": "@FILE@ /S /D=@DEST@"}}
+		So(New(p).Install(), ShouldBeNil)
+		So(invoked, ShouldEqual, 1)
+		So(paths.NewPath(filepath.Join(root, cache.Dir, "ag", "ag-0.29.zip")).Exists(), ShouldBeTrue)
+
+		os.RemoveAll(filepath.Join(root, "ag", "ag-0.29"))
+		So(New(p).Install(), ShouldBeNil)
+		So(invoked, ShouldEqual, 1)
+		So(paths.NewPath(filepath.Join(root, "ag", "ag-0.29", "ag.exe")).FileContent(), ShouldEqual, "ag")
+	})
+
+	Convey("The portable archive and its files list are written on the cache FS", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+		c := cache.Default(root)
+		folder := root.Add("ag").Add("ag-1.0").SetDir()
+		So(folder.Mkdir(), ShouldBeNil)
+		So(folder.Add("ag.exe").WriteFile([]byte("exe")), ShouldBeNil)
+		So(c.Folder("ag").Mkdir(), ShouldBeNil)
+		exe := c.Folder("ag").Add("ag-1.0.exe")
+		So(exe.WriteFile([]byte("setup")), ShouldBeNil)
+		i := New(&testUninstPrg{name: "ag"}).(*inst)
+
+		fs.Fail("write", c.Folder("ag").Add("ag-1.0.zip.files").String(), os.ErrPermission)
+		_, err := i.buildZip(folder, exe, c)
+		So(err.Error(), ShouldStartWith, "unable to list files of '")
+		So(c.Folder("ag").Add("ag-1.0.zip").Exists(), ShouldBeFalse)
+		fs.Fail("write", c.Folder("ag").Add("ag-1.0.zip.files").String(), nil)
+
+		portable, err := i.buildZip(folder, exe, c)
+		So(err, ShouldBeNil)
+		So(portable.AddNoSep(".files").FileContent(), ShouldEqual, "ag.exe\n")
+		So(paths.NewPath(root.String()).Exists(), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,70 @@
+package installer
+
+import (
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	"github.com/VonC/senvgo/state"
+	"github.com/VonC/senvgo/version"
+)
+
+// Actions a Status calls for
+const (
+	ActionInstall  = "install"
+	ActionUpdate   = "update"
+	ActionUpToDate = "up to date"
+	ActionUnknown  = "unknown"
+)
+
+// Status compares the installed release of a program with the one
+// Install would install. Current or Latest is empty if unknown.
+type Status struct {
+	Current string
+	Latest  string
+	Action  string
+}
+
+// Status resolves the latest release of a program (see latest) and compares
+// it with its installed version folder, from the state or the 'latest' link.
+// A release newer than the installed one (see version.Compare), or another
+// release when the installed version is unknown, calls for an update.
+func (i *inst) Status() (*Status, error) {
+	root, err := fprgsenv()
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	s := &Status{Action: ActionUnknown}
+	var current *version.Version
+	if e := st.Get(i.p.Name()); e != nil {
+		s.Current = e.Folder
+		if e.Version != "" {
+			current, _ = version.Parse(e.Version)
+		}
+	} else if target, err := root.Add(i.p.Dir()).Add("latest").Readlink(); err == nil {
+		s.Current = paths.NewPath(target).Base()
+	}
+	if current == nil && s.Current != "" {
+		current = prgs.VersionOf(i.p, s.Current)
+	}
+	r, err := i.latest(cache.Default(root))
+	if err != nil {
+		return s, err
+	}
+	s.Latest = r.folder
+	latest, _ := version.Parse(r.version)
+	switch {
+	case s.Current == "":
+		s.Action = ActionInstall
+	case s.Current == s.Latest:
+		s.Action = ActionUpToDate
+	case current != nil && version.Compare(current, latest) >= 0:
+		s.Action = ActionUpToDate
+	default:
+		s.Action = ActionUpdate
+	}
+	return s, nil
+}
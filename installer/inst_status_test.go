@@ -0,0 +1,70 @@
+package installer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/state"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStatus(t *testing.T) {
+
+	root, _ := ioutil.TempDir("", "inst_status")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+
+	Convey("A program not installed is to install", t, func() {
+		SetBuffers(nil)
+		cached(root, "go", "go1.4.windows-amd64.zip", 0, zipContent("go1.4/bin/go.exe"))
+		p := &testUninstPrg{name: "go", values: map[string]string{"test": "bin/go.exe"}}
+		s, err := New(p).Status()
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, &Status{Latest: "go1.4.windows-amd64", Action: ActionInstall})
+
+		So(New(p).Install(), ShouldBeNil)
+		s, _ = New(p).Status()
+		So(s, ShouldResemble, &Status{Current: "go1.4.windows-amd64", Latest: "go1.4.windows-amd64", Action: ActionUpToDate})
+
+		cached(root, "go", "go1.10.windows-amd64.zip", time.Hour, zipContent("go1.10/bin/go.exe"))
+		s, _ = New(p).Status()
+		So(s.Latest, ShouldEqual, "go1.10.windows-amd64")
+		So(s.Action, ShouldEqual, ActionUpdate)
+	})
+
+	Convey("The installed folder can come from the 'latest' link", t, func() {
+		SetBuffers(nil)
+		cached(root, "npp", "npp.6.7.zip", 0, zipContent("notepad++.exe"))
+		os.MkdirAll(filepath.Join(root, "npp", "npp.6.8"), 0755)
+		os.Symlink("npp.6.8", filepath.Join(root, "npp", "latest"))
+		p := &testUninstPrg{name: "npp", values: map[string]string{"test": "notepad++.exe"}}
+		s, err := New(p).Status()
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, &Status{Current: "npp.6.8", Latest: "npp.6.7", Action: ActionUpToDate})
+
+		Convey("A folder without version is updated to another latest folder", func() {
+			st, _ := state.Load(paths.NewPathDir(root))
+			st.Set(&state.Entry{Name: "npp", Folder: "npp-nightly-a"})
+			st.Save()
+			cached(root, "npp", "npp-nightly-b.zip", -time.Hour, zipContent("notepad++.exe"))
+			p.values["folder.rx"] = `(npp-nightly-\w)`
+			s, _ := New(p).Status()
+			So(s, ShouldResemble, &Status{Current: "npp-nightly-a", Latest: "npp-nightly-b", Action: ActionUpdate})
+		})
+	})
+
+	Convey("A program without archive has an unknown latest release", t, func() {
+		SetBuffers(nil)
+		p := &testUninstPrg{name: "svn", values: map[string]string{}}
+		s, err := New(p).Status()
+		So(err.Error(), ShouldStartWith, "no archive to install for 'svn'")
+		So(s.Action, ShouldEqual, ActionUnknown)
+	})
+}
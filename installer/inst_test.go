@@ -1,17 +1,29 @@
 package installer
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
 	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/prgs"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 type testInstaller struct{ i Inst }
 
-type testPrg struct{ name string }
+type testPrg struct {
+	prgs.Prg
+	name string
+}
 
-func (tp *testPrg) Name() string { return tp.name }
+func (tp *testPrg) Name() string                { return tp.name }
+func (tp *testPrg) Dir() string                 { return tp.name }
+func (tp *testPrg) Test() string                { return "" }
+func (tp *testPrg) Value(key string) string     { return "" }
+func (tp *testPrg) Extractors() []*prgs.Setting { return nil }
 
 func (ti *testInstaller) IsInstalled() bool {
 	ti.i.IsInstalled()
@@ -25,8 +37,22 @@ func (ti *testInstaller) Install() error {
 	ti.i.Install()
 	return nil
 }
+func (ti *testInstaller) Uninstall() error {
+	return nil
+}
+func (ti *testInstaller) Status() (*Status, error) {
+	return ti.i.Status()
+}
+func (ti *testInstaller) Resolve() (*lockfile.Entry, error) {
+	return ti.i.Resolve()
+}
 func TestMain(t *testing.T) {
 
+	root, _ := ioutil.TempDir("", "inst_main")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+
 	Convey("For a given installer", t, func() {
 		SetBuffers(nil)
 		p := &testPrg{name: "prg1"}
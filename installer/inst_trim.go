@@ -0,0 +1,55 @@
+package installer
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/paths"
+)
+
+// CacheLimit is the number of archives kept in the cache of a program,
+// unless its config sets 'cache'
+var CacheLimit = 3
+
+// limit returns the number of archives to keep in the cache of the program
+func (i *inst) limit() int {
+	if n, err := strconv.Atoi(i.p.Value("cache")); err == nil && n > 0 {
+		return n
+	}
+	return CacheLimit
+}
+
+// trim removes from the cache of the program the archives beyond its limit
+// (see limit), oldest version first (see archives), with their portable
+// archive and its '.files' list. The installed archive is always kept.
+// Each archive removed, or failing to be, is recorded in the journal.
+func (i *inst) trim(root *paths.Path, c *cache.Cache, installed *release) {
+	var rx *regexp.Regexp
+	if value := i.p.Value("folder.rx"); value != "" {
+		rx, _ = regexp.Compile(value)
+	}
+	dir := c.Folder(i.p.Name())
+	kept := 0
+	for _, cand := range archives(i.p, dir, rx) {
+		archive := cand.r.archive
+		if archive.Base() == installed.archive.Base() || kept < i.limit() {
+			kept++
+			continue
+		}
+		files := []*paths.Path{}
+		if portable := c.Portable(i.p.Name(), archive.Base()); portable != nil {
+			files = append(files, portable.AddNoSep(".files"), portable)
+		}
+		var err error
+		for _, f := range append(files, archive) {
+			if rerr := f.Remove(); rerr != nil && !errors.Is(rerr, paths.ErrNotExist) {
+				err = rerr
+				break
+			}
+		}
+		i.record(root, journal.Trim, archive.Base(), cand.r.version, err)
+	}
+}
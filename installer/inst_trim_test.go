@@ -0,0 +1,88 @@
+package installer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTrim(t *testing.T) {
+
+	root, _ := ioutil.TempDir("", "inst_trim")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+
+	Convey("An install keeps only the newest archives of a program in cache", t, func() {
+		SetBuffers(nil)
+		cached(root, "node", "node-1.10.zip", 2*time.Hour, zipContent("node-1.10/node.exe"))
+		cached(root, "node", "node-1.9.exe", 0, []byte("exe"))
+		cached(root, "node", "node-1.9.zip", 0, zipContent("node.exe"))
+		cached(root, "node", "node-1.9.zip.files", 0, []byte("node.exe\n"))
+		cached(root, "node", "node-1.8.zip", -time.Hour, zipContent("node-1.8/node.exe"))
+		cached(root, "node", "node-1.7.zip", time.Hour, zipContent("node-1.7/node.exe"))
+		cached(root, "node", "README.txt", 0, []byte("not an archive"))
+		p := &testUninstPrg{name: "node", values: map[string]string{"test": "node.exe", "cache": "2"}}
+		So(New(p).Install(), ShouldBeNil)
+		So(exists(root, cache.Dir, "node", "node-1.10.zip"), ShouldBeTrue)
+		So(exists(root, cache.Dir, "node", "node-1.9.exe"), ShouldBeTrue)
+		So(exists(root, cache.Dir, "node", "node-1.9.zip"), ShouldBeTrue)
+		So(exists(root, cache.Dir, "node", "node-1.8.zip"), ShouldBeFalse)
+		So(exists(root, cache.Dir, "node", "node-1.7.zip"), ShouldBeFalse)
+		So(exists(root, cache.Dir, "node", "README.txt"), ShouldBeTrue)
+		events, _ := journal.Open(paths.NewPathDir(root)).Events("node")
+		So(len(events), ShouldEqual, 3)
+		So(events[1].Action, ShouldEqual, journal.Trim)
+		So(events[1].Folder, ShouldEqual, "node-1.8.zip")
+		So(events[1].Version, ShouldEqual, "1.8")
+		So(events[2].Folder, ShouldEqual, "node-1.7.zip")
+
+		Convey("The installed archive and its portable archive are kept", func() {
+			p.values["version"] = "1.9"
+			p.values["cache"] = "1"
+			So(New(p).Install(), ShouldBeNil)
+			So(exists(root, cache.Dir, "node", "node-1.10.zip"), ShouldBeTrue)
+			So(exists(root, cache.Dir, "node", "node-1.9.exe"), ShouldBeTrue)
+			So(exists(root, cache.Dir, "node", "node-1.9.zip.files"), ShouldBeTrue)
+
+			delete(p.values, "version")
+			So(New(p).Install(), ShouldBeNil)
+			So(exists(root, cache.Dir, "node", "node-1.9.exe"), ShouldBeFalse)
+			So(exists(root, cache.Dir, "node", "node-1.9.zip"), ShouldBeFalse)
+			So(exists(root, cache.Dir, "node", "node-1.9.zip.files"), ShouldBeFalse)
+			events, _ := journal.Open(paths.NewPathDir(root)).Events("node")
+			So(events[len(events)-1].Action, ShouldEqual, journal.Trim)
+			So(events[len(events)-1].Folder, ShouldEqual, "node-1.9.exe")
+			So(events[len(events)-1].Outcome, ShouldEqual, journal.OK)
+		})
+	})
+
+	Convey("An archive which cannot be trimmed is journaled as failed", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		c := cache.Default(paths.NewPathFS(fs, filepath.FromSlash("/prgs/")))
+		dir := c.Folder("vim")
+		So(dir.Mkdir(), ShouldBeNil)
+		for _, name := range []string{"vim-7.4.zip", "vim-7.3.zip"} {
+			So(dir.Add(name).WriteFile([]byte(name)), ShouldBeNil)
+		}
+		fs.Fail("remove", dir.Add("vim-7.3.zip").String(), os.ErrPermission)
+		i := New(&testUninstPrg{name: "vim", values: map[string]string{"cache": "1"}}).(*inst)
+		i.trim(paths.NewPathDir(root), c, &release{archive: dir.Add("vim-7.4.zip")})
+		So(dir.Add("vim-7.3.zip").Exists(), ShouldBeTrue)
+		events, _ := journal.Open(paths.NewPathDir(root)).Events("vim")
+		So(len(events), ShouldEqual, 1)
+		So(events[0].Action, ShouldEqual, journal.Trim)
+		So(events[0].Outcome, ShouldEqual, journal.Failed)
+		So(events[0].Error, ShouldContainSubstring, "vim-7.3.zip")
+	})
+}
@@ -0,0 +1,154 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/cmds"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/state"
+	"github.com/VonC/senvgo/upstream"
+)
+
+var fexec cmds.Executor
+
+func init() {
+	fexec = cmds.OS
+}
+
+// Uninstall removes an installed program:
+// its 'uninstcmd' is invoked first, if any (see runUninstcmd).
+// Then the version folder, the 'latest' link and the shims are removed,
+// and the program is removed from the state.
+// The version folder is renamed aside, then purged in the background (see paths.Delete):
+// what is locked, or not purged before exiting, is left as a '.trash' folder,
+// purged on a later run.
+// The uninstall, successful or not, is recorded in the journal.
+func (i *inst) Uninstall() error {
+	root, err := fprgsenv()
+	if err != nil {
+		return err
+	}
+	st, err := state.Load(root)
+	if err != nil {
+		return err
+	}
+	folder := root.Add(i.p.Dir()).SetDir()
+	latest := folder.Add("latest")
+	installed, version, archive := "", "", ""
+	if e := st.Get(i.p.Name()); e != nil {
+		installed, version, archive = e.Folder, e.Version, e.Archive
+	} else if target, err := latest.Readlink(); err == nil {
+		installed = paths.NewPath(target).Base()
+	}
+	if installed == "" {
+		return fmt.Errorf("'%s' is not installed", i.p.Name())
+	}
+	dst := folder.Add(installed).SetDir()
+	err = i.runUninstcmd(cache.Default(root), dst, archive)
+	if err == nil {
+		err = i.uninstall(root, folder, latest, dst, st)
+	}
+	i.record(root, journal.Uninstall, installed, version, err)
+	return err
+}
+
+func (i *inst) uninstall(root, folder, latest, dst *paths.Path, st *state.Store) error {
+	// the program folder can be shared ('dir'): only remove it if empty,
+	// once the version folder is purged, and the links removed
+	unlinked := make(chan struct{})
+	defer close(unlinked)
+	err := dst.DeleteThen(func(err error) {
+		<-unlinked
+		if err == nil {
+			folder.Remove()
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("unable to remove '%v': %v", dst, err)
+	}
+	if err = removeIfExists(latest); err != nil {
+		return err
+	}
+	for _, shim := range i.p.Addbins() {
+		if err = removeIfExists(root.Add("bin").Add(shim.Name)); err != nil {
+			return err
+		}
+	}
+	st.Remove(i.p.Name())
+	return st.Save()
+}
+
+// runUninstcmd invokes the 'uninstcmd' of a program (see cmds.Template),
+// with @DEST@ the version folder, and @FILE@ its 'uninstexe' in it,
+// or, without uninstexe, the cached installer it was installed from
+// (like 'msiexec /x @FILENS@' for an .msi, see installerOf).
+// Neither of them is an error: the program cannot be uninstalled.
+func (i *inst) runUninstcmd(c *cache.Cache, dst *paths.Path, archive string) error {
+	uninstexe, uninstcmd := i.p.Value("uninstexe"), i.p.Value("uninstcmd")
+	if uninstexe == "" && uninstcmd == "" {
+		return nil
+	}
+	var file *paths.Path
+	if uninstexe != "" {
+		if file = dst.Add(uninstexe); !file.Exists() {
+			return fmt.Errorf("uninstexe '%s' of '%s' not found in '%v'", uninstexe, i.p.Name(), dst)
+		}
+	} else if file = i.installerOf(c, dst.Base(), archive); file == nil {
+		return fmt.Errorf("no installer of '%v' for the uninstcmd of '%s': %w '%v'", dst, i.p.Name(), ErrNotInCache, c.Folder(i.p.Name()))
+	}
+	if uninstcmd == "" {
+		return fmt.Errorf("no uninstcmd for '%s': unable to invoke '%v'", i.p.Name(), file)
+	}
+	cmd, err := cmds.Parse(uninstcmd)
+	if err != nil {
+		return fmt.Errorf("invalid uninstcmd for '%s': %v", i.p.Name(), err)
+	}
+	godbg.Pdbgf("invoking uninstall for '%s': '%s'", i.p.Name(), cmd)
+	if err = cmd.Run(&cmds.Vars{File: file, Dest: dst}, fexec); err != nil {
+		return fmt.Errorf("unable to uninstall '%s': %v", i.p.Name(), err)
+	}
+	return nil
+}
+
+// installerOf returns the cached installer (.exe, .msi) of a version folder:
+// its recorded archive (see state.Entry), or else the installer archive
+// resolved to that folder (see archives), nil if none is in cache.
+func (i *inst) installerOf(c *cache.Cache, installed, archive string) *paths.Path {
+	if archive != "" {
+		if file := c.Folder(i.p.Name()).Add(archive); isInstaller(archive) && file.Exists() {
+			return file
+		}
+	}
+	var rx *regexp.Regexp
+	if value := i.p.Value("folder.rx"); value != "" && folderFromName(i.p) {
+		rx, _ = regexp.Compile(upstream.Expand(i.p, value))
+	}
+	for _, cand := range archives(i.p, c.Folder(i.p.Name()), rx) {
+		if cand.r.folder == installed && isInstaller(cand.r.archive.Base()) {
+			return cand.r.archive
+		}
+	}
+	return nil
+}
+
+// isInstaller checks if an archive is an installer (.exe, .msi)
+func isInstaller(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".exe" || ext == ".msi"
+}
+
+// removeIfExists removes a file or a link, on its Path FS
+func removeIfExists(file *paths.Path) error {
+	if err := file.Remove(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove '%v': %v", file.NoSep(), err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/cmds"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	"github.com/VonC/senvgo/state"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testUninstPrg struct {
+	prgs.Prg
+	name       string
+	values     map[string]string
+	extractors []*prgs.Setting
+}
+
+func (tp *testUninstPrg) Name() string { return tp.name }
+func (tp *testUninstPrg) Dir() string  { return tp.name }
+func (tp *testUninstPrg) Addbins() []*prgs.Setting {
+	return []*prgs.Setting{{Name: tp.name + ".bat", Value: "bin/" + tp.name + ".exe %*"}}
+}
+func (tp *testUninstPrg) Value(key string) string { return tp.values[key] }
+func (tp *testUninstPrg) Test() string            { return tp.values["test"] }
+func (tp *testUninstPrg) Extractors() []*prgs.Setting {
+	return tp.extractors
+}
+
+var argvs [][]string
+
+func testfexec(argv []string) ([]byte, error) {
+	argvs = append(argvs, argv)
+	if len(argvs) > 1 {
+		return []byte("access denied"), fmt.Errorf("exit status 1")
+	}
+	return nil, nil
+}
+
+// installed creates in root an installed program: its version folder,
+// its 'latest' link, its shim and its state entry
+func installed(root, name, version string) {
+	folder := filepath.Join(root, name, version)
+	os.MkdirAll(folder, 0755)
+	ioutil.WriteFile(filepath.Join(folder, "uninst.exe"), []byte("exe"), 0755)
+	os.Symlink(folder, filepath.Join(root, name, "latest"))
+	os.MkdirAll(filepath.Join(root, "bin"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "bin", name+".bat"), []byte("shim"), 0755)
+	st, _ := state.Load(paths.NewPathDir(root))
+	st.Set(&state.Entry{Name: name, Folder: version, Version: strings.TrimPrefix(version, name+"-")})
+	st.Save()
+}
+
+func TestUninstall(t *testing.T) {
+
+	root, _ := ioutil.TempDir("", "uninst")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+
+	Convey("A portable program is removed, leaving no trace", t, func() {
+		SetBuffers(nil)
+		installed(root, "prg1", "prg1-1.0")
+		os.MkdirAll(filepath.Join(root, "prg1", "prg1-0.9"), 0755)
+		err := New(&testUninstPrg{name: "prg1"}).Uninstall()
+		So(err, ShouldBeNil)
+		So(exists(root, "prg1", "prg1-1.0"), ShouldBeFalse)
+		paths.WaitPurges()
+		So(exists(root, "prg1", "prg1-1.0"+paths.TrashSuffix), ShouldBeFalse)
+		So(exists(root, "prg1", "latest"), ShouldBeFalse)
+		So(exists(root, "bin", "prg1.bat"), ShouldBeFalse)
+		So(exists(root, "prg1", "prg1-0.9"), ShouldBeTrue)
+		st, _ := state.Load(paths.NewPathDir(root))
+		So(st.Get("prg1"), ShouldBeNil)
+		events, _ := journal.Open(paths.NewPathDir(root)).Events("prg1")
+		So(events[0].Action, ShouldEqual, journal.Uninstall)
+		So(events[0].Folder, ShouldEqual, "prg1-1.0")
+		So(events[0].Version, ShouldEqual, "1.0")
+
+		Convey("A program not installed cannot be uninstalled", func() {
+			err := New(&testUninstPrg{name: "prg1"}).Uninstall()
+			So(err.Error(), ShouldEqual, "'prg1' is not installed")
+		})
+	})
+
+	Convey("A program with an uninstexe is uninstalled with its uninstcmd", t, func() {
+		SetBuffers(nil)
+		fexec = testfexec
+		argvs = nil
+		installed(root, "prg2", "v2")
+		p := &testUninstPrg{name: "prg2", values: map[string]string{
+			"uninstexe": "uninst.exe",
+			"uninstcmd": "@FILE@ /LOG=@DEST@..\\uninst.log /S"}}
+		err := New(p).Uninstall()
+		So(err, ShouldBeNil)
+		paths.WaitPurges()
+		dst := filepath.Join(root, "prg2", "v2")
+		So(argvs, ShouldResemble, [][]string{{filepath.Join(dst, "uninst.exe"), "/LOG=" + dst + string(os.PathSeparator) + "..\\uninst.log", "/S"}})
+		So(exists(root, "prg2"), ShouldBeFalse)
+
+		Convey("A failed uninstcmd leaves the program installed", func() {
+			installed(root, "prg2", "v2")
+			err := New(p).Uninstall()
+			So(err.Error(), ShouldStartWith, "unable to uninstall 'prg2': '")
+			So(err.Error(), ShouldEndWith, "exit status 1\naccess denied")
+			So(exists(root, "prg2", "latest"), ShouldBeTrue)
+
+			Convey("An uninstexe without uninstcmd cannot be uninstalled", func() {
+				delete(p.values, "uninstcmd")
+				err := New(p).Uninstall()
+				So(err.Error(), ShouldStartWith, "no uninstcmd for 'prg2'")
+			})
+		})
+		fexec = cmds.OS
+	})
+
+	Convey("An .msi program is uninstalled with its cached installer", t, func() {
+		SetBuffers(nil)
+		fexec = testfexec
+		defer func() { fexec = cmds.OS }()
+		argvs = nil
+		p := &testUninstPrg{name: "node", values: map[string]string{
+			"arch":      "x86,x64",
+			"test":      "node.exe",
+			"folder.rx": `(node-v.*?)\.(?:msi|zip)`,
+			"uninstcmd": `C:\WINDOWS\system32\msiexec.exe /x @FILENS@ /l @DESTNS@_uninst.log /qn`},
+			extractors: []*prgs.Setting{{Name: "folder.get", Value: "_name"}, {Name: "folder.rx", Value: `(node-v.*?)\.(?:msi|zip)`}}}
+		installed(root, "node", "node-v0.12.0")
+		cached(root, "node", "node-v0.12.0.msi", 0, []byte("msi"))
+		cached(root, "node", "node-v0.12.0.zip", 0, zipContent("node.exe"))
+		dst := filepath.Join(root, "node", "node-v0.12.0")
+		So(New(p).Uninstall(), ShouldBeNil)
+		So(argvs, ShouldResemble, [][]string{{`C:\WINDOWS\system32\msiexec.exe`, "/x", filepath.Join(root, cache.Dir, "node", "node-v0.12.0.msi"), "/l", dst + string(os.PathSeparator) + "_uninst.log", "/qn"}})
+		So(exists(root, "node", "node-v0.12.0"), ShouldBeFalse)
+
+		Convey("The installer recorded in the state is used first", func() {
+			argvs = nil
+			installed(root, "node", "node-v0.12.0")
+			st, _ := state.Load(paths.NewPathDir(root))
+			st.Get("node").Archive = "node-v0.12.0-x64.msi"
+			st.Save()
+			cached(root, "node", "node-v0.12.0-x64.msi", 0, []byte("msi"))
+			So(New(p).Uninstall(), ShouldBeNil)
+			So(argvs[0][2], ShouldEqual, filepath.Join(root, cache.Dir, "node", "node-v0.12.0-x64.msi"))
+		})
+
+		Convey("Without its installer in cache, it cannot be uninstalled", func() {
+			installed(root, "node", "node-v0.10.0")
+			err := New(p).Uninstall()
+			So(errors.Is(err, ErrNotInCache), ShouldBeTrue)
+			So(err.Error(), ShouldStartWith, "no installer of '"+filepath.Join(root, "node", "node-v0.10.0"))
+			So(exists(root, "node", "node-v0.10.0"), ShouldBeTrue)
+			events, _ := journal.Open(paths.NewPathDir(root)).Events("node")
+			So(events[len(events)-1].Outcome, ShouldEqual, journal.Failed)
+
+			p.values["uninstexe"] = "Uninstall.exe"
+			err = New(p).Uninstall()
+			So(err.Error(), ShouldEqual, "uninstexe 'Uninstall.exe' of 'node' not found in '"+filepath.Join(root, "node", "node-v0.10.0")+string(os.PathSeparator)+"'")
+			delete(p.values, "uninstexe")
+		})
+	})
+}
+
+func exists(elem ...string) bool {
+	_, err := os.Lstat(filepath.Join(elem...))
+	return err == nil
+}
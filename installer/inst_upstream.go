@@ -0,0 +1,50 @@
+package installer
+
+import (
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/prgs"
+	"github.com/VonC/senvgo/upstream"
+)
+
+var fupstream func(p prgs.Prg, c *cache.Cache) (*release, error)
+
+func init() {
+	fupstream = iupstream
+}
+
+// iupstream returns the latest release of a program upstream
+// (see upstream.Resolve), its archive being in the program cache folder
+// once downloaded.
+// Its version folder is the one extracted, or else the archive name without
+// extension, and its version the one of the archive name, or else of the folder
+// (see prgs.VersionOf).
+func iupstream(p prgs.Prg, c *cache.Cache) (*release, error) {
+	u, err := upstream.Resolve(p, c)
+	if err != nil {
+		return nil, err
+	}
+	r := &release{archive: c.Folder(p.Name()).Add(u.Archive), folder: u.Folder, url: u.URL}
+	if r.folder == "" {
+		r.folder = archiveFolder(u.Archive)
+	}
+	if r.folder == "" {
+		r.folder = u.Archive
+	}
+	v := prgs.VersionOf(p, u.Archive)
+	if v == nil {
+		v = prgs.VersionOf(p, r.folder)
+	}
+	if v != nil {
+		r.version = v.String()
+	}
+	return r, nil
+}
+
+// latest returns the latest release of the program: upstream if it has
+// extractors (see iupstream), else in cache (see iresolve).
+func (i *inst) latest(c *cache.Cache) (*release, error) {
+	if len(i.p.Extractors()) > 0 {
+		return fupstream(i.p, c)
+	}
+	return fresolve(i.p, c)
+}
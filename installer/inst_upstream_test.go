@@ -0,0 +1,158 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	"github.com/VonC/senvgo/upstream"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUpstream(t *testing.T) {
+
+	root, _ := ioutil.TempDir("", "inst_upstream")
+	defer os.RemoveAll(root)
+	envs.SetRootFlag(root)
+	defer envs.SetRootFlag("")
+	release := "PortableGit-2.1.zip"
+	downloads := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if release == "" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/download/") {
+			downloads = append(downloads, r.URL.Path)
+			w.Write(zipContent(archiveFolder(path.Base(r.URL.Path)) + "/bin/git.exe"))
+			return
+		}
+		fmt.Fprintf(w, `<a href="/download/%s">`, release)
+	}))
+	defer ts.Close()
+	p := &testUninstPrg{name: "git", values: map[string]string{"test": "bin/git.exe", "page.rel": ts.URL + "/releases"},
+		extractors: []*prgs.Setting{
+			{Name: "url.get", Value: "rel"},
+			{Name: "url.rx", Value: `href="(/download/.*?)"`},
+			{Name: "url.prepend", Value: ts.URL},
+			{Name: "name.get", Value: "_url"},
+			{Name: "name.rx", Value: `/download/(.*)`},
+		}}
+
+	Convey("The latest release of a program is resolved upstream", t, func() {
+		SetBuffers(nil)
+		r, err := iupstream(p, cache.Default(paths.NewPathDir(root)))
+		So(err, ShouldBeNil)
+		So(r.url, ShouldEqual, ts.URL+"/download/PortableGit-2.1.zip")
+		So(r.archive.String(), ShouldEqual, cache.Default(paths.NewPathDir(root)).Folder("git").Add("PortableGit-2.1.zip").String())
+		So(r.folder, ShouldEqual, "PortableGit-2.1")
+		So(r.version, ShouldEqual, "2.1")
+
+		cached(root, "git", "PortableGit-2.0.zip", 0, zipContent("PortableGit-2.0/bin/git.exe"))
+		s, err := New(p).Status()
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, &Status{Current: "", Latest: "PortableGit-2.1", Action: ActionInstall})
+
+		Convey("Its archive is downloaded in cache, then installed", func() {
+			downloads = nil
+			So(New(p).Install(), ShouldBeNil)
+			So(downloads, ShouldResemble, []string{"/download/PortableGit-2.1.zip"})
+			So(exists(root, cache.Dir, "git", "PortableGit-2.1.zip"), ShouldBeTrue)
+			So(exists(root, "git", "PortableGit-2.1", "bin", "git.exe"), ShouldBeTrue)
+			So(New(p).Install(), ShouldBeNil)
+			So(len(downloads), ShouldEqual, 1)
+			s, _ := New(p).Status()
+			So(s.Action, ShouldEqual, ActionUpToDate)
+
+			e, err := New(p).Resolve()
+			So(err, ShouldBeNil)
+			So(e.URL, ShouldEqual, ts.URL+"/download/PortableGit-2.1.zip")
+			So(os.Remove(filepath.Join(root, cache.Dir, "git", "PortableGit-2.1.zip")), ShouldBeNil)
+			So(NewLocked(p, e).Install(), ShouldBeNil)
+			So(len(downloads), ShouldEqual, 2)
+			So(exists(root, cache.Dir, "git", "PortableGit-2.1.zip"), ShouldBeTrue)
+		})
+
+		Convey("Without network, the latest cached page is used", func() {
+			release = ""
+			defer func() { release = "PortableGit-2.1.zip" }()
+			s, err := New(p).Status()
+			So(err, ShouldBeNil)
+			So(s.Latest, ShouldEqual, "PortableGit-2.1")
+			So(ErrString(), ShouldContainSubstring, "503 Service Unavailable")
+
+			p.values["page.rel"] = ts.URL + "/other"
+			defer func() { p.values["page.rel"] = ts.URL + "/releases" }()
+			s, err = New(p).Status()
+			So(err.Error(), ShouldContainSubstring, "unable to get page '"+ts.URL+"/other', and none in cache")
+			So(s.Action, ShouldEqual, ActionUnknown)
+		})
+
+		Convey("Offline, pages and archives only come from the cache", func() {
+			upstream.SetOffline(true)
+			defer upstream.SetOffline(false)
+			release = "PortableGit-2.2.zip"
+			defer func() { release = "PortableGit-2.1.zip" }()
+			downloads = nil
+			s, err := New(p).Status()
+			So(err, ShouldBeNil)
+			So(s.Latest, ShouldEqual, "PortableGit-2.1")
+			So(New(p).Install(), ShouldBeNil)
+			So(downloads, ShouldBeEmpty)
+			So(exists(root, "git", "PortableGit-2.1"), ShouldBeTrue)
+
+			p.values["page.rel"] = ts.URL + "/other"
+			defer func() { p.values["page.rel"] = ts.URL + "/releases" }()
+			_, err = New(p).Status()
+			So(errors.Is(err, ErrNotInCache), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "url.get 'rel' of 'git': page '"+ts.URL+"/other' (offline): not in cache")
+
+			svn := &testUninstPrg{name: "svn", values: map[string]string{"test": "bin/svn.exe", "page.rel": ts.URL + "/releases"}, extractors: p.extractors}
+			err = New(svn).Install()
+			So(errors.Is(err, ErrNotInCache), ShouldBeTrue)
+			So(ErrString(), ShouldContainSubstring, "Installing 'svn' from the cache: url.get 'rel' of 'svn': page '"+ts.URL+"/releases' (offline): not in cache")
+			cache.Default(paths.NewPathDir(root)).AddPage("svn", ts.URL+"/releases", []byte(`<a href="/download/svn-1.9.zip">`))
+			err = New(svn).Install()
+			So(errors.Is(err, ErrNotInCache), ShouldBeTrue)
+			So(err.Error(), ShouldStartWith, "no archive to install for 'svn': not in cache")
+			So(ErrString(), ShouldContainSubstring, "archive 'svn-1.9.zip' of 'svn': '"+ts.URL+"/download/svn-1.9.zip' not downloaded (offline): not in cache")
+
+			e := &lockfile.Entry{Name: "svn", URL: ts.URL + "/download/svn-1.8.zip", Archive: "svn-1.8.zip", Checksum: "sha256:none"}
+			err = NewLocked(svn, e).Install()
+			So(errors.Is(err, ErrNotInCache), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "locked archive 'svn-1.8.zip' of 'svn': '"+ts.URL+"/download/svn-1.8.zip' not downloaded (offline): not in cache")
+		})
+	})
+
+	Convey("'folder.rx' only applies to cached archives if extracted from their name", t, func() {
+		SetBuffers(nil)
+		cached(root, "jdk8", "jdk-8u40-windows-x64.exe", 0, []byte("exe"))
+		cached(root, "jdk8", "jdk-8u31-windows-x64.exe", time.Hour, []byte("exe"))
+		p := &testUninstPrg{name: "jdk8", values: map[string]string{"arch": "i586,x64", "folder.rx": `>(Java SE 8(?:u\d*)?)<`},
+			extractors: []*prgs.Setting{{Name: "folder.get", Value: "src"}, {Name: "folder.rx", Value: `>(Java SE 8(?:u\d*)?)<`}}}
+		r, err := iresolve(p, cache.Default(paths.NewPathDir(root)))
+		So(err, ShouldBeNil)
+		So(r.folder, ShouldEqual, "jdk-8u40-windows-x64")
+
+		p.extractors[0].Value = "_name"
+		p.values["arch"] = "x64,x64"
+		p.values["folder.rx"] = `(jdk-8u\d+)-windows-_$arch_`
+		r, err = iresolve(p, cache.Default(paths.NewPathDir(root)))
+		So(err, ShouldBeNil)
+		So(r.folder, ShouldEqual, "jdk-8u40")
+	})
+}
@@ -0,0 +1,172 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+)
+
+// FileName is the name of the journal, in %PRGS2%:
+// one JSON event per line, appended
+const FileName = "senvgo.journal.jsonl"
+
+// MaxSize is the size above which the journal is rotated
+var MaxSize int64 = 1 << 20
+
+// Backups is the number of rotated journals kept
+// ('senvgo.journal.1.jsonl' being the most recent)
+var Backups = 3
+
+// Actions recorded in the journal
+const (
+	Install   = "install"
+	Uninstall = "uninstall"
+	Switch    = "switch"
+	Trim      = "cache-trim"
+)
+
+// Outcomes of an action
+const (
+	OK     = "ok"
+	Failed = "failed"
+)
+
+// Event is an action on a program, as recorded in the journal
+type Event struct {
+	Time    time.Time `json:"time"`
+	Program string    `json:"program"`
+	Version string    `json:"version,omitempty"`
+	Folder  string    `json:"folder,omitempty"`
+	Action  string    `json:"action"`
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+}
+
+func (e *Event) String() string {
+	res := fmt.Sprintf("%s %-10s %-10s %-8s %s", e.Time.Format("2006-01-02 15:04:05"), e.Action, e.Program, e.Outcome, e.Folder)
+	if e.Version != "" && e.Version != e.Folder {
+		res = res + " (" + e.Version + ")"
+	}
+	if e.Error != "" {
+		res = res + ": " + e.Error
+	}
+	return strings.TrimSpace(res)
+}
+
+// Journal is the append-only journal of a programs root
+type Journal struct {
+	root *paths.Path
+}
+
+var fnow func() time.Time
+
+func init() {
+	fnow = time.Now
+}
+
+// Open returns the journal of a programs root
+func Open(root *paths.Path) *Journal {
+	return &Journal{root: root}
+}
+
+// file returns the journal file, or one of its backups (n > 0),
+// on the FS of the programs root
+func (j *Journal) file(n int) *paths.Path {
+	if n == 0 {
+		return j.root.Add(FileName)
+	}
+	return j.root.Add(fmt.Sprintf("%s.%d.jsonl", strings.TrimSuffix(FileName, ".jsonl"), n))
+}
+
+// Record appends an event to the journal (timestamped now if it has no time),
+// after rotating the journal if it is above MaxSize.
+func (j *Journal) Record(e *Event) error {
+	if e.Time.IsZero() {
+		e.Time = fnow()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err = j.rotate(); err != nil {
+		return fmt.Errorf("unable to rotate journal '%s': %v", j.file(0), err)
+	}
+	f, err := j.root.FS().OpenFile(j.file(0).String(), os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write journal '%s': %v", j.file(0), err)
+	}
+	// a line cut by a crash must not swallow the event
+	last := make([]byte, 1)
+	if fi, serr := f.Stat(); serr == nil && fi.Size() > 0 {
+		if _, rerr := f.ReadAt(last, fi.Size()-1); rerr == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write journal '%s': %v", j.file(0), err)
+	}
+	return nil
+}
+
+func (j *Journal) rotate() error {
+	fi, err := j.root.FS().Stat(j.file(0).String())
+	if err != nil || fi.Size() < MaxSize {
+		return nil
+	}
+	for n := Backups; n > 0; n-- {
+		if err = j.file(n - 1).Rename(j.file(n)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if Backups == 0 {
+		return j.file(0).Remove()
+	}
+	return nil
+}
+
+// Events returns the events of a program (all events if name is empty),
+// oldest first, from the rotated journals then the journal.
+// A line which is not an event (like a line cut by a crash) is skipped.
+func (j *Journal) Events(name string) ([]*Event, error) {
+	res := []*Event{}
+	for n := Backups; n >= 0; n-- {
+		f, err := j.file(n).Open()
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read journal '%s': %v", j.file(n), err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		nline := 0
+		for scanner.Scan() {
+			nline++
+			e := &Event{}
+			if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+				godbg.Pdbgf("'%s' line %d: invalid event: %v", j.file(n), nline, err)
+				continue
+			}
+			if name == "" || e.Program == name {
+				res = append(res, e)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read journal '%s': %v", j.file(n), err)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,119 @@
+package journal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJournal(t *testing.T) {
+
+	Convey("Events are appended as JSON lines", t, func() {
+		SetBuffers(nil)
+		dir, _ := ioutil.TempDir("", "senvgo_journal")
+		defer os.RemoveAll(dir)
+		j := Open(paths.NewPathDir(dir))
+		events, err := j.Events("")
+		So(err, ShouldBeNil)
+		So(len(events), ShouldEqual, 0)
+
+		t := time.Date(2015, 4, 1, 10, 0, 0, 0, time.UTC)
+		fnow = func() time.Time { return t }
+		So(j.Record(&Event{Program: "git", Folder: "PortableGit-2.0", Version: "2.0", Action: Install, Outcome: OK}), ShouldBeNil)
+		So(j.Record(&Event{Program: "go", Folder: "go1.4", Action: Install, Outcome: Failed, Error: "no go.exe"}), ShouldBeNil)
+		So(j.Record(&Event{Program: "git", Folder: "PortableGit-2.0", Action: Uninstall, Outcome: OK}), ShouldBeNil)
+		fnow = time.Now
+		data, _ := ioutil.ReadFile(filepath.Join(dir, FileName))
+		So(strings.Split(string(data), "\n")[0], ShouldEqual,
+			`{"time":"2015-04-01T10:00:00Z","program":"git","version":"2.0","folder":"PortableGit-2.0","action":"install","outcome":"ok"}`)
+
+		events, err = j.Events("git")
+		So(err, ShouldBeNil)
+		So(len(events), ShouldEqual, 2)
+		So(events[0].String(), ShouldEqual, "2015-04-01 10:00:00 install    git        ok       PortableGit-2.0 (2.0)")
+		So(events[1].Action, ShouldEqual, Uninstall)
+		events, _ = j.Events("go")
+		So(events[0].String(), ShouldEqual, "2015-04-01 10:00:00 install    go         failed   go1.4: no go.exe")
+
+		Convey("A line cut by a crash is skipped", func() {
+			f, _ := os.OpenFile(filepath.Join(dir, FileName), os.O_APPEND|os.O_WRONLY, 0644)
+			f.WriteString(`{"time":"2015-04-01T`)
+			f.Close()
+			So(j.Record(&Event{Program: "go", Action: Install, Outcome: OK}), ShouldBeNil)
+			events, err := j.Events("")
+			So(err, ShouldBeNil)
+			So(len(events), ShouldEqual, 4)
+			So(ErrString(), ShouldContainSubstring, "line 4: invalid event")
+		})
+	})
+
+	Convey("The journal is rotated by size", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+		root.Mkdir()
+		j := Open(root)
+		maxSize, backups := MaxSize, Backups
+		MaxSize, Backups = 200, 2
+		for n := 0; n < 10; n++ {
+			So(j.Record(&Event{Program: fmt.Sprintf("prg%d", n), Action: Install, Outcome: OK}), ShouldBeNil)
+		}
+		So(root.Add(FileName).Exists(), ShouldBeTrue)
+		So(root.Add("senvgo.journal.1.jsonl").Exists(), ShouldBeTrue)
+		So(root.Add("senvgo.journal.2.jsonl").Exists(), ShouldBeTrue)
+		So(root.Add("senvgo.journal.3.jsonl").Exists(), ShouldBeFalse)
+		events, err := j.Events("")
+		So(err, ShouldBeNil)
+		So(10-len(events), ShouldBeGreaterThan, 0)
+		So(events[len(events)-1].Program, ShouldEqual, "prg9")
+		for n := 1; n < len(events); n++ {
+			So(events[n].Program > events[n-1].Program, ShouldBeTrue)
+		}
+
+		Convey("A journal which cannot be rotated returns an error", func() {
+			fs.Fail("rename", root.Add("senvgo.journal.1.jsonl").String(), os.ErrPermission)
+			for n := 0; n < 5; n++ {
+				err = j.Record(&Event{Program: "git", Action: Install, Outcome: OK})
+				if err != nil {
+					break
+				}
+			}
+			So(err.Error(), ShouldStartWith, "unable to rotate journal '")
+			fs.Fail("rename", root.Add("senvgo.journal.1.jsonl").String(), nil)
+		})
+
+		Convey("Without backups, a full journal is removed", func() {
+			Backups = 0
+			for n := 0; n < 5; n++ {
+				So(j.Record(&Event{Program: "git", Action: Install, Outcome: OK}), ShouldBeNil)
+			}
+			fi, err := fs.Stat(root.Add(FileName).String())
+			So(err, ShouldBeNil)
+			So(fi.Size() < 200, ShouldBeTrue)
+		})
+		MaxSize, Backups = maxSize, backups
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("A journal which cannot be written returns an error", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+		root.Mkdir()
+		fs.Fail("write", root.Add(FileName).String(), os.ErrPermission)
+		fs.Fail("open", root.Add(FileName).String(), os.ErrPermission)
+		j := Open(root)
+		err := j.Record(&Event{Program: "git"})
+		So(err.Error(), ShouldStartWith, "unable to write journal '")
+		_, err = j.Events("")
+		So(err.Error(), ShouldStartWith, "unable to read journal '")
+	})
+}
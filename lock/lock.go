@@ -0,0 +1,239 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+)
+
+// Dir is the lock folder name, in %PRGS2%
+const Dir = ".senvgo.lock"
+
+// exclusive is the name of the exclusive lock file, in the lock folder
+// (shared lock files are named 'shared-<pid>')
+const exclusive = "exclusive"
+
+// StaleAge is the age after which a lock is stale, even if its process
+// is still running (or cannot be checked, from another host)
+var StaleAge = 6 * time.Hour
+
+// PollInterval is how often a busy lock is checked again, when waiting for it
+var PollInterval = 500 * time.Millisecond
+
+// Mode is the mode of a lock
+type Mode int
+
+const (
+	// Exclusive is for commands writing in %PRGS2%:
+	// no other lock, exclusive or shared, can be held at the same time
+	Exclusive Mode = iota
+	// Shared is for read-only commands: any number of shared locks can be held
+	// at the same time, but not while an exclusive lock is held
+	Shared
+)
+
+func (m Mode) String() string {
+	if m == Shared {
+		return "shared"
+	}
+	return "exclusive"
+}
+
+// Holder is what a lock file records of the process holding it
+type Holder struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Mode    string    `json:"mode"`
+	Started time.Time `json:"started"`
+}
+
+func (h *Holder) String() string {
+	return fmt.Sprintf("%s lock held by pid %d on '%s' since %s", h.Mode, h.PID, h.Host, h.Started.Format("2006-01-02 15:04:05"))
+}
+
+// BusyError is returned when a lock is held by another live process
+type BusyError struct {
+	Root   string
+	Holder *Holder
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("'%s' is locked: %v (use --wait to wait for it)", e.Root, e.Holder)
+}
+
+// Lock is an advisory lock on a programs root, held until released
+type Lock struct {
+	file *paths.Path
+}
+
+var fprocessalive func(pid int) bool
+var fnow func() time.Time
+var fsleep func(d time.Duration)
+var fgetpid func() int
+var fhostname func() (string, error)
+
+func iprocessalive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess opens the process on Windows, and fails if there is none
+		p.Release()
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+func init() {
+	fprocessalive = iprocessalive
+	fnow = time.Now
+	fsleep = time.Sleep
+	fgetpid = os.Getpid
+	fhostname = os.Hostname
+}
+
+// Acquire takes a lock on a programs root.
+// A lock held by another live process returns a BusyError,
+// unless wait is set: Acquire then checks again every PollInterval.
+// A stale lock (its process is gone, or older than StaleAge) is removed.
+func Acquire(root *paths.Path, mode Mode, wait bool) (*Lock, error) {
+	dir := root.Add(Dir).SetDir()
+	if err := dir.Mkdir(); err != nil {
+		return nil, err
+	}
+	waiting := false
+	for {
+		l, busy, err := tryAcquire(dir, mode)
+		if err != nil || busy == nil {
+			return l, err
+		}
+		if !wait {
+			return nil, &BusyError{Root: root.NoSep().String(), Holder: busy}
+		}
+		if !waiting {
+			fmt.Fprintf(godbg.Err(), "Waiting for %v\n", busy)
+			waiting = true
+		}
+		fsleep(PollInterval)
+	}
+}
+
+// tryAcquire creates the lock file of a mode, then checks no other lock
+// file conflicts with it (else removes it, and returns the conflicting holder).
+// Creating first, then checking, prevents two processes from both succeeding.
+func tryAcquire(dir *paths.Path, mode Mode) (*Lock, *Holder, error) {
+	name := exclusive
+	if mode == Shared {
+		name = "shared-" + strconv.Itoa(fgetpid())
+		if h := live(dir, exclusive); h != nil {
+			return nil, h, nil
+		}
+	}
+	file := dir.Add(name)
+	if err := create(file, mode); err != nil {
+		if !os.IsExist(err) {
+			return nil, nil, fmt.Errorf("unable to lock '%v': %v", dir, err)
+		}
+		if h := live(dir, name); h != nil {
+			return nil, h, nil
+		}
+		// stale: removed by live(), try again
+		return tryAcquire(dir, mode)
+	}
+	l := &Lock{file: file}
+	for _, other := range holders(dir) {
+		if other == name || (mode == Shared && other != exclusive) {
+			continue
+		}
+		if h := live(dir, other); h != nil {
+			l.Release()
+			return nil, h, nil
+		}
+	}
+	return l, nil, nil
+}
+
+func create(file *paths.Path, mode Mode) error {
+	f, err := os.OpenFile(file.String(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	host, _ := fhostname()
+	data, _ := json.Marshal(&Holder{PID: fgetpid(), Host: host, Mode: mode.String(), Started: fnow()})
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file.String())
+	}
+	return err
+}
+
+// holders returns the lock files of a lock folder
+func holders(dir *paths.Path) []string {
+	res := []string{}
+	for _, fi := range dir.GetNameOrderedFiles("") {
+		if fi.Name() == exclusive || strings.HasPrefix(fi.Name(), "shared-") {
+			res = append(res, fi.Name())
+		}
+	}
+	return res
+}
+
+// live returns the holder of a lock file, nil if there is none,
+// or if it is stale (and then removed)
+func live(dir *paths.Path, name string) *Holder {
+	file := filepath.Join(dir.String(), name)
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	h := &Holder{}
+	if err == nil {
+		err = json.Unmarshal(data, h)
+	}
+	if err != nil {
+		// being written, or corrupted: only its age tells
+		fi, serr := os.Stat(file)
+		if serr != nil {
+			return nil
+		}
+		h = &Holder{Mode: "unknown", Started: fi.ModTime()}
+	}
+	host, _ := fhostname()
+	stale := fnow().Sub(h.Started) > StaleAge
+	if !stale && h.PID != 0 && h.Host == host {
+		stale = !fprocessalive(h.PID)
+	}
+	if stale {
+		godbg.Pdbgf("removing stale lock '%s': %v", file, h)
+		os.Remove(file)
+		return nil
+	}
+	return h
+}
+
+// Release removes the lock file. Releasing twice does nothing.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := os.Remove(l.file.String())
+	l.file = nil
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to release lock: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package lock
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// asPID acquires a lock as if from another process
+func asPID(pid int, root *paths.Path, mode Mode) (*Lock, error) {
+	fgetpid = func() int { return pid }
+	defer func() { fgetpid = os.Getpid }()
+	return Acquire(root, mode, false)
+}
+
+func TestLock(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "senvgo_lock")
+	defer os.RemoveAll(dir)
+	root := paths.NewPathDir(dir)
+	alive := map[int]bool{}
+	fprocessalive = func(pid int) bool { return alive[pid] }
+
+	Convey("An exclusive lock excludes any other lock", t, func() {
+		SetBuffers(nil)
+		alive[100] = true
+		l, err := asPID(100, root, Exclusive)
+		So(err, ShouldBeNil)
+		So(l, ShouldNotBeNil)
+		_, err = asPID(200, root, Exclusive)
+		So(err.Error(), ShouldStartWith, "'"+dir+"' is locked: exclusive lock held by pid 100 on '")
+		So(err.Error(), ShouldEndWith, "(use --wait to wait for it)")
+		_, err = asPID(200, root, Shared)
+		busy := &BusyError{}
+		So(errors.As(err, &busy), ShouldBeTrue)
+		So(busy.Holder.PID, ShouldEqual, 100)
+		So(l.Release(), ShouldBeNil)
+		So(l.Release(), ShouldBeNil)
+		l, err = asPID(200, root, Exclusive)
+		So(err, ShouldBeNil)
+		So(l.Release(), ShouldBeNil)
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("Shared locks only exclude an exclusive lock", t, func() {
+		SetBuffers(nil)
+		alive[101], alive[102] = true, true
+		l1, err := asPID(101, root, Shared)
+		So(err, ShouldBeNil)
+		l2, err := asPID(102, root, Shared)
+		So(err, ShouldBeNil)
+		_, err = asPID(200, root, Exclusive)
+		So(err.Error(), ShouldContainSubstring, "shared lock held by pid 101")
+		_, err = os.Stat(filepath.Join(dir, Dir, exclusive))
+		So(os.IsNotExist(err), ShouldBeTrue)
+		l1.Release()
+		_, err = asPID(200, root, Exclusive)
+		So(err.Error(), ShouldContainSubstring, "shared lock held by pid 102")
+		l2.Release()
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("A stale lock is removed", t, func() {
+		SetBuffers(nil)
+		alive[103] = true
+		_, err := asPID(103, root, Exclusive)
+		So(err, ShouldBeNil)
+		alive[103] = false
+		l, err := asPID(200, root, Exclusive)
+		So(err, ShouldBeNil)
+		So(ErrString(), ShouldContainSubstring, "removing stale lock '"+filepath.Join(dir, Dir, exclusive)+"': exclusive lock held by pid 103")
+		l.Release()
+
+		SetBuffers(nil)
+		alive[104] = true
+		asPID(104, root, Shared)
+		fnow = func() time.Time { return time.Now().Add(StaleAge + time.Minute) }
+		l, err = asPID(200, root, Exclusive)
+		So(err, ShouldBeNil)
+		So(ErrString(), ShouldContainSubstring, "shared lock held by pid 104")
+		fnow = time.Now
+		l.Release()
+
+		SetBuffers(nil)
+		ioutil.WriteFile(filepath.Join(dir, Dir, exclusive), []byte("{"), 0644)
+		_, err = asPID(200, root, Shared)
+		So(err.Error(), ShouldContainSubstring, "unknown lock held by pid 0")
+		os.Remove(filepath.Join(dir, Dir, exclusive))
+	})
+
+	Convey("A busy lock can be waited for", t, func() {
+		SetBuffers(nil)
+		alive[105] = true
+		l, _ := asPID(105, root, Exclusive)
+		sleeps := 0
+		fsleep = func(d time.Duration) {
+			sleeps++
+			if sleeps == 3 {
+				l.Release()
+			}
+		}
+		l2, err := Acquire(root, Exclusive, true)
+		So(err, ShouldBeNil)
+		So(sleeps, ShouldEqual, 3)
+		So(ErrString(), ShouldStartWith, "Waiting for exclusive lock held by pid 105")
+		l2.Release()
+		fsleep = time.Sleep
+	})
+
+	Convey("A root which cannot be created cannot be locked", t, func() {
+		SetBuffers(nil)
+		file := filepath.Join(dir, "file")
+		ioutil.WriteFile(file, []byte("file"), 0644)
+		_, err := Acquire(paths.NewPathDir(file), Exclusive, false)
+		So(err, ShouldNotBeNil)
+	})
+	fprocessalive = iprocessalive
+
+	Convey("The current process is alive", t, func() {
+		So(iprocessalive(os.Getpid()), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,120 @@
+package lockfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/VonC/senvgo/paths"
+)
+
+// FileName is the name of the lockfile, in %PRGS2%:
+// it can be shared for everyone to install the same releases
+const FileName = "senvgo.lock"
+
+// Entry is the release a program is locked to
+type Entry struct {
+	// Name is the program name
+	Name string `json:"name"`
+	// URL is the archive url upstream, if known
+	URL string `json:"url,omitempty"`
+	// Archive is the archive name, in the program cache folder
+	Archive string `json:"archive"`
+	// Folder is the version folder the archive is installed in
+	Folder string `json:"folder"`
+	// Version is the release version, if known
+	Version string `json:"version,omitempty"`
+	// Checksum is the archive checksum (see Checksum)
+	Checksum string `json:"checksum"`
+}
+
+// File is the lockfile of a root folder
+type File struct {
+	file    *paths.Path
+	entries map[string]*Entry
+}
+
+// Load reads the lockfile of a root folder.
+// A root without lockfile has an empty one.
+func Load(root *paths.Path) (*File, error) {
+	f := &File{file: root.Add(FileName), entries: make(map[string]*Entry)}
+	data, err := f.file.Content()
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read lockfile '%v': %v", f.file, err)
+	}
+	entries := []*Entry{}
+	if err = json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, fmt.Errorf("invalid lockfile '%v': %v", f.file, err)
+	}
+	for _, e := range entries {
+		f.entries[e.Name] = e
+	}
+	return f, nil
+}
+
+func (f *File) String() string {
+	return f.file.String()
+}
+
+// Get returns the entry of a program, nil if not locked
+func (f *File) Get(name string) *Entry {
+	return f.entries[name]
+}
+
+// Set locks a program to a release, replacing any previous entry
+func (f *File) Set(e *Entry) {
+	f.entries[e.Name] = e
+}
+
+// Names returns the names of all locked programs, sorted
+func (f *File) Names() []string {
+	res := []string{}
+	for name := range f.entries {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Save writes the lockfile, sorted by program name for stable diffs,
+// through a temporary file renamed once complete.
+func (f *File) Save() error {
+	entries := []*Entry{}
+	for _, name := range f.Names() {
+		entries = append(entries, f.entries[name])
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := paths.NewPathFS(f.file.FS(), f.file.String()+".tmp")
+	if err = tmp.WriteFile(append(data, '\n')); err != nil {
+		return fmt.Errorf("unable to write lockfile '%v': %v", f.file, err)
+	}
+	if err = tmp.Rename(f.file); err != nil {
+		return fmt.Errorf("unable to write lockfile '%v': %v", f.file, err)
+	}
+	return nil
+}
+
+// Checksum returns the checksum of a file, as 'sha256:<hex>'
+func Checksum(file *paths.Path) (string, error) {
+	r, err := file.FS().Open(file.String())
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,73 @@
+package lockfile
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLockfile(t *testing.T) {
+
+	fs := paths.NewMemFS()
+	root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+	root.Mkdir()
+
+	Convey("A root without lockfile has an empty one", t, func() {
+		SetBuffers(nil)
+		f, err := Load(root)
+		So(err, ShouldBeNil)
+		So(f.Names(), ShouldBeEmpty)
+		So(f.Get("go"), ShouldBeNil)
+
+		Convey("A lockfile can be saved and loaded back", func() {
+			f.Set(&Entry{Name: "go", Archive: "go1.4.2.windows-amd64.zip", Folder: "go1.4.2.windows-amd64", Version: "1.4.2", Checksum: "sha256:00"})
+			f.Set(&Entry{Name: "git", URL: "https://github.com/msysgit/msysgit/releases/download/Git-1.9.5/PortableGit-1.9.5.7z", Archive: "PortableGit-1.9.5.7z", Folder: "PortableGit-1.9.5"})
+			So(f.Save(), ShouldBeNil)
+			f, err = Load(root)
+			So(err, ShouldBeNil)
+			So(f.Names(), ShouldResemble, []string{"git", "go"})
+			So(f.Get("go").Version, ShouldEqual, "1.4.2")
+			So(f.Get("go").URL, ShouldBeEmpty)
+			So(f.Get("git").URL, ShouldEndWith, "/download/Git-1.9.5/PortableGit-1.9.5.7z")
+			So(f.String(), ShouldEqual, root.Add(FileName).String())
+			So(root.Add(FileName+".tmp").Exists(), ShouldBeFalse)
+			So(NoOutput(), ShouldBeTrue)
+		})
+
+		Convey("Lockfile errors are reported", func() {
+			tmp := root.Add(FileName + ".tmp").String()
+			fs.Fail("write", tmp, fmt.Errorf("disk full"))
+			err := f.Save()
+			So(err.Error(), ShouldStartWith, "unable to write lockfile '"+root.Add(FileName).String()+"'")
+			So(err.Error(), ShouldEndWith, ": disk full")
+			fs.Fail("write", tmp, nil)
+			fs.Fail("rename", tmp, fmt.Errorf("access denied"))
+			err = f.Save()
+			So(err.Error(), ShouldEndWith, ": access denied")
+			fs.Fail("rename", tmp, nil)
+
+			root.Add(FileName).WriteFile([]byte("{"))
+			_, err = Load(root)
+			So(err.Error(), ShouldStartWith, "invalid lockfile")
+			fs.Fail("open", root.Add(FileName).String(), fmt.Errorf("access denied"))
+			_, err = Load(root)
+			So(err.Error(), ShouldStartWith, "unable to read lockfile")
+			fs.Fail("open", root.Add(FileName).String(), nil)
+			root.Add(FileName).Remove()
+		})
+	})
+
+	Convey("A checksum is the sha256 of a file", t, func() {
+		file := root.Add("a.zip")
+		file.WriteFile([]byte("abc"))
+		sum, err := Checksum(file)
+		So(err, ShouldBeNil)
+		So(sum, ShouldEqual, "sha256:ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")
+		_, err = Checksum(root.Add("b.zip"))
+		So(err, ShouldNotBeNil)
+	})
+}
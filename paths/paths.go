@@ -2,6 +2,7 @@ package paths
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -14,12 +15,20 @@ import (
 // Either filename or http://...
 type Path struct {
 	path string
+	// filesys is the FS of the path operations (nil means OS)
+	filesys FS
 }
 
 // NewPath creates a new path.
 // If it is a folder, it will end with a trailing '/'
 func NewPath(p string) *Path {
-	res := &Path{path: p}
+	return NewPathFS(nil, p)
+}
+
+// NewPathFS creates a new path on a FS (nil means OS).
+// Paths derived from it (Add, Dir, ...) are on the same FS.
+func NewPathFS(fs FS, p string) *Path {
+	res := &Path{path: p, filesys: fs}
 	if strings.HasPrefix(res.path, "http") == false {
 		res.path = filepath.FromSlash(p)
 		// fmt.Printf("p '%s' vs. res.path '%s'\n", p, res.path)
@@ -37,7 +46,11 @@ func NewPath(p string) *Path {
 // NewPathDir will create a Path *always* terminated with a traling '/'.
 // Handy for folders which doesn't exist yet
 func NewPathDir(p string) *Path {
-	res := &Path{}
+	return newPathDir(nil, p)
+}
+
+func newPathDir(fs FS, p string) *Path {
+	res := &Path{filesys: fs}
 	res.path = filepath.FromSlash(p)
 	if !strings.HasSuffix(res.path, string(filepath.Separator)) {
 		res.path = res.path + string(filepath.Separator)
@@ -53,22 +66,16 @@ func (p *Path) EndsWithSeparator() bool {
 	return false
 }
 
-var fstat func(f *os.File) (fi os.FileInfo, err error)
-
-func ifstat(f *os.File) (fi os.FileInfo, err error) {
-	return f.Stat()
-}
-
 // IsDir checks is a path is an existing directory.
-// If there is any error, it is printed on Stderr, but not returned.
+// If there is any error, it is printed on Stderr, but not returned (see CheckDir).
 func (p *Path) IsDir() bool {
-	f, err := os.Open(p.path)
+	f, err := p.fs().Open(p.path)
 	if err != nil {
 		fmt.Fprintln(godbg.Err(), err)
 		return false
 	}
 	defer f.Close()
-	fi, err := fstat(f)
+	fi, err := f.Stat()
 	if err != nil {
 		fmt.Fprintln(godbg.Err(), err)
 		return false
@@ -80,13 +87,26 @@ func (p *Path) IsDir() bool {
 	return false
 }
 
+// CheckDir checks if a path is an existing directory,
+// like IsDir, but returns why it is not (as an *Error).
+func (p *Path) CheckDir() error {
+	fi, err := p.fs().Stat(filepath.FromSlash(p.NoSep().path))
+	if err != nil {
+		return newError("access folder", p, err)
+	}
+	if !fi.IsDir() {
+		return &Error{Op: "access folder", Path: p.String(), Kind: ErrNotDir, Err: ErrNotDir}
+	}
+	return nil
+}
+
 // SetDir makes sure a Path represents a folder (existing or not)
 // That means it ends with a path separator
 func (p *Path) SetDir() *Path {
 	if p.EndsWithSeparator() {
 		return p
 	}
-	return NewPathDir(p.path)
+	return newPathDir(p.filesys, p.path)
 }
 
 // Add adds a string path to a Path
@@ -94,7 +114,7 @@ func (p *Path) SetDir() *Path {
 // (existing or not it: just means making sure it ends with file separator)
 func (p *Path) Add(s string) *Path {
 	pp := p.SetDir()
-	return NewPath(pp.path + s)
+	return NewPathFS(p.filesys, pp.path+s)
 }
 
 // AddP adds a Path to a Path
@@ -113,7 +133,7 @@ func (p *Path) NoSep() *Path {
 	for strings.HasSuffix(pp, string(filepath.Separator)) {
 		pp = pp[:len(pp)-1]
 	}
-	res := &Path{}
+	res := &Path{filesys: p.filesys}
 	res.path = filepath.FromSlash(pp)
 	return res
 }
@@ -121,7 +141,7 @@ func (p *Path) NoSep() *Path {
 // AddNoSep adds a string path to a Path with no triling separator
 func (p *Path) AddNoSep(s string) *Path {
 	pp := p.NoSep()
-	return NewPath(pp.path + s)
+	return NewPathFS(p.filesys, pp.path+s)
 }
 
 // AddPNoSep adds a Path to a Path, making sure the resulting path doesn't end with a file separator
@@ -130,17 +150,11 @@ func (p *Path) AddPNoSep(path *Path) *Path {
 	return p.AddNoSep(path.String())
 }
 
-var fosstat func(name string) (fi os.FileInfo, err error)
-
-func ifosstat(name string) (fi os.FileInfo, err error) {
-	return os.Stat(name)
-}
-
 // Exists returns whether the given file or directory exists or not
 // http://stackoverflow.com/questions/10510691/how-to-check-whether-a-file-or-directory-denoted-by-a-path-exists-in-golang
 func (p *Path) Exists() bool {
 	path := filepath.FromSlash(p.path)
-	_, err := fosstat(path)
+	_, err := p.fs().Stat(path)
 	if err == nil {
 		return true
 	}
@@ -168,17 +182,11 @@ func (p *Path) String() string {
 	return res
 }
 
-var fosmkdirall func(path string, perm os.FileMode) error
-
-func ifosmkdirall(path string, perm os.FileMode) error {
-	return os.MkdirAll(path, perm)
-}
-
 // MkdirAll creates a directory named path, along with any necessary parents,
 // and return true if created, false otherwise.
-// Any error is printed on Stderr
+// Any error is printed on Stderr (see Mkdir)
 func (p *Path) MkdirAll() bool {
-	err := fosmkdirall(p.path, 0755)
+	err := p.fs().MkdirAll(p.path, 0755)
 	if err != nil {
 		fmt.Fprintf(godbg.Err(), "Error creating folder for path '%v': '%v'\n", p.path, err)
 		return false
@@ -186,44 +194,120 @@ func (p *Path) MkdirAll() bool {
 	return true
 }
 
-var fosopenfile func(name string, flag int, perm os.FileMode) (file *os.File, err error)
-
-func ifosopenfile(name string, flag int, perm os.FileMode) (file *os.File, err error) {
-	return os.OpenFile(name, flag, perm)
+// Mkdir creates a directory named path, along with any necessary parents,
+// like MkdirAll, but returns the error (as an *Error).
+func (p *Path) Mkdir() error {
+	return newError("create folder", p, p.fs().MkdirAll(p.path, 0755))
 }
 
-var fosremove func(name string) error
-
-func ifosremove(name string) error {
-	return os.Remove(name)
-}
-
-// MustOpenFile create or append a file, or panic if issue.
+// MustOpenFile create or append a file on the Path FS, or panic if issue (see OpenFile).
 // If the Path is a Dir, returns nil.
 // The caller is responsible for closing the file
-func (p *Path) MustOpenFile(append bool) (file *os.File) {
+func (p *Path) MustOpenFile(append bool) (file File) {
 	if p.IsDir() {
 		return nil
 	}
 	var err error
 	if p.Exists() {
 		if append {
-			file, err = fosopenfile(p.path, os.O_APPEND|os.O_WRONLY, 0600)
+			file, err = p.fs().OpenFile(p.path, os.O_APPEND|os.O_WRONLY, 0600)
 		} else {
-			err = fosremove(p.path)
+			err = p.fs().Remove(p.path)
 		}
 		if err != nil {
 			panic(err)
 		}
 	}
 	if file == nil {
-		if file, err = fosopenfile(p.path, os.O_CREATE|os.O_WRONLY, 0600); err != nil {
+		if file, err = p.fs().OpenFile(p.path, os.O_CREATE|os.O_WRONLY, 0600); err != nil {
 			panic(err)
 		}
 	}
 	return file
 }
 
+// OpenFile create or append a file, like MustOpenFile,
+// but on the Path FS, and returns an error (as an *Error) instead of panicking,
+// including if the Path is a Dir.
+// The caller is responsible for closing the file
+func (p *Path) OpenFile(append bool) (File, error) {
+	if p.CheckDir() == nil {
+		return nil, &Error{Op: "open file", Path: p.String(), Err: fmt.Errorf("is a folder")}
+	}
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if append {
+		flag = os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	}
+	file, err := p.fs().OpenFile(p.path, flag, 0644)
+	if err != nil {
+		return nil, newError("open file", p, err)
+	}
+	return file, nil
+}
+
+// Open opens a file (or a folder) for reading, on the Path FS.
+// The caller is responsible for closing the file
+func (p *Path) Open() (File, error) {
+	f, err := p.fs().Open(p.path)
+	if err != nil {
+		return nil, newError("open", p, err)
+	}
+	return f, nil
+}
+
+// WriteFile creates or truncates a file with data, on the Path FS
+func (p *Path) WriteFile(data []byte) error {
+	out, err := p.OpenFile(false)
+	if err != nil {
+		return err
+	}
+	if _, err = out.Write(data); err != nil {
+		out.Close()
+		return newError("write", p, err)
+	}
+	return out.Close()
+}
+
+// Copy copies a file to dst, which can be on another FS
+func (p *Path) Copy(dst *Path) error {
+	in, err := p.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := dst.OpenFile(false)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return newError("copy", p, err)
+	}
+	return out.Close()
+}
+
+// Remove removes a file or an empty folder, on the Path FS
+func (p *Path) Remove() error {
+	return newError("remove", p, p.fs().Remove(p.NoSep().path))
+}
+
+// Rename moves a file or a folder, on the Path FS
+func (p *Path) Rename(to *Path) error {
+	return newError("rename", p, p.fs().Rename(p.NoSep().path, to.NoSep().path))
+}
+
+// Symlink makes the Path a symbolic link to oldname (relative to the folder
+// of the Path, like os.Symlink), on the Path FS
+func (p *Path) Symlink(oldname string) error {
+	return newError("link", p, p.fs().Symlink(oldname, p.NoSep().path))
+}
+
+// Readlink returns the target of a symbolic link, on the Path FS
+func (p *Path) Readlink() (string, error) {
+	target, err := p.fs().Readlink(p.NoSep().path)
+	return target, newError("read link", p, err)
+}
+
 var ffpabs func(path string) (string, error)
 
 func iffpabs(path string) (string, error) {
@@ -240,9 +324,9 @@ func (p *Path) Abs() *Path {
 		return nil
 	}
 	if strings.HasSuffix(p.path, string(filepath.Separator)) {
-		return NewPathDir(res)
+		return newPathDir(p.filesys, res)
 	}
-	return NewPath(res)
+	return NewPathFS(p.filesys, res)
 }
 
 // Dir is filepath.Dir() for Path:
@@ -253,7 +337,7 @@ func (p *Path) Dir() *Path {
 	for strings.HasSuffix(pp, string(filepath.Separator)) {
 		pp = pp[:len(pp)-1]
 	}
-	return NewPathDir(filepath.Dir(pp))
+	return newPathDir(p.filesys, filepath.Dir(pp))
 }
 
 // Base is filepath.Base():
@@ -273,7 +357,7 @@ func (p *Path) Dot() *Path {
 	if strings.HasPrefix(p.path, "."+string(filepath.Separator)) {
 		return p
 	}
-	return NewPath("." + string(filepath.Separator) + p.path)
+	return NewPathFS(p.filesys, "."+string(filepath.Separator)+p.path)
 }
 
 var hasTarRx, _ = regexp.Compile(`\.tar(?:\.[^\.]+)?$`)
@@ -315,9 +399,9 @@ func (p *Path) RemoveExtension() *Path {
 		sp = sp[:len(sp)-len(ext)]
 	}
 	if p.EndsWithSeparator() {
-		return NewPathDir(sp)
+		return newPathDir(p.filesys, sp)
 	}
-	return NewPath(sp)
+	return NewPathFS(p.filesys, sp)
 }
 
 // SetExtTar add a .tar to the path after removing its current extension
@@ -410,7 +494,7 @@ func (p *Path) NoExt() *Path {
 	if endssep {
 		sp = sp + "/"
 	}
-	res := NewPath(sp)
+	res := NewPathFS(p.filesys, sp)
 	return res
 }
 
@@ -442,9 +526,5 @@ func (p *Path) IsEmpty() bool {
 }
 
 func init() {
-	fstat = ifstat
-	fosstat = ifosstat
-	fosmkdirall = ifosmkdirall
-	fosopenfile = ifosopenfile
 	ffpabs = iffpabs
 }
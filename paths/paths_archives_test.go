@@ -2,11 +2,12 @@ package paths
 
 import (
 	"archive/zip"
-	"fmt"
-	"io"
+	"bytes"
+	"errors"
+	"io/ioutil"
 	"os"
 	"os/exec"
-	"strings"
+	"path/filepath"
 	"testing"
 
 	. "github.com/VonC/godbg"
@@ -17,144 +18,179 @@ func TestArchive(t *testing.T) {
 
 	Convey("Tests for Uncompress", t, func() {
 
-		testHas7 = false
-		Convey("Uncompress fails if p is a folder", func() {
-			p := NewPath(".")
+		dir, _ := ioutil.TempDir("", "uncompress")
+		defer os.RemoveAll(dir)
+		dest := NewPathDir(filepath.Join(dir, "dest"))
+
+		Convey("Uncompress fails if p is a folder, a non-existing file, or not an archive", func() {
 			SetBuffers(nil)
-			b := p.Uncompress(nil)
-			So(b, ShouldBeFalse)
+			folder := NewPathDir(filepath.Join(dir, "folder.zip"))
+			So(folder.MkdirAll(), ShouldBeTrue)
+			_, err := folder.Uncompress(dest, false)
+			So(err, ShouldNotBeNil)
+			_, err = NewPath(filepath.Join(dir, "xxx.zip")).Uncompress(dest, false)
+			So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+			bad := NewPath(filepath.Join(dir, "bad.zip"))
+			ioutil.WriteFile(bad.String(), []byte("not a zip"), 0644)
+			_, err = bad.Uncompress(dest, false)
+			So(errors.Is(err, ErrNotArchive), ShouldBeTrue)
+		})
+
+		Convey("Uncompress detects the top folder of an archive, and keeps its structure", func() {
+			SetBuffers(nil)
+			top, err := NewPath("testzip.zip").Uncompress(dest, false)
+			So(err, ShouldBeNil)
+			So(top, ShouldEqual, "testzip")
+			So(dest.Add("testzip").Add("a.txt").Exists(), ShouldBeTrue)
+			So(dest.Add("testzip").Add("c").Add("abcd.txt").Exists(), ShouldBeTrue)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `  [*Path.Uncompress] (func)
-    Error while opening zip '.\' for '<nil>'
-'read .\: The handle is invalid.'`)
+		})
+	})
+
+	Convey("Tests for Uncompress errors", t, func() {
+
+		fs := NewMemFS()
+		arc := NewPathFS(fs, filepath.FromSlash("/arc/"))
+		So(arc.MkdirAll(), ShouldBeTrue)
+		content, _ := ioutil.ReadFile("testzip.zip")
+		p := arc.Add("testzip.zip")
+		So(p.WriteFile(content), ShouldBeNil)
+		dest := NewPathFS(fs, filepath.FromSlash("/dest/"))
+		a := dest.Add("testzip").Add("a.txt")
+
+		Convey("Uncompress fails if p is a folder", func() {
+			SetBuffers(nil)
+			folder := arc.Add("folder.zip")
+			So(folder.MkdirAll(), ShouldBeTrue)
+			top, err := folder.Uncompress(dest, false)
+			So(top, ShouldBeEmpty)
+			So(err.Error(), ShouldEqual, "unable to read archive '"+folder.NoSep().String()+"': read "+folder.NoSep().String()+": invalid argument")
 		})
 
 		Convey("Uncompress fails if p is a non-existing file", func() {
-			p := NewPath("xxx")
 			SetBuffers(nil)
-			b := p.Uncompress(nil)
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `  [*Path.Uncompress] (func)
-    Error while opening zip 'xxx' for '<nil>'
-'open xxx: The system cannot find the file specified.'`)
+			xxx := arc.Add("xxx.zip")
+			_, err := xxx.Uncompress(dest, false)
+			So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "unable to read archive '"+xxx.String()+"' (does not exist): open "+xxx.String()+": file does not exist")
 		})
 
 		Convey("Uncompress fails if p is not a zip file", func() {
-			p := NewPath("paths.go")
 			SetBuffers(nil)
-			b := p.Uncompress(nil)
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `  [*Path.Uncompress] (func)
-    Error while opening zip 'paths.go' for '<nil>'
-'zip: not a valid zip file'`)
+			bad := arc.Add("bad.zip")
+			So(bad.WriteFile([]byte("not a zip")), ShouldBeNil)
+			_, err := bad.Uncompress(dest, false)
+			So(errors.Is(err, ErrNotArchive), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "unable to read archive '"+bad.String()+"' (not an archive): zip: not a valid zip file")
 		})
 
-		Convey("cloneZipItem can fail on a particular item", func() {
-			p := NewPath("testzip.zip")
+		Convey("Uncompress can fail on a particular item", func() {
 			SetBuffers(nil)
-			testmkd = true
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [cloneZipItem] (*Path.Uncompress) (func)
-      Error while mkdir for zip element: 'testzip'`)
-			testmkd = false
+			fs.Fail("mkdir", dest.Add("testzip").String(), os.ErrPermission)
+			_, err := p.Uncompress(dest, false)
+			So(errors.Is(err, ErrPermission), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "mkdir "+dest.Add("testzip").String())
+			fs.Fail("mkdir", dest.Add("testzip").String(), nil)
 		})
 
-		Convey("cloneZipItem can fail on opening a particular item file", func() {
-			p := NewPath("testzip.zip")
+		Convey("Uncompress can fail on opening a particular item file", func() {
 			SetBuffers(nil)
-			fzipfileopen = testfzipfileopen
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [cloneZipItem] (*Path.Uncompress) (func)
-      Error while checking if zip element is a file: 'testzip/'
-'Error (Open) zip.File for 'testzip/''`)
-			fzipfileopen = ifzipfileopen
+			bad := arc.Add("method.zip")
+			So(bad.WriteFile(rawZip("a", 99, 0)), ShouldBeNil)
+			_, err := bad.Uncompress(dest, false)
+			So(errors.Is(err, ErrCorruptEntry), ShouldBeTrue)
+			So(err.Error(), ShouldEndWith, "zip: unsupported compression algorithm")
 		})
 
-		Convey("cloneZipItem can fail on creating a particular item element", func() {
-			p := NewPath("testzip.zip")
+		Convey("Uncompress can fail on creating a particular item element", func() {
 			SetBuffers(nil)
-			foscreate = testfoscreate
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [cloneZipItem] (*Path.Uncompress) (func)
-      Error while creating zip element to '.\testzip\a.txt' from 'testzip/a.txt'
-err='Error (Create) zip element '.\testzip\a.txt''`)
-			foscreate = ifoscreate
-			NewPath("testzip").DeleteFolder()
+			fs.Fail("write", a.String(), os.ErrPermission)
+			_, err := p.Uncompress(dest, false)
+			So(errors.Is(err, ErrPermission), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "write "+a.String())
+			So(a.Exists(), ShouldBeFalse)
+			fs.Fail("write", a.String(), nil)
 		})
 
-		Convey("cloneZipItem can fail on copying a particular item element", func() {
-			p := NewPath("testzip.zip")
+		Convey("Uncompress can fail on copying a particular item element", func() {
 			SetBuffers(nil)
-			fiocopy = testfiocopy
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [cloneZipItem] (*Path.Uncompress) (func)
-      Error while copying zip element to '.\testzip\a.txt' from 'testzip/a.txt'
-err='Error (io.Copy) zip element'`)
-			fiocopy = ifiocopy
-			NewPath("testzip").DeleteFolder()
+			bad := arc.Add("crc.zip")
+			So(bad.WriteFile(rawZip("a", zip.Store, 1)), ShouldBeNil)
+			_, err := bad.Uncompress(dest, false)
+			So(errors.Is(err, ErrCorruptEntry), ShouldBeTrue)
+			So(err.Error(), ShouldEndWith, "zip: checksum error")
+			So(a.Exists(), ShouldBeFalse)
 		})
 
-		Convey("cloneZipItem can fail on closing a particular item element", func() {
-			p := NewPath("testzip.zip")
+		Convey("Uncompress can fail on closing a particular item element", func() {
 			SetBuffers(nil)
-			foscloseze = testfosclose
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `      [func] (cloneZipItem) (*Path.Uncompress) (func)
-        Error while closing zip element '.\testzip\a.txt'
-err='Error (Close) closing zip element '.\testzip\a.txt''`)
-			foscloseze = ifosclose
-			NewPath("testzip").DeleteFolder()
+			fs.Fail("close", a.String(), os.ErrPermission)
+			_, err := p.Uncompress(dest, false)
+			So(errors.Is(err, ErrPermission), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "close "+a.String())
+			fs.Fail("close", a.String(), nil)
 		})
 
-		Convey("cloneZipItem can fail on closing a zip file", func() {
-			p := NewPath("testzip.zip")
+		Convey("Uncompress can fail on closing the zip archive file", func() {
 			SetBuffers(nil)
-			fosclose = testfosclose
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `      [func] (cloneZipItem) (*Path.Uncompress) (func)
-        Error while closing zip file 'testzip/'
-err='Error (Close) closing zip element 'testzip/''`)
-			fosclose = ifosclose
-			NewPath("testzip").DeleteFolder()
+			fs.Fail("close", p.String(), os.ErrPermission)
+			_, err := p.Uncompress(dest, false)
+			So(errors.Is(err, ErrPermission), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "close "+p.String())
+			So(a.FileContent(), ShouldEqual, "a\r\n")
+			fs.Fail("close", p.String(), nil)
 		})
 
-		Convey("cloneZipItem can fail on closing zip archive file", func() {
-			p := NewPath("testzip.zip")
+		Convey("Uncompress of a valid zip archive succeeds", func() {
 			SetBuffers(nil)
-			fosclosearc = testfosclose
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeFalse)
+			top, err := p.Uncompress(dest, false)
+			So(err, ShouldBeNil)
+			So(top, ShouldEqual, "testzip")
+			So(dest.Add("testzip").Add("c").Add("abcd.txt").Exists(), ShouldBeTrue)
+			So(NoOutput(), ShouldBeFalse)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [func] (*Path.Uncompress) (func)
-      Error while closing zip archive 'testzip.zip'
-err='Error (Close) closing zip element 'testzip.zip''`)
-			fosclosearc = ifosclose
-			NewPath("testzip").DeleteFolder()
 		})
+	})
 
-		Convey("cloneZipItem of a valid zip archives succeed", func() {
-			p := NewPath("testzip.zip")
+	Convey("Tests for Uncompress through 7z", t, func() {
+
+		dir, _ := ioutil.TempDir("", "uncompress7z")
+		defer os.RemoveAll(dir)
+		dest := NewPathDir(filepath.Join(dir, "dest"))
+		defer func() { fexec7z = ifexec7z }()
+
+		Convey("Uncompress fails if archive does not exist", func() {
 			SetBuffers(nil)
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeTrue)
-			So(NoOutput(), ShouldBeTrue)
-			So(NewPath("testzip").DeleteFolder(), ShouldBeNil)
+			fexec7z = func(args ...string) ([]byte, error) {
+				return []byte("7-Zip 9.20  Copyright (c) 1999-2010 Igor Pavlov  2010-11-18\n\n\nError:\ncannot find archive\n"), errors.New("exit status 2")
+			}
+			tt := NewPath(filepath.Join(dir, "tt.7z"))
+			top, err := tt.Uncompress(dest, false)
+			So(top, ShouldBeEmpty)
+			So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+			So(err.Error(), ShouldStartWith, "unable to extract '"+tt.String()+"' (does not exist): exit status 2")
+			So(err.Error(), ShouldContainSubstring, "cannot find archive")
 		})
 
+		Convey("Uncompress can uncompress an archive, respecting its directory structure", func() {
+			SetBuffers(nil)
+			fexec7z = testzip7z
+			p := NewPath(filepath.Join(dir, "testzip.7z"))
+			top, err := p.Uncompress(dest, false)
+			So(err, ShouldBeNil)
+			So(top, ShouldEqual, "testzip")
+			So(dest.Add("testzip").Add("a.txt").Exists(), ShouldBeTrue)
+			So(dest.Add("testzip").Add("c").Add("abcd.txt").Exists(), ShouldBeTrue)
+			So(dest.Add("abcd.txt").Exists(), ShouldBeFalse)
+
+			flat := NewPathDir(filepath.Join(dir, "flat"))
+			top, err = p.Uncompress(flat, true)
+			So(err, ShouldBeNil)
+			So(top, ShouldEqual, "testzip")
+			So(flat.Add("a.txt").Exists(), ShouldBeTrue)
+			So(flat.Add("c").Add("abcd.txt").Exists(), ShouldBeTrue)
+			So(flat.Add("testzip").Exists(), ShouldBeFalse)
+		})
 	})
 
 	Convey("Tests for Uncompress 7z", t, func() {
@@ -190,46 +226,8 @@ err='Error (Close) closing zip element 'testzip.zip''`)
 			defaultcmd = "7z/7z.exe"
 		})
 
-		Convey("Uncompress fails if archive does not exist", func() {
-			SetBuffers(nil)
-			testHas7 = true
-			up := NewPath("tt.zip")
-			b := up.Uncompress(NewPath("."))
-			So(b, ShouldBeFalse)
-			So(OutString(), ShouldBeEmpty)
-			err := ErrString()
-			So(err, ShouldNotBeEmpty)
-			err = strings.Replace(err, NewPath(".").Abs().String(), "", -1)
-			So(err, ShouldEqualNL, `    [*Path.uncompress7z] (*Path.Uncompress) (func)
-      Unzip: 'tt.zip' => 7zU...
-/C 7z\7z.exe x -aoa -o -pdefault -sccUTF-8 tt.zip
-    [*Path.uncompress7z] (*Path.Uncompress) (func)
-      Error invoking 7ZU '[/C 7z\7z.exe x -aoa -o -pdefault -sccUTF-8 tt.zip]'
-''
-7-Zip [64] 9.22 beta  Copyright (c) 1999-2011 Igor Pavlov  2011-04-18
-
-
-Error:
-cannot find archive
-' exit status 2'
-/C 7z\7z.exe x -aoa -o -pdefault -sccUTF-8 tt.zip`)
-			testHas7 = false
-		})
-
-		Convey("Uncompress can uncompress an archive, respecting its directory structure", func() {
-			SetBuffers(nil)
-			testHas7 = true
-			b := p.Uncompress(NewPath("."))
-			So(b, ShouldBeTrue)
-			So(NewPath("testzip/a.txt").Exists(), ShouldBeTrue)
-			So(NewPath("testzip/c/abcd.txt").Exists(), ShouldBeTrue)
-			testHas7 = false
-			So(NewPath("testzip").DeleteFolder(), ShouldBeNil)
-		})
-
 		Convey("Uncompress can uncompress an archive, all in one folder", func() {
 			SetBuffers(nil)
-			testHas7 = true
 			dest := NewPath("testzip")
 			So(dest.MkdirAll(), ShouldBeTrue)
 			b := p.uncompress7z(dest, nil, "extract", true)
@@ -238,13 +236,11 @@ cannot find archive
 			So(NewPath("testzip/abcd.txt").Exists(), ShouldBeTrue)
 			So(OutString(), ShouldBeEmpty)
 			So(ErrString(), ShouldNotBeEmpty)
-			testHas7 = false
 			So(NewPath("testzip").DeleteFolder(), ShouldBeNil)
 		})
 
 		Convey("Uncompress can extract a file of an archive", func() {
 			SetBuffers(nil)
-			testHas7 = true
 			dest := NewPath("testzip")
 			// Let's *not* create the destination folder: a file extract from an archive creates it
 			b := p.uncompress7z(dest, NewPath("testzip/a.txt"), "extract file", true)
@@ -257,7 +253,6 @@ cannot find archive
 			So(NewPath("testzip/c/abcd.txt").Exists(), ShouldBeFalse)
 			So(OutString(), ShouldBeEmpty)
 			So(ErrString(), ShouldNotBeEmpty)
-			testHas7 = false
 			So(NewPath("testzip").DeleteFolder(), ShouldBeNil)
 		})
 	})
@@ -399,6 +394,64 @@ cannot find archive
 	})
 }
 
+const testzip7zListing = `
+7-Zip 9.20  Copyright (c) 1999-2010 Igor Pavlov  2010-11-18
+
+Listing archive: testzip.7z
+
+--
+Path = testzip.7z
+Type = zip
+
+----------
+Path = testzip
+Folder = +
+Size = 0
+Attributes = D....
+
+Path = testzip\a.txt
+Folder = -
+Size = 3
+Attributes = ....A
+
+Path = testzip\b.txt
+Folder = -
+Size = 4
+Attributes = ....A
+
+Path = testzip\c
+Folder = +
+Size = 0
+Attributes = D....
+
+Path = testzip\c\abcd.txt
+Folder = -
+Size = 6
+Attributes = ....A
+`
+
+// testzip7z plays 7z on testzip.zip, whatever the archive name
+func testzip7z(args ...string) ([]byte, error) {
+	if args[0] == "l" {
+		return []byte(testzip7zListing), nil
+	}
+	dest := NewPathDir(args[2][len("-o"):])
+	return []byte("Everything is Ok"), NewPath("testzip.zip").Extract(dest)
+}
+
+// rawZip returns a zip with a 'testzip/a.txt' entry, stored as is
+// with the given method and checksum
+func rawZip(content string, method uint16, crc uint32) []byte {
+	b := new(bytes.Buffer)
+	zw := zip.NewWriter(b)
+	zw.Create("testzip/")
+	w, _ := zw.CreateRaw(&zip.FileHeader{Name: "testzip/a.txt", Method: method, CRC32: crc,
+		CompressedSize64: uint64(len(content)), UncompressedSize64: uint64(len(content))})
+	w.Write([]byte(content))
+	zw.Close()
+	return b.Bytes()
+}
+
 func check7z() error {
 	p := NewPath("7z/7z.exe")
 	if p.Exists() {
@@ -410,20 +463,6 @@ func check7z() error {
 	return err
 }
 
-func testfzipfileopen(f *zip.File) (rc io.ReadCloser, err error) {
-	return nil, fmt.Errorf("Error (Open) zip.File for '%s'", f.Name)
-}
-func testfoscreate(name string) (file *os.File, err error) {
-	return nil, fmt.Errorf("Error (Create) zip element '%s'", name)
-}
-func testfiocopy(dst io.Writer, src io.Reader) (written int64, err error) {
-	return 0, fmt.Errorf("Error (io.Copy) zip element")
-}
-func testfosclose(f io.ReadCloser, name string) (err error) {
-	ifosclose(f, name)
-	return fmt.Errorf("Error (Close) closing zip element '%v'", name)
-}
-
 /*
 C:\Users\vonc\prog\go\src\github.com\VonC\senvgo\paths>mkdir testzip
 C:\Users\vonc\prog\go\src\github.com\VonC\senvgo\paths>echo a> testzip\a.txt
@@ -0,0 +1,206 @@
+package paths
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is an archive format Compress can write
+type Format string
+
+const (
+	// Zip is the '.zip' format
+	Zip Format = "zip"
+	// TarGz is the '.tar.gz' format
+	TarGz Format = "tar.gz"
+)
+
+// FormatOf returns the format of an archive from its name, empty if unknown.
+// (No IsZip()/IsTarGz(): names like 'python-2.7.6.amd64.zip' have several dots)
+func FormatOf(archive *Path) Format {
+	name := strings.ToLower(archive.String())
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return Zip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return TarGz
+	}
+	return ""
+}
+
+// CompressOptions tunes Compress. All fields are optional.
+type CompressOptions struct {
+	// Include are patterns (see Glob) of the only files to include
+	Include []string
+	// Exclude are patterns of files or folders to exclude
+	Exclude []string
+	// ModTime is the time of all entries, DefaultModTime if zero
+	ModTime time.Time
+	// Progress is called after each file added, with the number
+	// of files done, the total number of files, and the file added
+	Progress func(done, total int, name string)
+}
+
+// DefaultModTime is the time of all archive entries, for reproducible archives
+var DefaultModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Compress writes the content of a folder in a new archive, without 7z
+// (and without any password).
+// Patterns are globs (see Glob), matched against the '/' separated path
+// of a file relative to the folder, or, for patterns without '/', its name
+// (or the name of any of its parent folders, for Exclude).
+// Entries are sorted by path, with a fixed time and no owner,
+// for the same content to always give the same archive.
+// It returns the files included; on error, no partial archive is left.
+func (p *Path) Compress(dest *Path, format Format, opts *CompressOptions) (files []string, err error) {
+	if opts == nil {
+		opts = &CompressOptions{}
+	}
+	modTime := opts.ModTime
+	if modTime.IsZero() {
+		modTime = DefaultModTime
+	}
+	if format != Zip && format != TarGz {
+		return nil, fmt.Errorf("unsupported archive format '%s' for '%v'", format, dest)
+	}
+	include, err := Globs(opts.Include...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compress '%v' in '%v': %v", p, dest, err)
+	}
+	exclude, err := Globs(opts.Exclude...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compress '%v' in '%v': %v", p, dest, err)
+	}
+	err = p.Walk(&WalkOptions{Match: include, Exclude: exclude}, func(rel string, fi os.FileInfo) error {
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to compress '%v' in '%v': %v", p, dest, err)
+	}
+	root := p.NoSep().String()
+	fs := p.fs()
+	sort.Strings(files)
+	out, err := dest.fs().OpenFile(dest.String(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, newError("create archive", dest, err)
+	}
+	var aw archiveWriter
+	if format == Zip {
+		aw = &zipWriter{zw: zip.NewWriter(out)}
+	} else {
+		gw := gzip.NewWriter(out)
+		gw.ModTime = modTime
+		aw = &tarWriter{tw: tar.NewWriter(gw), gw: gw}
+	}
+	dirs := map[string]bool{}
+	for i, name := range files {
+		if err = addDirs(aw, path.Dir(name), dirs, modTime); err != nil {
+			break
+		}
+		if err = addFile(aw, fs, filepath.Join(root, filepath.FromSlash(name)), name, modTime); err != nil {
+			break
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(files), name)
+		}
+	}
+	if cerr := aw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		dest.fs().Remove(dest.String())
+		return nil, fmt.Errorf("unable to compress '%v' in '%v': %v", p, dest, err)
+	}
+	return files, nil
+}
+
+// addDirs adds a folder entry, after its parents, if not already added
+func addDirs(aw archiveWriter, dir string, dirs map[string]bool, modTime time.Time) error {
+	if dir == "." || dirs[dir] {
+		return nil
+	}
+	if err := addDirs(aw, path.Dir(dir), dirs, modTime); err != nil {
+		return err
+	}
+	dirs[dir] = true
+	return aw.add(dir+"/", os.ModeDir|0755, 0, modTime, nil)
+}
+
+// addFile adds a file content: a link is added as the file it links to
+func addFile(aw archiveWriter, fs FS, fpath, name string, modTime time.Time) error {
+	f, err := fs.Open(fpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return aw.add(name, fi.Mode().Perm(), fi.Size(), modTime, f)
+}
+
+type archiveWriter interface {
+	// add adds a file (or a folder if its name ends with '/') to an archive
+	add(name string, mode os.FileMode, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipWriter) add(name string, mode os.FileMode, size int64, modTime time.Time, r io.Reader) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+	hdr.SetMode(mode)
+	if r == nil {
+		hdr.Method = zip.Store
+	}
+	w, err := z.zw.CreateHeader(hdr)
+	if err != nil || r == nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.zw.Close()
+}
+
+type tarWriter struct {
+	tw *tar.Writer
+	gw *gzip.Writer
+}
+
+func (t *tarWriter) add(name string, mode os.FileMode, size int64, modTime time.Time, r io.Reader) error {
+	hdr := &tar.Header{Name: name, Mode: int64(mode.Perm()), Size: size, ModTime: modTime, Typeflag: tar.TypeReg}
+	if r == nil {
+		hdr.Typeflag = tar.TypeDir
+	}
+	if err := t.tw.WriteHeader(hdr); err != nil || r == nil {
+		return err
+	}
+	_, err := io.Copy(t.tw, r)
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.gw.Close()
+}
@@ -0,0 +1,128 @@
+package paths
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func zipNames(archive *Path) []string {
+	names := []string{}
+	r, err := zip.OpenReader(archive.String())
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		names = append(names, fmt.Sprintf("%s %v", f.Name, f.Modified.UTC().Format("2006-01-02")))
+	}
+	return names
+}
+
+func tarGzNames(archive *Path) []string {
+	names := []string{}
+	f, _ := os.Open(archive.String())
+	defer f.Close()
+	gr, _ := gzip.NewReader(f)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	return names
+}
+
+func TestCompress(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "compress")
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "prg-1.2.3")
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.MkdirAll(filepath.Join(src, "doc", "html"), 0755)
+	os.MkdirAll(filepath.Join(src, "empty"), 0755)
+	for _, f := range []string{"bin/prg.exe", "bin/prg.pdb", "README", "doc/html/index.html", "doc/a.txt"} {
+		ioutil.WriteFile(filepath.Join(src, filepath.FromSlash(f)), []byte(f), 0644)
+	}
+	folder := NewPathDir(src)
+
+	Convey("A folder can be compressed natively", t, func() {
+		SetBuffers(nil)
+
+		Convey("in a zip, sorted, with parent folders first and a fixed time", func() {
+			archive := NewPath(filepath.Join(dir, "prg-1.2.3.win64.zip"))
+			So(FormatOf(archive), ShouldEqual, Zip)
+			files, err := folder.Compress(archive, Zip, nil)
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, []string{"README", "bin/prg.exe", "bin/prg.pdb", "doc/a.txt", "doc/html/index.html"})
+			So(zipNames(archive), ShouldResemble, []string{"README 1980-01-01", "bin/ 1980-01-01",
+				"bin/prg.exe 1980-01-01", "bin/prg.pdb 1980-01-01", "doc/ 1980-01-01",
+				"doc/a.txt 1980-01-01", "doc/html/ 1980-01-01", "doc/html/index.html 1980-01-01"})
+			So(NoOutput(), ShouldBeTrue)
+		})
+
+		Convey("in a tar.gz, always the same for the same content", func() {
+			archive := NewPath(filepath.Join(dir, "prg-1.2.3.tar.gz"))
+			So(FormatOf(archive), ShouldEqual, TarGz)
+			_, err := folder.Compress(archive, TarGz, nil)
+			So(err, ShouldBeNil)
+			So(len(tarGzNames(archive)), ShouldEqual, 8)
+			content := archive.FileContent()
+			os.Chtimes(filepath.Join(src, "README"), time.Now(), time.Now())
+			_, err = folder.Compress(archive, TarGz, nil)
+			So(err, ShouldBeNil)
+			So(archive.FileContent() == content, ShouldBeTrue)
+		})
+
+		Convey("with include and exclude patterns", func() {
+			archive := NewPath(filepath.Join(dir, "filtered.tar.gz"))
+			files, err := folder.Compress(archive, TarGz, &CompressOptions{Exclude: []string{"*.pdb", "html"}})
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, []string{"README", "bin/prg.exe", "doc/a.txt"})
+			files, err = folder.Compress(archive, TarGz, &CompressOptions{Include: []string{"*.txt", "bin/*.exe"}})
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, []string{"bin/prg.exe", "doc/a.txt"})
+			So(tarGzNames(archive), ShouldResemble, []string{"bin/", "bin/prg.exe", "doc/", "doc/a.txt"})
+			_, err = folder.Compress(archive, TarGz, &CompressOptions{Exclude: []string{"[a"}})
+			So(err.Error(), ShouldEndWith, "invalid pattern '[a': syntax error in pattern")
+		})
+
+		Convey("with progress callbacks and a given time", func() {
+			archive := NewPath(filepath.Join(dir, "progress.zip"))
+			progress := []string{}
+			_, err := folder.Compress(archive, Zip, &CompressOptions{
+				Include: []string{"bin/*"},
+				ModTime: time.Date(2015, 3, 8, 0, 0, 0, 0, time.UTC),
+				Progress: func(done, total int, name string) {
+					progress = append(progress, fmt.Sprintf("%d/%d %s", done, total, name))
+				}})
+			So(err, ShouldBeNil)
+			So(progress, ShouldResemble, []string{"1/2 bin/prg.exe", "2/2 bin/prg.pdb"})
+			So(zipNames(archive)[0], ShouldEqual, "bin/ 2015-03-08")
+		})
+
+		Convey("but only in a zip or a tar.gz, and never partially", func() {
+			archive := NewPath(filepath.Join(dir, "prg.7z"))
+			So(FormatOf(archive), ShouldBeEmpty)
+			_, err := folder.Compress(archive, "7z", nil)
+			So(err.Error(), ShouldStartWith, "unsupported archive format '7z'")
+			So(archive.Exists(), ShouldBeFalse)
+			none := NewPath(filepath.Join(dir, "none.zip"))
+			_, err = NewPathDir(filepath.Join(dir, "none")).Compress(none, Zip, nil)
+			So(err.Error(), ShouldStartWith, "unable to compress")
+			So(none.Exists(), ShouldBeFalse)
+		})
+	})
+}
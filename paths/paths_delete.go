@@ -0,0 +1,207 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VonC/godbg"
+)
+
+// TrashSuffix ends the name of a folder renamed aside by Delete,
+// until it is purged.
+const TrashSuffix = ".trash"
+
+// ErrLocked is the kind of a DeleteError, to be checked with errors.Is
+var ErrLocked = errors.New("locked")
+
+// DeleteRetries is the number of retries of Purge, before giving up.
+var DeleteRetries = 5
+
+// DeleteBackoff is the delay before the first retry of Purge,
+// doubled for each retry.
+var DeleteBackoff = 200 * time.Millisecond
+
+var fsleep func(d time.Duration)
+
+// DeleteError lists the files (or folders) a deletion could not remove,
+// and the last error while removing them.
+type DeleteError struct {
+	Path   string
+	Locked []string
+	Err    error
+}
+
+func (e *DeleteError) Error() string {
+	return fmt.Sprintf("unable to delete '%s': %d file(s) locked: '%s'\nlast error: %v",
+		e.Path, len(e.Locked), strings.Join(e.Locked, "', '"), e.Err)
+}
+
+// Unwrap returns the last error while removing the locked files
+func (e *DeleteError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes a DeleteError an ErrLocked
+func (e *DeleteError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Trash renames a folder aside, next to it, with the TrashSuffix:
+// it is either renamed as a whole, or left untouched.
+// It returns the renamed folder, to Purge.
+func (dir *Path) Trash() (*Path, error) {
+	name := dir.NoSep().String()
+	trash := name + TrashSuffix
+	for i := 1; ; i++ {
+		_, err := dir.fs().Stat(trash)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, newError("trash", dir, err)
+		}
+		trash = fmt.Sprintf("%s.%d%s", name, i, TrashSuffix)
+	}
+	res := newPathDir(dir.filesys, trash)
+	if err := dir.NoSep().Rename(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Purge deletes a folder and its content, retrying DeleteRetries times
+// with a growing delay (see DeleteBackoff) for files still locked.
+// If some files remain, it returns a DeleteError listing them.
+func (dir *Path) Purge() error {
+	name := dir.NoSep().String()
+	backoff := DeleteBackoff
+	for retry := 0; ; retry++ {
+		locked, err := purge(dir.fs(), name)
+		if len(locked) == 0 {
+			return nil
+		}
+		if retry >= DeleteRetries {
+			return &DeleteError{Path: name, Locked: locked, Err: err}
+		}
+		godbg.Pdbgf("retry deleting '%s' in %v: %d file(s) locked", name, backoff, len(locked))
+		fsleep(backoff)
+		backoff *= 2
+	}
+}
+
+// purge removes a file, or a folder after its content,
+// and returns what could not be removed, with the last error.
+func purge(fs FS, name string) ([]string, error) {
+	fi, err := fs.Stat(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return []string{name}, err
+	}
+	var locked []string
+	if fi.IsDir() {
+		f, err := fs.Open(name)
+		if err != nil {
+			return []string{name}, err
+		}
+		fis, err := f.Readdir(-1)
+		f.Close()
+		if err != nil {
+			return []string{name}, err
+		}
+		sort.Sort(byName(fis))
+		var last error
+		for _, child := range fis {
+			l, err := purge(fs, filepath.Join(name, child.Name()))
+			if err != nil {
+				locked, last = append(locked, l...), err
+			}
+		}
+		if len(locked) > 0 {
+			return locked, last
+		}
+	}
+	if err = fs.Remove(name); err != nil && !os.IsNotExist(err) {
+		return []string{name}, err
+	}
+	return nil, nil
+}
+
+// Delete renames a folder aside (see Trash), then purges it in the background
+// (see Purge), and returns without waiting for that purge (see WaitPurges).
+// A folder which cannot be renamed is kept whole, and an *Error is returned.
+// A folder not purged (locked files, or a process exiting first) remains
+// as a '.trash' folder, for PurgeTrash.
+func (dir *Path) Delete() error {
+	return dir.DeleteThen(nil)
+}
+
+// DeleteThen is Delete, calling then (if not nil) in the background
+// with the result of the purge, once it is done.
+func (dir *Path) DeleteThen(then func(err error)) error {
+	var trash *Path
+	if dir.Exists() {
+		var err error
+		if trash, err = dir.Trash(); err != nil {
+			return err
+		}
+	}
+	purges.Add(1)
+	go func() {
+		defer purges.Done()
+		var err error
+		if trash != nil {
+			if err = trash.Purge(); err != nil {
+				godbg.Pdbgf("'%v' will be purged on a later run: %v", trash, err)
+			}
+		}
+		if then != nil {
+			then(err)
+		}
+	}()
+	return nil
+}
+
+var purges sync.WaitGroup
+
+// WaitPurges waits for the background purges started by Delete.
+// A process can exit without it: PurgeTrash ends them on a later run.
+func WaitPurges() {
+	purges.Wait()
+}
+
+// PurgeTrash purges the '.trash' folders left within a folder
+// (up to maxDepth levels, 0 for no limit) by an earlier Delete.
+// It returns the first purge error (none for a folder which does not exist).
+func (dir *Path) PurgeTrash(maxDepth int) error {
+	if !dir.Exists() {
+		return nil
+	}
+	match, _ := Globs("*" + TrashSuffix)
+	var res error
+	err := dir.Walk(&WalkOptions{Match: match, MaxDepth: maxDepth, Dirs: true}, func(rel string, fi os.FileInfo) error {
+		if !fi.IsDir() {
+			return nil
+		}
+		godbg.Pdbgf("purging '%s' in '%v'", rel, dir)
+		if err := dir.Add(filepath.FromSlash(rel)).Purge(); err != nil && res == nil {
+			res = err
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return err
+	}
+	return res
+}
+
+func init() {
+	fsleep = time.Sleep
+}
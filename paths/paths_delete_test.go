@@ -0,0 +1,120 @@
+package paths
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDelete(t *testing.T) {
+
+	Convey("A folder is renamed aside, then purged", t, func() {
+		SetBuffers(nil)
+		fs := NewMemFS()
+		root := NewPathFS(fs, filepath.FromSlash("/prgs/git/"))
+		testTree(fs, root, "v1/bin/git.exe", "v1/README")
+		v1 := root.Add("v1")
+		So(v1.Delete(), ShouldBeNil)
+		So(v1.Exists(), ShouldBeFalse)
+		WaitPurges()
+		fis, _ := root.Find(&WalkOptions{Dirs: true})
+		So(len(fis), ShouldEqual, 0)
+		So(v1.Delete(), ShouldBeNil)
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("A folder which cannot be renamed is kept whole", t, func() {
+		SetBuffers(nil)
+		fs := NewMemFS()
+		root := NewPathFS(fs, filepath.FromSlash("/prgs/git/"))
+		testTree(fs, root, "v1/bin/git.exe")
+		v1 := root.Add("v1")
+		fs.Fail("rename", v1.NoSep().String(), os.ErrPermission)
+		err := v1.Delete()
+		So(errors.Is(err, ErrPermission), ShouldBeTrue)
+		So(v1.Add("bin").Add("git.exe").Exists(), ShouldBeTrue)
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("A trash name which cannot be checked keeps the folder whole", t, func() {
+		SetBuffers(nil)
+		fs := NewMemFS()
+		root := NewPathFS(fs, filepath.FromSlash("/prgs/git/"))
+		testTree(fs, root, "v1/bin/git.exe")
+		v1 := root.Add("v1")
+		fs.Fail("stat", v1.NoSep().String()+TrashSuffix, os.ErrPermission)
+		_, err := v1.Trash()
+		So(errors.Is(err, ErrPermission), ShouldBeTrue)
+		So(err.Error(), ShouldStartWith, "unable to trash '"+v1.String()+"'")
+		So(errors.Is(v1.Delete(), ErrPermission), ShouldBeTrue)
+		So(v1.Add("bin").Add("git.exe").Exists(), ShouldBeTrue)
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("Delete returns before the purge is done", t, func() {
+		SetBuffers(nil)
+		retried := make(chan bool)
+		release := make(chan bool)
+		fsleep = func(d time.Duration) {
+			retried <- true
+			<-release
+		}
+		fs := NewMemFS()
+		root := NewPathFS(fs, filepath.FromSlash("/prgs/git/"))
+		testTree(fs, root, "v1/bin/git.exe")
+		v1 := root.Add("v1")
+		trash := NewPathFS(fs, v1.NoSep().String()+TrashSuffix)
+		gitexe := trash.Add("bin").Add("git.exe")
+		fs.Fail("remove", gitexe.String(), os.ErrPermission)
+		So(v1.Delete(), ShouldBeNil)
+		So(v1.Exists(), ShouldBeFalse)
+		<-retried
+		So(gitexe.Exists(), ShouldBeTrue)
+		fs.Fail("remove", gitexe.String(), nil)
+		close(release)
+		WaitPurges()
+		So(trash.Exists(), ShouldBeFalse)
+		fsleep = time.Sleep
+	})
+
+	Convey("Locked files are retried, reported, and purged later", t, func() {
+		SetBuffers(nil)
+		sleeps := []time.Duration{}
+		fsleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+		fs := NewMemFS()
+		root := NewPathFS(fs, filepath.FromSlash("/prgs/"))
+		testTree(fs, root, "git/v1/bin/git.exe", "git/v1/bin/sh.exe", "git/v1/README", "git/v1.trash/x", "git/v2/a")
+		trash := filepath.FromSlash("/prgs/git/v1.1.trash")
+		gitexe := filepath.Join(trash, "bin", "git.exe")
+		fs.Fail("remove", gitexe, os.ErrPermission)
+		var err error
+		So(root.Add("git").Add("v1").DeleteThen(func(purged error) { err = purged }), ShouldBeNil)
+		WaitPurges()
+		So(errors.Is(err, ErrLocked), ShouldBeTrue)
+		So(errors.Is(err, os.ErrPermission), ShouldBeTrue)
+		So(err.(*DeleteError).Locked, ShouldResemble, []string{gitexe})
+		So(err.Error(), ShouldStartWith, "unable to delete '"+trash+"': 1 file(s) locked: '"+gitexe+"'")
+		So(len(sleeps), ShouldEqual, DeleteRetries)
+		So(sleeps[1], ShouldEqual, 2*DeleteBackoff)
+		So(NewPathFS(fs, filepath.Join(trash, "README")).Exists(), ShouldBeFalse)
+		So(ErrString(), ShouldContainSubstring, "retry deleting '"+trash+"'")
+		So(ErrString(), ShouldContainSubstring, "'"+trash+string(filepath.Separator)+"' will be purged on a later run: unable to delete")
+
+		SetBuffers(nil)
+		So(root.PurgeTrash(2), ShouldNotBeNil)
+		fs.Fail("remove", gitexe, nil)
+		sleeps = []time.Duration{}
+		So(root.PurgeTrash(2), ShouldBeNil)
+		So(len(sleeps), ShouldEqual, 0)
+		fis, _ := root.Find(&WalkOptions{Dirs: true})
+		So(names(fis), ShouldResemble, []string{"git", "git/v2", "git/v2/a"})
+		So(ErrString(), ShouldContainSubstring, "purging 'git/v1.1.trash'")
+		So(NewPath("xxx_no_dir").PurgeTrash(0), ShouldBeNil)
+		fsleep = time.Sleep
+	})
+}
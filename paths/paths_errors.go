@@ -0,0 +1,92 @@
+package paths
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Kinds of Path errors, to be checked with errors.Is
+var (
+	ErrNotExist     = errors.New("does not exist")
+	ErrPermission   = errors.New("permission denied")
+	ErrNotDir       = errors.New("not a folder")
+	ErrNotArchive   = errors.New("not an archive")
+	ErrCorruptEntry = errors.New("corrupt archive entry")
+)
+
+// Error is the error of a Path operation, with its kind (ErrNotExist, ...),
+// nil if unknown, and its cause.
+type Error struct {
+	Op   string
+	Path string
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Kind == nil || e.Kind == e.Err {
+		return fmt.Sprintf("unable to %s '%s': %v", e.Op, e.Path, e.Err)
+	}
+	return fmt.Sprintf("unable to %s '%s' (%v): %v", e.Op, e.Path, e.Kind, e.Err)
+}
+
+// Unwrap returns the cause of the error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is checks the kind of the error
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// newError returns a Path error, its kind deduced from its cause.
+func newError(op string, p *Path, err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Op: op, Path: p.String(), Kind: kindOf(err), Err: err}
+}
+
+func kindOf(err error) error {
+	var ferr flate.CorruptInputError
+	switch {
+	case os.IsNotExist(err):
+		return ErrNotExist
+	case os.IsPermission(err):
+		return ErrPermission
+	case errors.Is(err, zip.ErrFormat), errors.Is(err, gzip.ErrHeader), errors.Is(err, tar.ErrHeader):
+		return ErrNotArchive
+	case errors.Is(err, zip.ErrChecksum), errors.Is(err, zip.ErrAlgorithm), errors.Is(err, gzip.ErrChecksum),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &ferr):
+		return ErrCorruptEntry
+	}
+	return nil
+}
+
+// kindOf7z deduces an error kind from the output of a failed 7z command
+func kindOf7z(out []byte) error {
+	s := strings.ToLower(string(out))
+	switch {
+	case strings.Contains(s, "cannot find"), strings.Contains(s, "can not find"):
+		return ErrNotExist
+	case strings.Contains(s, "access is denied"):
+		return ErrPermission
+	case strings.Contains(s, "can not open the file as archive"), strings.Contains(s, "cannot open the file as archive"),
+		strings.Contains(s, "can not open file as archive"):
+		return ErrNotArchive
+	case strings.Contains(s, "crc failed"), strings.Contains(s, "data error"), strings.Contains(s, "unexpected end"):
+		return ErrCorruptEntry
+	}
+	return nil
+}
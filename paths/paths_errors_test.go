@@ -0,0 +1,112 @@
+package paths
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrors(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "errors")
+	defer os.RemoveAll(dir)
+	file := NewPath(filepath.Join(dir, "a.txt"))
+	ioutil.WriteFile(file.String(), []byte("a"), 0644)
+	none := NewPath(filepath.Join(dir, "none"))
+
+	Convey("Path errors are typed", t, func() {
+		SetBuffers(nil)
+		err := none.CheckDir()
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+		So(errors.Is(err, os.ErrNotExist), ShouldBeTrue)
+		So(err.Error(), ShouldStartWith, "unable to access folder '"+none.String()+"' (does not exist): ")
+		err = file.CheckDir()
+		So(errors.Is(err, ErrNotDir), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "unable to access folder '"+file.String()+"': not a folder")
+		So(NewPathDir(dir).CheckDir(), ShouldBeNil)
+		_, err = none.Content()
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+		content, err := file.Content()
+		So(err, ShouldBeNil)
+		So(content, ShouldEqual, "a")
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("Permission errors are detected", func() {
+			fs := NewMemFS()
+			fs.Fail("mkdir", none.String(), os.ErrPermission)
+			err := NewPathFS(fs, none.String()).Mkdir()
+			So(errors.Is(err, ErrPermission), ShouldBeTrue)
+			So(errors.Is(err, ErrNotExist), ShouldBeFalse)
+			So(NewPath(filepath.Join(dir, "b", "c")).Mkdir(), ShouldBeNil)
+		})
+
+		Convey("Files can be opened without panic", func() {
+			f, err := file.OpenFile(true)
+			So(err, ShouldBeNil)
+			f.Write([]byte("b"))
+			f.Close()
+			So(file.FileContent(), ShouldEqual, "ab")
+			_, err = NewPathDir(dir).OpenFile(false)
+			So(err.Error(), ShouldEqual, "unable to open file '"+NewPathDir(dir).String()+"': is a folder")
+		})
+
+		Convey("Folders are read with a checked pattern", func() {
+			fis, err := NewPathDir(dir).ReadDir(`\.txt$`)
+			So(err, ShouldBeNil)
+			So(len(fis), ShouldEqual, 1)
+			_, err = NewPathDir(dir).ReadDir(`(`)
+			So(err.Error(), ShouldStartWith, "invalid pattern '(' for")
+			_, err = none.ReadDir("")
+			So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+		})
+
+		Convey("Archive errors are typed", func() {
+			dest := NewPathDir(filepath.Join(dir, "dest"))
+			notzip := NewPath(filepath.Join(dir, "a.zip"))
+			ioutil.WriteFile(notzip.String(), []byte("not a zip"), 0644)
+			err := notzip.Extract(dest)
+			So(errors.Is(err, ErrNotArchive), ShouldBeTrue)
+			So(err.Error(), ShouldStartWith, "unable to read archive '"+notzip.String()+"' (not an archive): ")
+			err = NewPath(filepath.Join(dir, "none.tar.gz")).Extract(dest)
+			So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+
+			b := new(bytes.Buffer)
+			zw := zip.NewWriter(b)
+			w, _ := zw.Create("ok.txt")
+			w.Write([]byte("ok"))
+			w, _ = zw.Create("../evil.txt")
+			w.Write([]byte("evil"))
+			zw.Close()
+			evil := NewPath(filepath.Join(dir, "evil.zip"))
+			ioutil.WriteFile(evil.String(), b.Bytes(), 0644)
+			err = evil.Extract(dest)
+			So(errors.Is(err, ErrCorruptEntry), ShouldBeTrue)
+			So(err.Error(), ShouldEndWith, "entry '../evil.txt' outside of '"+dest.String()+"'")
+			So(dest.Add("ok.txt").Exists(), ShouldBeTrue)
+			So(NewPath(filepath.Join(dir, "evil.txt")).Exists(), ShouldBeFalse)
+
+			corrupt := NewPath(filepath.Join(dir, "corrupt.zip"))
+			content := b.Bytes()
+			content[bytes.Index(content, []byte("ok.txt"))+len("ok.txt")] ^= 0xff
+			ioutil.WriteFile(corrupt.String(), content, 0644)
+			err = corrupt.Extract(NewPathDir(filepath.Join(dir, "dest2")))
+			So(errors.Is(err, ErrCorruptEntry), ShouldBeTrue)
+		})
+
+		Convey("7z errors are typed from its output", func() {
+			fexec7z = func(args ...string) ([]byte, error) {
+				return []byte("ERROR: a.exe\nCan not open the file as archive"), errors.New("exit status 2")
+			}
+			err := NewPath(filepath.Join(dir, "a.exe")).Extract(NewPathDir(filepath.Join(dir, "dest3")))
+			So(errors.Is(err, ErrNotArchive), ShouldBeTrue)
+			fexec7z = ifexec7z
+		})
+	})
+}
@@ -0,0 +1,151 @@
+package paths
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FS is the filesystem Path operations run through:
+// OS by default, or a MemFS in tests (see NewPathFS).
+type FS interface {
+	Stat(name string) (os.FileInfo, error)
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	Symlink(oldname, newname string) error
+	Readlink(name string) (string, error)
+}
+
+// File is a file opened by a FS
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+	Readdir(n int) ([]os.FileInfo, error)
+}
+
+// OS is the FS of the operating system
+var OS FS = osFS{}
+
+// osFS calls the os package directly:
+// tests use a MemFS instead (see NewPathFS).
+type osFS struct{}
+
+type osFile struct {
+	*os.File
+}
+
+func (f osFile) Stat() (os.FileInfo, error) {
+	return f.File.Stat()
+}
+
+func (f osFile) Readdir(n int) ([]os.FileInfo, error) {
+	return f.File.Readdir(n)
+}
+
+func (osFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) Open(name string) (File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return osFile{f}, nil
+}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return osFile{f}, nil
+}
+
+func (osFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFS) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (osFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Symlink makes newname a link to oldname:
+// a directory junction on Windows (see symlink)
+func (osFS) Symlink(oldname, newname string) error {
+	return symlink(oldname, newname)
+}
+
+func (osFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+// fs returns the FS of a Path, OS by default
+func (p *Path) fs() FS {
+	if p == nil || p.filesys == nil {
+		return OS
+	}
+	return p.filesys
+}
+
+// FS returns the filesystem the Path operations run through
+func (p *Path) FS() FS {
+	return p.fs()
+}
+
+// walkFS is filepath.Walk on a FS: the root, then its content
+// in lexical order, folders before their content.
+func walkFS(fs FS, root string, fn filepath.WalkFunc) error {
+	fi, err := fs.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	err = walkFSDir(fs, root, fi, fn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walkFSDir(fs FS, name string, fi os.FileInfo, fn filepath.WalkFunc) error {
+	if err := fn(name, fi, nil); err != nil || !fi.IsDir() {
+		return err
+	}
+	f, err := fs.Open(name)
+	if err != nil {
+		return fn(name, fi, err)
+	}
+	fis, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return fn(name, fi, err)
+	}
+	sort.Sort(byName(fis))
+	for _, child := range fis {
+		err = walkFSDir(fs, filepath.Join(name, child.Name()), child, fn)
+		if err == filepath.SkipDir && child.IsDir() {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
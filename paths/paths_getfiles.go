@@ -10,38 +10,28 @@ import (
 	"github.com/VonC/godbg"
 )
 
-var fosopen func(name string) (file *os.File, err error)
-
-func ifosopen(name string) (file *os.File, err error) {
-	file, err = os.Open(name)
-	//fmt.Printf("ifosopen '%v' => %+v\n", name, file)
-	return
-}
-
-var fosfreaddir func(f *os.File, n int) (fi []os.FileInfo, err error)
-
-func ifosfreaddir(f *os.File, n int) (fi []os.FileInfo, err error) {
-	return f.Readdir(n)
-}
-
 // GetFiles returns all files and folders within a dir, matching a pattern.
 // If the dir is not an actual existing dir, returns an empty list.
 // Empty pattern means all files and subfolders are returned.
-// This is not recursive.
+// An invalid pattern returns nil, like an unreadable dir.
+// This is not recursive (see ReadDir, and Walk or Find).
 func (dir *Path) GetFiles(pattern string) []os.FileInfo {
-	if dir.IsDir() == false {
+	f, err := dir.fs().Open(dir.String())
+	if os.IsNotExist(err) {
 		return []os.FileInfo{}
 	}
-	f, err := fosopen(dir.String())
 	if err != nil {
 		godbg.Pdbgf("Error while opening dir '%v': '%v'\n", dir, err)
 		return nil
 	}
 	defer f.Close()
+	if fi, err := f.Stat(); err != nil || !fi.IsDir() {
+		return []os.FileInfo{}
+	}
 	//fmt.Printf("=> %+v\n", f)
 	filteredList := []os.FileInfo{}
 	res := filteredList
-	list, err := fosfreaddir(f, -1)
+	list, err := f.Readdir(-1)
 	if err != nil {
 		godbg.Pdbgf("Error while reading dir '%v': '%v'\n", dir, err)
 		return nil
@@ -49,7 +39,11 @@ func (dir *Path) GetFiles(pattern string) []os.FileInfo {
 	if len(list) == 0 {
 		return res
 	}
-	rx := regexp.MustCompile(pattern)
+	rx, err := regexp.Compile(pattern)
+	if err != nil {
+		godbg.Pdbgf("Invalid pattern '%v' for dir '%v': '%v'\n", pattern, dir, err)
+		return nil
+	}
 	for _, fi := range list {
 		if pattern == "" || rx.MatchString(fi.Name()) {
 			filteredList = append(filteredList, fi)
@@ -63,6 +57,35 @@ func (dir *Path) GetFiles(pattern string) []os.FileInfo {
 	return res
 }
 
+// ReadDir returns all files and folders within a dir, matching a pattern,
+// like GetFiles, but returns an error (as an *Error) if the dir cannot be read,
+// or if the pattern is not a valid regexp.
+func (dir *Path) ReadDir(pattern string) ([]os.FileInfo, error) {
+	rx, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s' for '%v': %v", pattern, dir, err)
+	}
+	if err = dir.CheckDir(); err != nil {
+		return nil, err
+	}
+	f, err := dir.fs().Open(dir.String())
+	if err != nil {
+		return nil, newError("read folder", dir, err)
+	}
+	defer f.Close()
+	list, err := f.Readdir(-1)
+	if err != nil {
+		return nil, newError("read folder", dir, err)
+	}
+	res := []os.FileInfo{}
+	for _, fi := range list {
+		if rx.MatchString(fi.Name()) {
+			res = append(res, fi)
+		}
+	}
+	return res, nil
+}
+
 // https://groups.google.com/forum/#!topic/golang-nuts/Q7hYQ9GdX9Q
 
 type byDate []os.FileInfo
@@ -129,16 +152,11 @@ func (dir *Path) GetLastModifiedFile(pattern string) string {
 	return filteredList[0].Name()
 }
 
-var fosremoveall func(path string) (err error)
-
-func ifosremoveall(path string) (err error) {
-	return os.RemoveAll(path)
-}
-
 // DeleteFolder deletes all content (files and subfolders) of a directory.
 // Then delete the directoriy itself
 // Does nothing if dir is a file.
 // return the error ot the first os.RemoveAll issue
+// (see Delete, for a folder with files which may be locked)
 func (dir *Path) DeleteFolder() error {
 	if dir.IsDir() == false {
 		return nil
@@ -150,22 +168,16 @@ func (dir *Path) DeleteFolder() error {
 	var err, res error
 	for _, fi := range files {
 		fpath := filepath.Join(dir.String(), fi.Name())
-		err := fosremoveall(fpath)
+		err := dir.fs().RemoveAll(fpath)
 		if err != nil {
 			res = fmt.Errorf("error removing file '%v' in '%v': '%v'\n", fi.Name(), dir, err)
 			return res
 		}
 	}
-	err = fosremoveall(dir.String())
+	err = dir.fs().RemoveAll(dir.String())
 	if err != nil {
 		res = fmt.Errorf("error removing dir '%v': '%v'\n", dir, err)
 		return res
 	}
 	return nil
 }
-
-func init() {
-	fosopen = ifosopen
-	fosremoveall = ifosremoveall
-	fosfreaddir = ifosfreaddir
-}
@@ -1,8 +1,10 @@
 package paths
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -10,465 +12,293 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 )
 
+// getFilesDir returns a folder, on a MemFS, with the files 'f1.go' to 'f6':
+// 'fn' was modified n hours ago. It has an empty sub-folder 'empty'.
+func getFilesDir() (*MemFS, *Path) {
+	fs := NewMemFS()
+	dir := NewPathFS(fs, filepath.FromSlash("/getfiles/"))
+	dir.Mkdir()
+	for _, name := range []string{"f4.go", "f1.go", "f6", "f5.go", "f3", "f2"} {
+		file := dir.Add(name)
+		file.WriteFile(nil)
+		hours, _ := strconv.Atoi(name[1:2])
+		t := time.Now().Add(-time.Duration(hours) * time.Hour)
+		fs.Chtimes(file.String(), t, t)
+	}
+	return fs, dir
+}
+
 func TestPathGetFiles(t *testing.T) {
 
 	Convey("Tests for GetFiles(pattern)", t, func() {
 
+		fs, dir := getFilesDir()
+		empty := dir.Add("empty").SetDir()
+
 		Convey("GetFiles() returns empty list is not IsDir", func() {
 			SetBuffers(nil)
-			dir := NewPathDir("xxx1")
-			files := dir.GetFiles("")
-			SetBuffers(nil)
+			files := dir.Add("xxx1").SetDir().GetFiles("")
 			So(len(files), ShouldEqual, 0)
 			So(NoOutput(), ShouldBeTrue)
 		})
 
 		Convey("GetFiles() can fail opening the directory", func() {
-			dir := NewPathDir("..")
 			SetBuffers(nil)
-			fosopen = testfosopen
+			fs.Fail("open", dir.String(), errors.New("I/O error"))
 			files := dir.GetFiles("err")
 			So(files, ShouldBeNil)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `  [*Path.GetFiles:269] (func.055:477)
-    Error while opening dir '..\': 'Error os.Open for '..\''`)
-			fosopen = ifosopen
+			So(ErrString(), ShouldContainSubstring, "Error while opening dir '"+dir.String()+"'")
+			fs.Fail("open", dir.String(), nil)
 		})
 
 		Convey("GetFiles() can fail listing files the directory", func() {
-			dir := NewPathDir("../..")
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
+			fs.Fail("readdir", dir.String(), errors.New("I/O error"))
 			files := dir.GetFiles("errlist")
 			So(files, ShouldBeNil)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `  [*Path.GetFiles] (func)
-    Error while reading dir '..\..\': 'Error file.Readdir for '..\..\' (-1)'`)
-			fosfreaddir = ifosfreaddir
+			So(ErrString(), ShouldContainSubstring, "Error while reading dir '"+dir.String()+"'")
+			fs.Fail("readdir", dir.String(), nil)
 		})
 
 		Convey("GetFiles() returns an empty list for an empty folder", func() {
-			dir := NewPathDir("../../..")
+			So(empty.Mkdir(), ShouldBeNil)
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			files := dir.GetFiles("emptyfolder")
+			files := empty.GetFiles("emptyfolder")
 			So(files, ShouldNotBeNil)
 			So(len(files), ShouldEqual, 0)
 			So(NoOutput(), ShouldBeTrue)
-			fosfreaddir = ifosfreaddir
+			So(empty.Remove(), ShouldBeNil)
 		})
 
 		Convey("GetFiles() with empty patterns return all files", func() {
-			dir := NewPathDir(".")
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
 			files := dir.GetFiles("")
 			So(len(files), ShouldEqual, 6)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f4.go f1.go f6 f5.go f3 f2]`)
+			So(fmt.Sprint(names(files)), ShouldEqual, `[f1.go f2 f3 f4.go f5.go f6]`)
 			So(NoOutput(), ShouldBeTrue)
-			fosfreaddir = ifosfreaddir
 		})
 
 		Convey("GetFiles() with bad pattern return no files and a warning", func() {
-			dir := NewPathDir(".")
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
 			files := dir.GetFiles("^g.*$")
 			So(len(files), ShouldEqual, 0)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `  [*Path.GetFiles] (func)
-    NO FILE in '.\' for '^g.*$'`)
-			fosfreaddir = ifosfreaddir
+			So(ErrString(), ShouldContainSubstring, "NO FILE in '"+dir.String()+"' for '^g.*$'")
 		})
 
 		Convey("GetFiles() with patterns return selected files", func() {
-			dir := NewPathDir(".")
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
 			files := dir.GetFiles("f[236]")
-			So(len(files), ShouldEqual, 3)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f6 f3 f2]`)
-			So(NoOutput(), ShouldBeTrue)
-
+			So(fmt.Sprint(names(files)), ShouldEqual, `[f2 f3 f6]`)
 			files = dir.GetFiles(`.*\.go`)
-			So(len(files), ShouldEqual, 3)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f4.go f1.go f5.go]`)
+			So(fmt.Sprint(names(files)), ShouldEqual, `[f1.go f4.go f5.go]`)
 			So(NoOutput(), ShouldBeTrue)
-
-			fosfreaddir = ifosfreaddir
 		})
 
 	})
 
 	Convey("Tests for GetDateOrderedFiles(pattern)", t, func() {
 
+		fs, dir := getFilesDir()
+
 		Convey("GetDateOrderedFiles() returns empty list is not IsDir", func() {
-			dir := NewPathDir("xxx2")
-			files := dir.GetDateOrderedFiles("")
 			SetBuffers(nil)
+			files := dir.Add("xxx2").SetDir().GetDateOrderedFiles("")
 			So(len(files), ShouldEqual, 0)
 			So(NoOutput(), ShouldBeTrue)
 		})
 
-		Convey("GetDateOrderedFiles() can fail opening the directory", func() {
-			dir := NewPathDir("..")
+		Convey("GetDateOrderedFiles() can fail listing files the directory", func() {
 			SetBuffers(nil)
-			fosopen = testfosopen
-			files := dir.GetDateOrderedFiles("err")
+			fs.Fail("readdir", dir.String(), errors.New("I/O error"))
+			files := dir.GetDateOrderedFiles("errlist")
 			So(files, ShouldBeNil)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [*Path.GetFiles] (*Path.GetDateOrderedFiles) (func)
-      Error while opening dir '..\': 'Error os.Open for '..\''`)
-			fosopen = ifosopen
+			So(ErrString(), ShouldContainSubstring, "Error while reading dir '"+dir.String()+"'")
+			fs.Fail("readdir", dir.String(), nil)
 		})
 
-		Convey("GetDateOrderedFiles() can fail listing files the directory", func() {
-			dir := NewPathDir("../..")
+		Convey("GetDateOrderedFiles() can fail opening the directory", func() {
+			fs := NewMemFS()
+			fs.Fail("open", "..", fmt.Errorf("Error os.Open for '%s'", `..\`))
+			dir := NewPathFS(fs, "..").SetDir()
 			SetBuffers(nil)
-			fosopen = ifosopen
-			fosfreaddir = testfosfreaddir
-			files := dir.GetDateOrderedFiles("errlist")
+			files := dir.GetDateOrderedFiles("err")
 			So(files, ShouldBeNil)
 			So(OutString(), ShouldBeEmpty)
 			So(ErrString(), ShouldEqualNL, `    [*Path.GetFiles] (*Path.GetDateOrderedFiles) (func)
-      Error while reading dir '..\..\': 'Error file.Readdir for '..\..\' (-1)'`)
-			fosfreaddir = ifosfreaddir
+      Error while opening dir '..\': 'open ..: Error os.Open for '..\''`)
 		})
 
 		Convey("GetDateOrderedFiles() returns an empty list for an empty folder", func() {
-			dir := NewPathDir("../../..")
+			fs := NewMemFS()
+			fs.MkdirAll("../../..", 0755)
+			dir := NewPathFS(fs, "../../..").SetDir()
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
 			files := dir.GetDateOrderedFiles("emptyfolder")
 			So(files, ShouldNotBeNil)
 			So(len(files), ShouldEqual, 0)
 			So(NoOutput(), ShouldBeTrue)
-			fosfreaddir = ifosfreaddir
-		})
-
-		Convey("GetDateOrderedFiles() with empty patterns return all files, ordered from most recent to oldest", func() {
-			dir := NewPathDir(".")
-			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			files := dir.GetDateOrderedFiles("")
-			So(len(files), ShouldEqual, 6)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f1.go f2 f3 f4.go f5.go f6]`)
-			So(NoOutput(), ShouldBeTrue)
-			fosfreaddir = ifosfreaddir
 		})
 
 		Convey("GetDateOrderedFiles() with bad pattern return no files and a warning", func() {
-			dir := NewPathDir(".")
+			fs := NewMemFS()
+			for _, name := range []string{"f4.go", "f1.go", "f6", "f5.go", "f3", "f2"} {
+				NewPathFS(fs, name).WriteFile(nil)
+			}
+			dir := NewPathFS(fs, ".").SetDir()
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
 			files := dir.GetDateOrderedFiles("^g.*$")
 			So(len(files), ShouldEqual, 0)
 			So(OutString(), ShouldBeEmpty)
 			So(ErrString(), ShouldEqualNL, `    [*Path.GetFiles] (*Path.GetDateOrderedFiles) (func)
       NO FILE in '.\' for '^g.*$'`)
-			fosfreaddir = ifosfreaddir
 		})
 
-		Convey("GetDateOrderedFiles() with patterns return selected files, ordered from most recent to oldest", func() {
-			dir := NewPathDir(".")
+		Convey("GetDateOrderedFiles() return selected files, ordered from most recent to oldest", func() {
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			files := dir.GetDateOrderedFiles("f[236]")
-			So(len(files), ShouldEqual, 3)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f2 f3 f6]`)
-			So(NoOutput(), ShouldBeTrue)
-
-			files = dir.GetDateOrderedFiles(`.*\.go`)
-			So(len(files), ShouldEqual, 3)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f1.go f4.go f5.go]`)
+			files := dir.GetDateOrderedFiles("")
+			So(fmt.Sprint(names(files)), ShouldEqual, `[f1.go f2 f3 f4.go f5.go f6]`)
+			files = dir.GetDateOrderedFiles("f[236]")
+			So(fmt.Sprint(names(files)), ShouldEqual, `[f2 f3 f6]`)
 			So(NoOutput(), ShouldBeTrue)
-
-			fosfreaddir = ifosfreaddir
 		})
-
 	})
 
 	Convey("Tests for GetNameOrderedFiles(pattern)", t, func() {
 
-		Convey("GetNameOrderedFiles() returns empty list is not IsDir", func() {
-			dir := NewPathDir("xxx3")
-			files := dir.GetNameOrderedFiles("")
-			SetBuffers(nil)
-			So(len(files), ShouldEqual, 0)
-			So(NoOutput(), ShouldBeTrue)
-		})
+		fs, dir := getFilesDir()
 
 		Convey("GetNameOrderedFiles() can fail opening the directory", func() {
-			dir := NewPathDir("..")
 			SetBuffers(nil)
-			fosopen = testfosopen
+			fs.Fail("open", dir.String(), errors.New("I/O error"))
 			files := dir.GetNameOrderedFiles("err")
 			So(files, ShouldBeNil)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [*Path.GetFiles] (*Path.GetNameOrderedFiles) (func)
-      Error while opening dir '..\': 'Error os.Open for '..\''`)
-			fosopen = ifosopen
+			So(ErrString(), ShouldContainSubstring, "Error while opening dir '"+dir.String()+"'")
+			fs.Fail("open", dir.String(), nil)
 		})
 
-		Convey("GetNameOrderedFiles() can fail listing files the directory", func() {
-			dir := NewPathDir("../..")
+		Convey("GetNameOrderedFiles() can fail opening the directory", func() {
+			fs := NewMemFS()
+			fs.Fail("open", "..", fmt.Errorf("Error os.Open for '%s'", `..\`))
+			dir := NewPathFS(fs, "..").SetDir()
 			SetBuffers(nil)
-			fosopen = ifosopen
-			fosfreaddir = testfosfreaddir
-			files := dir.GetNameOrderedFiles("errlist")
+			files := dir.GetNameOrderedFiles("err")
 			So(files, ShouldBeNil)
 			So(OutString(), ShouldBeEmpty)
 			So(ErrString(), ShouldEqualNL, `    [*Path.GetFiles] (*Path.GetNameOrderedFiles) (func)
-      Error while reading dir '..\..\': 'Error file.Readdir for '..\..\' (-1)'`)
-			fosfreaddir = ifosfreaddir
+      Error while opening dir '..\': 'open ..: Error os.Open for '..\''`)
 		})
 
 		Convey("GetNameOrderedFiles() returns an empty list for an empty folder", func() {
-			dir := NewPathDir("../../..")
+			fs := NewMemFS()
+			fs.MkdirAll("../../..", 0755)
+			dir := NewPathFS(fs, "../../..").SetDir()
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
 			files := dir.GetNameOrderedFiles("emptyfolder")
 			So(files, ShouldNotBeNil)
 			So(len(files), ShouldEqual, 0)
 			So(NoOutput(), ShouldBeTrue)
-			fosfreaddir = ifosfreaddir
-		})
-
-		Convey("GetNameOrderedFiles() with empty patterns return all files, ordered alphabetically", func() {
-			dir := NewPathDir(".")
-			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			files := dir.GetNameOrderedFiles("")
-			So(len(files), ShouldEqual, 6)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f1.go f2 f3 f4.go f5.go f6]`)
-			So(NoOutput(), ShouldBeTrue)
-			fosfreaddir = ifosfreaddir
 		})
 
 		Convey("GetNameOrderedFiles() with bad pattern return no files and a warning", func() {
-			dir := NewPathDir(".")
+			fs := NewMemFS()
+			for _, name := range []string{"f4.go", "f1.go", "f6", "f5.go", "f3", "f2"} {
+				NewPathFS(fs, name).WriteFile(nil)
+			}
+			dir := NewPathFS(fs, ".").SetDir()
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
 			files := dir.GetNameOrderedFiles("^g.*$")
 			So(len(files), ShouldEqual, 0)
 			So(OutString(), ShouldBeEmpty)
 			So(ErrString(), ShouldEqualNL, `    [*Path.GetFiles] (*Path.GetNameOrderedFiles) (func)
       NO FILE in '.\' for '^g.*$'`)
-			fosfreaddir = ifosfreaddir
 		})
 
-		Convey("GetNameOrderedFiles() with patterns return selected files, ordered alphabetically", func() {
-			dir := NewPathDir(".")
+		Convey("GetNameOrderedFiles() return selected files, ordered alphabetically", func() {
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			files := dir.GetNameOrderedFiles("f[236]")
-			So(len(files), ShouldEqual, 3)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f2 f3 f6]`)
+			fs.Chtimes(dir.Add("f6").String(), time.Now(), time.Now())
+			files := dir.GetNameOrderedFiles(`.*\.go|f6`)
+			So(fmt.Sprint(names(files)), ShouldEqual, `[f1.go f4.go f5.go f6]`)
 			So(NoOutput(), ShouldBeTrue)
-
-			files = dir.GetNameOrderedFiles(`.*\.go`)
-			So(len(files), ShouldEqual, 3)
-			So(fmt.Sprintf("%+v", files), ShouldEqual, `[f1.go f4.go f5.go]`)
-			So(NoOutput(), ShouldBeTrue)
-
-			fosfreaddir = ifosfreaddir
 		})
-
 	})
 
 	Convey("Tests for GetLastModifiedFile(pattern)", t, func() {
 
-		Convey("GetLastModifiedFile() returns empty list is not IsDir", func() {
-			dir := NewPathDir("xxx4")
-			lastModifiedFile := dir.GetLastModifiedFile("")
-			SetBuffers(nil)
-			So(len(lastModifiedFile), ShouldEqual, 0)
-			So(NoOutput(), ShouldBeTrue)
-		})
+		fs, dir := getFilesDir()
 
-		Convey("GetLastModifiedFile() can fail opening the directory", func() {
-			dir := NewPathDir("..")
+		Convey("GetLastModifiedFile() returns empty if not IsDir", func() {
 			SetBuffers(nil)
-			fosopen = testfosopen
-			lastModifiedFile := dir.GetLastModifiedFile("err")
-			So(lastModifiedFile, ShouldBeEmpty)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `      [*Path.GetFiles] (*Path.GetDateOrderedFiles) (*Path.GetLastModifiedFile) (func)
-        Error while opening dir '..\': 'Error os.Open for '..\''
-  [*Path.GetLastModifiedFile] (func)
-    Error while accessing dir '..\'`)
-			fosopen = ifosopen
+			So(dir.Add("xxx4").SetDir().GetLastModifiedFile(""), ShouldBeEmpty)
+			So(NoOutput(), ShouldBeTrue)
 		})
 
 		Convey("GetLastModifiedFile() can fail listing files the directory", func() {
-			dir := NewPathDir("../..")
 			SetBuffers(nil)
-			fosopen = ifosopen
-			fosfreaddir = testfosfreaddir
-			lastModifiedFile := dir.GetLastModifiedFile("errlist")
-			So(lastModifiedFile, ShouldBeEmpty)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `      [*Path.GetFiles] (*Path.GetDateOrderedFiles) (*Path.GetLastModifiedFile) (func)
-        Error while reading dir '..\..\': 'Error file.Readdir for '..\..\' (-1)'
-  [*Path.GetLastModifiedFile] (func)
-    Error while accessing dir '..\..\'`)
-			fosfreaddir = ifosfreaddir
+			fs.Fail("readdir", dir.String(), errors.New("I/O error"))
+			So(dir.GetLastModifiedFile("errlist"), ShouldBeEmpty)
+			So(ErrString(), ShouldContainSubstring, "Error while accessing dir '"+dir.String()+"'")
+			fs.Fail("readdir", dir.String(), nil)
 		})
 
-		Convey("GetLastModifiedFile() returns an empty list for an empty folder", func() {
-			dir := NewPathDir("../../..")
+		Convey("GetLastModifiedFile() returns the most recent selected file", func() {
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			lastModifiedFile := dir.GetLastModifiedFile("emptyfolder")
-			So(lastModifiedFile, ShouldNotBeNil)
-			So(len(lastModifiedFile), ShouldEqual, 0)
+			So(dir.GetLastModifiedFile(""), ShouldEqual, `f1.go`)
+			So(dir.GetLastModifiedFile("f[236]"), ShouldEqual, `f2`)
+			So(dir.GetLastModifiedFile(`f[45]\.go`), ShouldEqual, `f4.go`)
 			So(NoOutput(), ShouldBeTrue)
-			fosfreaddir = ifosfreaddir
 		})
+	})
 
-		Convey("GetLastModifiedFile() with empty patterns return all files, ordered alphabetically", func() {
-			dir := NewPathDir(".")
-			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			lastModifiedFile := dir.GetLastModifiedFile("")
-			So(lastModifiedFile, ShouldEqual, `f1.go`)
-			So(NoOutput(), ShouldBeTrue)
-			fosfreaddir = ifosfreaddir
-		})
+	Convey("Tests for DeleteFolder()", t, func() {
 
-		Convey("GetLastModifiedFile() with bad pattern return no files and a warning", func() {
-			dir := NewPathDir(".")
-			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			lastModifiedFile := dir.GetLastModifiedFile("^g.*$")
-			So(len(lastModifiedFile), ShouldEqual, 0)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `      [*Path.GetFiles] (*Path.GetDateOrderedFiles) (*Path.GetLastModifiedFile) (func)
-        NO FILE in '.\' for '^g.*$'`)
-			fosfreaddir = ifosfreaddir
-		})
+		fs, dir := getFilesDir()
 
-		Convey("GetLastModifiedFile() with patterns return selected files, ordered alphabetically", func() {
-			dir := NewPathDir(".")
+		Convey("DeleteFolder() does nothing if it is a file", func() {
+			file := dir.Add("f1.go")
 			SetBuffers(nil)
-			fosfreaddir = testfosfreaddir
-			lastModifiedFile := dir.GetLastModifiedFile("f[236]")
-			So(lastModifiedFile, ShouldEqual, `f2`)
-			So(NoOutput(), ShouldBeTrue)
-
-			lastModifiedFile = dir.GetLastModifiedFile(`.*\.go`)
-			So(lastModifiedFile, ShouldEqual, `f1.go`)
+			So(file.DeleteFolder(), ShouldBeNil)
+			So(file.Exists(), ShouldBeTrue)
 			So(NoOutput(), ShouldBeTrue)
-
-			fosfreaddir = ifosfreaddir
 		})
 
-	})
-
-	Convey("Tests for DeleteFolder()", t, func() {
-		Convey("DeleteFolder() does nothing if it is a file", func() {
-			dir := NewPathDir("path_test.go")
-			SetBuffers(nil)
-			err := dir.DeleteFolder()
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `open path_test.go\: The system cannot find the file specified.`)
-			So(err, ShouldBeNil)
-			So(dir.Exists(), ShouldBeFalse)
-		})
 		Convey("DeleteFolder() can fail listing the files", func() {
-			dir := NewPathDir("../..")
 			SetBuffers(nil)
-			fosopen = ifosopen
-			fosfreaddir = testfosfreaddir
+			fs.Fail("readdir", dir.String(), errors.New("I/O error"))
 			err := dir.DeleteFolder()
-			So(err, ShouldNotBeEmpty)
-			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [*Path.GetFiles] (*Path.DeleteFolder) (func)
-      Error while reading dir '..\..\': 'Error file.Readdir for '..\..\' (-1)'`)
-			fosfreaddir = ifosfreaddir
+			So(err.Error(), ShouldStartWith, "error while getting files from dir '"+dir.String()+"'")
+			So(ErrString(), ShouldContainSubstring, "Error while reading dir '"+dir.String()+"'")
+			fs.Fail("readdir", dir.String(), nil)
 		})
+
 		Convey("DeleteFolder() can fail deleting one of the files", func() {
-			dir := NewPathDir(".")
 			SetBuffers(nil)
-			fosremoveall = testosremoveall
+			fs.Fail("remove", dir.Add("f3").String(), errors.New("locked"))
 			err := dir.DeleteFolder()
-			So(err, ShouldNotBeEmpty)
-			So(err.Error(), ShouldEqualNL, `error removing file 'paths_test.go' in '.\': 'Error deleting 'paths_test.go''`)
+			So(err.Error(), ShouldStartWith, "error removing file 'f3' in '"+dir.String()+"': 'remove ")
+			So(dir.Add("f1.go").Exists(), ShouldBeFalse)
+			So(dir.Add("f3").Exists(), ShouldBeTrue)
 			So(NoOutput(), ShouldBeTrue)
-			fosremoveall = ifosremoveall
+			fs.Fail("remove", dir.Add("f3").String(), nil)
 		})
+
 		Convey("DeleteFolder() can fail deleting the folder itself", func() {
-			dir := NewPathDir("..")
 			SetBuffers(nil)
-			fosremoveall = testosremoveall
+			fs.Fail("remove", dir.String(), errors.New("locked"))
 			err := dir.DeleteFolder()
-			So(err, ShouldNotBeEmpty)
-			So(err.Error(), ShouldEqualNL, `error removing dir '..\': 'Error deleting folder '..\''`)
+			So(err.Error(), ShouldStartWith, "error removing dir '"+dir.String()+"': 'remove ")
+			So(len(dir.GetFiles("")), ShouldEqual, 0)
 			So(NoOutput(), ShouldBeTrue)
-			fosremoveall = ifosremoveall
+			fs.Fail("remove", dir.String(), nil)
 		})
+
 		Convey("DeleteFolder() can delete the folder and its content", func() {
-			dir := NewPathDir("../..")
-			ifosremoveall("xxx5")
 			SetBuffers(nil)
-			fosremoveall = testosremoveall
-			err := dir.DeleteFolder()
-			So(err, ShouldBeNil)
+			So(dir.DeleteFolder(), ShouldBeNil)
+			So(dir.Exists(), ShouldBeFalse)
 			So(NoOutput(), ShouldBeTrue)
-			fosremoveall = ifosremoveall
 		})
 	})
 }
-
-func testosremoveall(name string) (err error) {
-	// Perrdbgf("'%v'", name)
-	if name == `paths_test.go` {
-		return fmt.Errorf("Error deleting '%v'", name)
-	}
-	if name == `..\` {
-		return fmt.Errorf("Error deleting folder '%v'", name)
-	}
-	// by default, simulate successfull removal
-	return nil
-}
-
-type testFileInfo struct {
-	name string
-	time time.Time
-}
-
-func (tfi *testFileInfo) Name() string       { return tfi.name }
-func (tfi *testFileInfo) Size() int64        { return 0 }
-func (tfi *testFileInfo) Mode() os.FileMode  { return 0 }
-func (tfi *testFileInfo) ModTime() time.Time { return tfi.time }
-func (tfi *testFileInfo) IsDir() bool        { return false }
-func (tfi *testFileInfo) Sys() interface{}   { return nil }
-func (tfi *testFileInfo) String() string     { return tfi.Name() }
-
-func testfosfreaddir(f *os.File, n int) (fi []os.FileInfo, err error) {
-	if f.Name() == `.\` {
-		return []os.FileInfo{
-			&testFileInfo{"f4.go", time.Now().Add(-4 * time.Hour)},
-			&testFileInfo{"f1.go", time.Now().Add(-1 * time.Hour)},
-			&testFileInfo{"f6", time.Now().Add(-6 * time.Hour)},
-			&testFileInfo{"f5.go", time.Now().Add(-5 * time.Hour)},
-			&testFileInfo{"f3", time.Now().Add(-3 * time.Hour)},
-			&testFileInfo{"f2", time.Now().Add(-2 * time.Hour)}}, nil
-	}
-	if f.Name() == `..\..\` {
-		ifosfreaddir(f, n)
-		return nil, fmt.Errorf("Error file.Readdir for '%s' (%d)", f.Name(), n)
-	}
-	if f.Name() == `..\..\..\` {
-		return []os.FileInfo{}, nil
-	}
-	return nil, nil
-}
-
-func testfosopen(name string) (file *os.File, err error) {
-	if name == `..\` {
-		return nil, fmt.Errorf("Error os.Open for '%s'", name)
-	}
-	fmt.Printf("testfosopen '%v' => %+v\n", name, file)
-	return nil, nil
-}
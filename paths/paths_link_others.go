@@ -0,0 +1,11 @@
+//go:build !windows
+
+package paths
+
+import "os"
+
+// symlink makes newname a symbolic link to oldname
+// (relative to the folder of newname)
+func symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
@@ -0,0 +1,34 @@
+package paths
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLink(t *testing.T) {
+
+	Convey("A folder can be linked on the OS, relative to the link folder", t, func() {
+		SetBuffers(nil)
+		dir, _ := ioutil.TempDir("", "senvgo_link")
+		defer os.RemoveAll(dir)
+		v1 := NewPathDir(filepath.Join(dir, "git", "v1"))
+		So(v1.Mkdir(), ShouldBeNil)
+		So(v1.Add("git.exe").WriteFile([]byte("exe")), ShouldBeNil)
+		latest := NewPath(filepath.Join(dir, "git", "latest"))
+		So(latest.Symlink("v1"), ShouldBeNil)
+		target, err := latest.Readlink()
+		So(err, ShouldBeNil)
+		So(filepath.Base(target), ShouldEqual, "v1")
+		So(NewPath(filepath.Join(dir, "git", "latest", "git.exe")).FileContent(), ShouldEqual, "exe")
+		So(latest.Symlink("v1"), ShouldNotBeNil)
+
+		So(latest.Remove(), ShouldBeNil)
+		So(v1.Add("git.exe").Exists(), ShouldBeTrue)
+		So(NoOutput(), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,68 @@
+package paths
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unicode/utf16"
+)
+
+const (
+	fsctlSetReparsePoint   = 0x000900A4
+	ioReparseTagMountPoint = 0xA0000003
+)
+
+// symlink makes newname a directory junction to the folder oldname
+// (relative to the folder of newname): unlike a symbolic link,
+// a junction needs no admin rights, but its target is absolute.
+func symlink(oldname, newname string) error {
+	target := oldname
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(newname), target)
+	}
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	if err = os.Mkdir(newname, 0755); err != nil {
+		return err
+	}
+	if err = setMountPoint(newname, target); err != nil {
+		os.Remove(newname)
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	return nil
+}
+
+// setMountPoint sets the reparse point of an empty folder
+// to an absolute target (a REPARSE_DATA_BUFFER with a MountPointReparseBuffer)
+func setMountPoint(name, target string) error {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	h, err := syscall.CreateFile(p, syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING,
+		syscall.FILE_FLAG_OPEN_REPARSE_POINT|syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+	subName := utf16.Encode([]rune(`\??\` + target))
+	printName := utf16.Encode([]rune(target))
+	// substitute then print names, both NUL-terminated
+	names := append(append(subName, 0), append(printName, 0)...)
+	buf := make([]byte, 16+2*len(names))
+	le := binary.LittleEndian
+	le.PutUint32(buf[0:], ioReparseTagMountPoint)
+	le.PutUint16(buf[4:], uint16(len(buf)-8))
+	le.PutUint16(buf[8:], 0)
+	le.PutUint16(buf[10:], uint16(2*len(subName)))
+	le.PutUint16(buf[12:], uint16(2*(len(subName)+1)))
+	le.PutUint16(buf[14:], uint16(2*len(printName)))
+	for i, c := range names {
+		le.PutUint16(buf[16+2*i:], c)
+	}
+	var n uint32
+	return syscall.DeviceIoControl(h, fsctlSetReparsePoint, &buf[0], uint32(len(buf)), nil, 0, &n, nil)
+}
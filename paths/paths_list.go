@@ -0,0 +1,364 @@
+package paths
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a file or a folder of an archive
+type Entry struct {
+	// Name is the '/' separated path of the entry, without trailing '/'
+	Name    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	IsDir   bool
+	// Link is the '/' separated target of a tar link entry: a hard link
+	// (relative to the archive root), or a symbolic link (Mode has os.ModeSymlink)
+	Link string
+}
+
+// isHardLink is true for a tar hard link, which is extracted as a copy of its target
+func (e *Entry) isHardLink() bool {
+	return e.Link != "" && e.Mode&os.ModeSymlink == 0
+}
+
+func (e *Entry) String() string {
+	if e.IsDir {
+		return e.Name + "/"
+	}
+	if e.Link != "" {
+		return fmt.Sprintf("%s -> %s", e.Name, e.Link)
+	}
+	return fmt.Sprintf("%s (%d)", e.Name, e.Size)
+}
+
+var fexec7z func(args ...string) ([]byte, error)
+
+func ifexec7z(args ...string) ([]byte, error) {
+	cmd := cmd7z()
+	if cmd == "" {
+		return nil, fmt.Errorf("no 7z.exe found")
+	}
+	return exec.Command(cmd, args...).Output()
+}
+
+// archiveKind returns how an archive is read: natively for
+// 'zip', 'tar', 'tar.gz', or through 7z for any other archive.
+// (Names like 'python-2.7.6.amd64.zip' have several dots: no isExt())
+func (p *Path) archiveKind() string {
+	name := strings.ToLower(p.String())
+	for _, kind := range []string{"zip", "tar", "tar.gz", "tgz"} {
+		if strings.HasSuffix(name, "."+kind) {
+			return kind
+		}
+	}
+	return "7z"
+}
+
+// List returns the entries of an archive (zip, tar, tar.gz, or 7z).
+func (p *Path) List() ([]*Entry, error) {
+	res := []*Entry{}
+	err := p.walkArchive(func(e *Entry, r io.Reader) (bool, error) {
+		res = append(res, e)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ExtractFile extracts one file of an archive in a dest folder,
+// and returns the extracted file path.
+// The file name is its '/' separated path in the archive,
+// but the file is extracted directly in dest.
+// A hard link is extracted as a copy of its target, a symbolic link is an error.
+func (p *Path) ExtractFile(name string, dest *Path) (*Path, error) {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+	res := dest.SetDir().Add(path.Base(name))
+	if p.archiveKind() == "7z" {
+		if out, err := fexec7z("e", "-aoa", "-o"+dest.NoSep().String(), "-pdefault", "-sccUTF-8", p.String(), "--", name); err != nil {
+			return nil, &Error{Op: "extract '" + name + "' from", Path: p.String(), Kind: kindOf7z(out), Err: fmt.Errorf("%v\n%s", err, out)}
+		}
+		if !res.Exists() {
+			return nil, fmt.Errorf("'%s' not found in '%v'", name, p)
+		}
+		return res, nil
+	}
+	linked, err := p.extractEntry(name, res)
+	if err == nil && linked != "" {
+		if linked, err = p.extractEntry(linked, res); err == nil && linked != "" {
+			err = &Error{Op: "extract '" + name + "' from", Path: p.String(), Kind: ErrCorruptEntry, Err: fmt.Errorf("hard link '%s' to a link", name)}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// extractEntry writes the content of an archive file in res,
+// or returns its target if it is a hard link.
+func (p *Path) extractEntry(name string, res *Path) (string, error) {
+	found := false
+	linked := ""
+	err := p.walkArchive(func(e *Entry, r io.Reader) (bool, error) {
+		if e.Name != name || e.IsDir {
+			return false, nil
+		}
+		found = true
+		if e.Mode&os.ModeSymlink != 0 {
+			return true, &Error{Op: "extract '" + name + "' from", Path: p.String(), Kind: ErrCorruptEntry, Err: fmt.Errorf("symbolic link '%s' not supported", e.Name)}
+		}
+		if e.isHardLink() {
+			linked = e.Link
+			return true, nil
+		}
+		return true, writeFile(res, r, e.Mode)
+	})
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("'%s' not found in '%v'", name, p)
+	}
+	return linked, nil
+}
+
+// Extract extracts all the files of an archive (zip, tar, tar.gz, or 7z)
+// in a dest folder, like Uncompress, but returns why it failed (as an *Error):
+// ErrNotArchive, ErrCorruptEntry, ErrPermission, ...
+// An entry outside of dest (like '../x') is a corrupt entry.
+// A tar hard link is extracted as a copy of its (already extracted) target,
+// which must be in dest; a symbolic link is a corrupt entry.
+func (p *Path) Extract(dest *Path) error {
+	if err := dest.Mkdir(); err != nil {
+		return err
+	}
+	if p.archiveKind() == "7z" {
+		if out, err := fexec7z("x", "-aoa", "-o"+dest.NoSep().String(), "-pdefault", "-sccUTF-8", p.String()); err != nil {
+			return &Error{Op: "extract", Path: p.String(), Kind: kindOf7z(out), Err: fmt.Errorf("%v\n%s", err, out)}
+		}
+		return nil
+	}
+	root := dest.NoSep().String()
+	fs := dest.fs()
+	return p.walkArchive(func(e *Entry, r io.Reader) (bool, error) {
+		target := filepath.Join(root, filepath.FromSlash(e.Name))
+		if !isIn(target, root) {
+			return true, &Error{Op: "extract", Path: p.String(), Kind: ErrCorruptEntry, Err: fmt.Errorf("entry '%s' outside of '%v'", e.Name, dest)}
+		}
+		if e.Mode&os.ModeSymlink != 0 {
+			return true, &Error{Op: "extract", Path: p.String(), Kind: ErrCorruptEntry, Err: fmt.Errorf("symbolic link '%s' not supported", e.Name)}
+		}
+		if e.IsDir {
+			return false, fs.MkdirAll(target, 0755)
+		}
+		if err := fs.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return true, err
+		}
+		file := &Path{path: target, filesys: dest.filesys}
+		if e.isHardLink() {
+			linked := filepath.Join(root, filepath.FromSlash(e.Link))
+			if !isIn(linked, root) || linked == root || linked == target {
+				return true, &Error{Op: "extract", Path: p.String(), Kind: ErrCorruptEntry, Err: fmt.Errorf("link '%s' to '%s' outside of '%v'", e.Name, e.Link, dest)}
+			}
+			return false, (&Path{path: linked, filesys: dest.filesys}).Copy(file)
+		}
+		return false, writeFile(file, r, e.Mode)
+	})
+}
+
+// closeArchive closes an archive once read, keeping the first error
+func closeArchive(file File, res *error) {
+	if err := file.Close(); *res == nil {
+		*res = err
+	}
+}
+
+// isIn is true if target is root or in root
+func isIn(target, root string) bool {
+	return target == root || strings.HasPrefix(target, root+string(filepath.Separator))
+}
+
+func writeFile(p *Path, r io.Reader, mode os.FileMode) error {
+	if mode.Perm() == 0 {
+		mode = 0644
+	}
+	f, err := p.fs().OpenFile(p.String(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		p.fs().Remove(p.String())
+		return err
+	}
+	return f.Close()
+}
+
+// walkArchive calls f for each entry of an archive, with a reader on its content
+// (nil for 7z archives), until f returns true or an error.
+// Errors are returned as *Error.
+func (p *Path) walkArchive(f func(e *Entry, r io.Reader) (bool, error)) error {
+	var err error
+	switch p.archiveKind() {
+	case "zip":
+		err = p.walkZip(f)
+	case "tar":
+		err = p.walkTar(f, false)
+	case "tar.gz", "tgz":
+		err = p.walkTar(f, true)
+	default:
+		err = p.walk7z(f)
+	}
+	return newError("read archive", p, err)
+}
+
+func (p *Path) walkZip(f func(e *Entry, r io.Reader) (bool, error)) (res error) {
+	file, err := p.fs().Open(p.String())
+	if err != nil {
+		return err
+	}
+	defer closeArchive(file, &res)
+	fi, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(file, fi.Size())
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		e := &Entry{Name: strings.TrimSuffix(zf.Name, "/"), Size: int64(zf.UncompressedSize64),
+			Mode: zf.Mode(), ModTime: zf.Modified, IsDir: zf.FileInfo().IsDir()}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		stop, err := f(e, rc)
+		rc.Close()
+		if stop || err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Path) walkTar(f func(e *Entry, r io.Reader) (bool, error), gz bool) (res error) {
+	file, err := p.fs().Open(p.String())
+	if err != nil {
+		return err
+	}
+	defer closeArchive(file, &res)
+	var r io.Reader = file
+	if gz {
+		gr, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fi := hdr.FileInfo()
+		e := &Entry{Name: strings.TrimSuffix(strings.TrimPrefix(hdr.Name, "./"), "/"), Size: hdr.Size,
+			Mode: fi.Mode(), ModTime: hdr.ModTime, IsDir: fi.IsDir()}
+		switch hdr.Typeflag {
+		case tar.TypeLink:
+			e.Link = strings.TrimPrefix(hdr.Linkname, "./")
+		case tar.TypeSymlink:
+			e.Link = hdr.Linkname
+		}
+		if e.Name == "" || e.Name == "." {
+			continue
+		}
+		if stop, err := f(e, tr); stop || err != nil {
+			return err
+		}
+	}
+}
+
+// walk7z parses the technical listing ('-slt') of 7z
+func (p *Path) walk7z(f func(e *Entry, r io.Reader) (bool, error)) error {
+	out, err := fexec7z("l", "-slt", "-pdefault", "-sccUTF-8", p.String())
+	if err != nil {
+		return &Error{Op: "read archive", Path: p.String(), Kind: kindOf7z(out), Err: fmt.Errorf("%v\n%s", err, out)}
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	started := false
+	var e *Entry
+	flush := func() (bool, error) {
+		if e == nil || e.Name == "" {
+			return false, nil
+		}
+		entry := e
+		e = nil
+		return f(entry, nil)
+	}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "----------" {
+			started = true
+			continue
+		}
+		if !started {
+			continue
+		}
+		if line == "" {
+			if stop, err := flush(); stop || err != nil {
+				return err
+			}
+			continue
+		}
+		elts := strings.SplitN(line, " = ", 2)
+		if len(elts) != 2 {
+			continue
+		}
+		if e == nil {
+			e = &Entry{Mode: 0644}
+		}
+		switch key, value := elts[0], elts[1]; key {
+		case "Path":
+			e.Name = strings.TrimSuffix(filepath.ToSlash(strings.Replace(value, `\`, "/", -1)), "/")
+		case "Size":
+			e.Size, _ = strconv.ParseInt(value, 10, 64)
+		case "Modified":
+			e.ModTime, _ = time.Parse("2006-01-02 15:04:05", value)
+		case "Folder":
+			e.IsDir = value == "+"
+		case "Attributes":
+			e.IsDir = e.IsDir || strings.HasPrefix(value, "D")
+		}
+		if e.IsDir {
+			e.Mode = os.ModeDir | 0755
+		}
+	}
+	_, err = flush()
+	return err
+}
+
+func init() {
+	fexec7z = ifexec7z
+}
@@ -0,0 +1,178 @@
+package paths
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const test7zListing = `
+7-Zip 9.20  Copyright (c) 1999-2010 Igor Pavlov  2010-11-18
+
+Listing archive: jdk-8u40-windows-x64.exe
+
+--
+Path = jdk-8u40-windows-x64.exe
+Type = PE
+
+----------
+Path = tools
+Folder = +
+Size = 0
+Modified = 2015-02-10 09:12:00
+Attributes = D....
+
+Path = tools\src.zip
+Folder = -
+Size = 21017541
+Modified = 2015-02-10 09:11:58
+Attributes = ....A
+`
+
+var args7z [][]string
+
+func testfexec7z(args ...string) ([]byte, error) {
+	args7z = append(args7z, args)
+	if args[0] == "l" {
+		return []byte(test7zListing), nil
+	}
+	if args[len(args)-1] == "tools/src.zip" {
+		dest := args[2][len("-o"):]
+		return nil, ioutil.WriteFile(filepath.Join(dest, "src.zip"), []byte("src"), 0644)
+	}
+	return []byte("No files to process"), nil
+}
+
+func listNames(entries []*Entry) []string {
+	res := []string{}
+	for _, e := range entries {
+		res = append(res, e.String())
+	}
+	return res
+}
+
+// tarLinks writes a tar with a 'bin/git.exe' file, then the given link headers
+func tarLinks(file string, links ...*tar.Header) {
+	b := new(bytes.Buffer)
+	tw := tar.NewWriter(b)
+	tw.WriteHeader(&tar.Header{Name: "bin/git.exe", Mode: 0755, Size: 3, Typeflag: tar.TypeReg})
+	tw.Write([]byte("git"))
+	for _, link := range links {
+		tw.WriteHeader(link)
+	}
+	tw.Close()
+	ioutil.WriteFile(file, b.Bytes(), 0644)
+}
+
+func TestList(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "list")
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "jdk1.8.0_40")
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "bin", "java.exe"), []byte("java"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "src.zip"), []byte("src.zip"), 0644)
+	folder := NewPathDir(src)
+	out := NewPathDir(filepath.Join(dir, "out"))
+	out.MkdirAll()
+
+	Convey("Archives can be listed and a file extracted", t, func() {
+		SetBuffers(nil)
+		for _, archive := range []*Path{NewPath(filepath.Join(dir, "jdk-8.40.zip")), NewPath(filepath.Join(dir, "jdk.tar.gz"))} {
+			_, err := folder.Compress(archive, FormatOf(archive), nil)
+			So(err, ShouldBeNil)
+			entries, err := archive.List()
+			So(err, ShouldBeNil)
+			So(listNames(entries), ShouldResemble, []string{"bin/", "bin/java.exe (4)", "src.zip (7)"})
+			So(entries[0].IsDir, ShouldBeTrue)
+			So(entries[1].Mode.Perm(), ShouldEqual, os.FileMode(0755))
+			So(entries[1].ModTime.Equal(DefaultModTime), ShouldBeTrue)
+
+			f, err := archive.ExtractFile("bin/java.exe", out)
+			So(err, ShouldBeNil)
+			So(f.String(), ShouldEqual, out.Add("java.exe").String())
+			So(f.FileContent(), ShouldEqual, "java")
+			os.Remove(f.String())
+			_, err = archive.ExtractFile("bin/javac.exe", out)
+			So(err.Error(), ShouldEqual, fmt.Sprintf("'bin/javac.exe' not found in '%v'", archive))
+		}
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("A tar is read natively too", func() {
+			archive := NewPath(filepath.Join(dir, "jdk.tar"))
+			tgz := NewPath(filepath.Join(dir, "jdk.tar.gz"))
+			f, _ := os.Open(tgz.String())
+			gr, _ := gzip.NewReader(f)
+			content, _ := ioutil.ReadAll(gr)
+			f.Close()
+			ioutil.WriteFile(archive.String(), content, 0644)
+			entries, err := archive.List()
+			So(err, ShouldBeNil)
+			So(len(entries), ShouldEqual, 3)
+		})
+
+		Convey("Tar hard links are extracted as copies, symbolic links are rejected", func() {
+			archive := NewPath(filepath.Join(dir, "links.tar"))
+			tarLinks(archive.String(), &tar.Header{Name: "git.exe", Linkname: "bin/git.exe", Typeflag: tar.TypeLink})
+			entries, err := archive.List()
+			So(err, ShouldBeNil)
+			So(listNames(entries), ShouldResemble, []string{"bin/git.exe (3)", "git.exe -> bin/git.exe"})
+			dest := NewPathDir(filepath.Join(dir, "links"))
+			So(archive.Extract(dest), ShouldBeNil)
+			So(dest.Add("git.exe").FileContent(), ShouldEqual, "git")
+			f, err := archive.ExtractFile("git.exe", out)
+			So(err, ShouldBeNil)
+			So(f.FileContent(), ShouldEqual, "git")
+			So(out.Add("bin").Exists(), ShouldBeFalse)
+			os.Remove(f.String())
+
+			tarLinks(archive.String(), &tar.Header{Name: "git.exe", Linkname: "../../etc/passwd", Typeflag: tar.TypeLink})
+			err = archive.Extract(NewPathDir(filepath.Join(dir, "links2")))
+			So(errors.Is(err, ErrCorruptEntry), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "link 'git.exe' to '../../etc/passwd' outside of")
+
+			tarLinks(archive.String(), &tar.Header{Name: "git", Linkname: "bin/git.exe", Typeflag: tar.TypeSymlink})
+			err = archive.Extract(NewPathDir(filepath.Join(dir, "links3")))
+			So(errors.Is(err, ErrCorruptEntry), ShouldBeTrue)
+			So(err.Error(), ShouldEndWith, "symbolic link 'git' not supported")
+			_, err = archive.ExtractFile("git", out)
+			So(errors.Is(err, ErrCorruptEntry), ShouldBeTrue)
+			_, err = os.Lstat(filepath.Join(dir, "links3", "git"))
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("Other archives are read through 7z", func() {
+			fexec7z = testfexec7z
+			args7z = nil
+			archive := NewPath(filepath.Join(dir, "jdk-8u40-windows-x64.exe"))
+			entries, err := archive.List()
+			So(err, ShouldBeNil)
+			So(listNames(entries), ShouldResemble, []string{"tools/", "tools/src.zip (21017541)"})
+			So(entries[1].ModTime, ShouldResemble, time.Date(2015, 2, 10, 9, 11, 58, 0, time.UTC))
+			f, err := archive.ExtractFile("tools/src.zip", out)
+			So(err, ShouldBeNil)
+			So(f.FileContent(), ShouldEqual, "src")
+			So(args7z[1], ShouldResemble, []string{"e", "-aoa", "-o" + out.NoSep().String(), "-pdefault", "-sccUTF-8", archive.String(), "--", "tools/src.zip"})
+			_, err = archive.ExtractFile("tools/none", out)
+			So(err.Error(), ShouldEndWith, "'tools/none' not found in '"+archive.String()+"'")
+			fexec7z = ifexec7z
+		})
+
+		Convey("An invalid archive cannot be listed", func() {
+			bad := NewPath(filepath.Join(dir, "bad.zip"))
+			ioutil.WriteFile(bad.String(), []byte("not a zip"), 0644)
+			_, err := bad.List()
+			So(err.Error(), ShouldStartWith, "unable to read archive")
+		})
+	})
+}
@@ -0,0 +1,409 @@
+package paths
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an in-memory FS, safe for concurrent use, for tests:
+// faults can be injected on any operation (see Fail).
+type MemFS struct {
+	mu     sync.Mutex
+	nodes  map[string]*memNode
+	faults map[string]error
+}
+
+type memNode struct {
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty in-memory FS (only its root folders exist)
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: map[string]*memNode{}, faults: map[string]error{}}
+}
+
+// Fail makes an operation on a name fail with err, until Fail is called
+// again with a nil err. op is "stat", "open" (read), "write", "close", "mkdir",
+// "readdir", "remove", "rename" (on the old name), "symlink" (on the new name)
+// or "readlink".
+func (m *MemFS) Fail(op, name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := op + " " + memKey(name)
+	if err == nil {
+		delete(m.faults, key)
+		return
+	}
+	m.faults[key] = err
+}
+
+func memKey(name string) string {
+	return filepath.Clean(filepath.FromSlash(name))
+}
+
+// fault returns the error injected for an operation, as an *os.PathError
+func (m *MemFS) fault(op, name string) error {
+	if err := m.faults[op+" "+name]; err != nil {
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+// node returns an existing file or folder: root folders always exist
+func (m *MemFS) node(name string) *memNode {
+	if n := m.nodes[name]; n != nil {
+		return n
+	}
+	if filepath.Dir(name) == name {
+		return &memNode{mode: os.ModeDir | 0755}
+	}
+	return nil
+}
+
+// resolve follows the symbolic links in any component of a name
+func (m *MemFS) resolve(name string) string {
+	for depth := 0; depth < 40; depth++ {
+		link := ""
+		for dir := name; link == "" && filepath.Dir(dir) != dir; dir = filepath.Dir(dir) {
+			if n := m.nodes[dir]; n != nil && n.mode&os.ModeSymlink != 0 {
+				link = dir
+			}
+		}
+		if link == "" {
+			return name
+		}
+		target := string(m.nodes[link].data)
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(link), target)
+		}
+		name = filepath.Join(target, name[len(link):])
+	}
+	return name
+}
+
+func (m *MemFS) stat(op, name string) (os.FileInfo, error) {
+	if err := m.fault(op, name); err != nil {
+		return nil, err
+	}
+	n := m.node(m.resolve(name))
+	if n == nil {
+		return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return &memFileInfo{name: filepath.Base(name), size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}, nil
+}
+
+// Stat returns the FileInfo of a file or folder
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stat("stat", memKey(name))
+}
+
+// Open opens a file or folder for reading
+func (m *MemFS) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens a file like os.OpenFile: its folder must exist
+func (m *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(name)
+	write := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	op := "open"
+	if write {
+		op = "write"
+	}
+	if err := m.fault(op, key); err != nil {
+		return nil, err
+	}
+	key = m.resolve(key)
+	n := m.node(key)
+	if n == nil {
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: op, Path: key, Err: os.ErrNotExist}
+		}
+		if parent := m.node(filepath.Dir(key)); parent == nil || !parent.mode.IsDir() {
+			return nil, &os.PathError{Op: op, Path: key, Err: os.ErrNotExist}
+		}
+		n = &memNode{mode: perm.Perm(), modTime: time.Now()}
+		m.nodes[key] = n
+	}
+	if write && n.mode.IsDir() {
+		return nil, &os.PathError{Op: op, Path: key, Err: os.ErrInvalid}
+	}
+	if flag&os.O_TRUNC != 0 {
+		n.data = nil
+	}
+	return &memFile{fs: m, name: key, node: n, append: flag&os.O_APPEND != 0, write: write}, nil
+}
+
+// MkdirAll creates a folder and its parents
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.resolve(memKey(path))
+	for dir := key; ; dir = filepath.Dir(dir) {
+		if err := m.fault("mkdir", dir); err != nil {
+			return err
+		}
+		if n := m.node(dir); n != nil {
+			if !n.mode.IsDir() {
+				return &os.PathError{Op: "mkdir", Path: dir, Err: ErrNotDir}
+			}
+			break
+		}
+		m.nodes[dir] = &memNode{mode: os.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+// children returns the names of the direct content of a folder, sorted
+func (m *MemFS) children(dir string) []string {
+	res := []string{}
+	for name := range m.nodes {
+		if name != dir && filepath.Dir(name) == dir {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Remove removes a file or an empty folder
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(name)
+	if err := m.fault("remove", key); err != nil {
+		return err
+	}
+	if m.nodes[key] == nil {
+		return &os.PathError{Op: "remove", Path: key, Err: os.ErrNotExist}
+	}
+	if len(m.children(key)) > 0 {
+		return &os.PathError{Op: "remove", Path: key, Err: os.ErrExist}
+	}
+	delete(m.nodes, key)
+	return nil
+}
+
+// RemoveAll removes a file or a folder and its content
+func (m *MemFS) RemoveAll(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(path)
+	if err := m.fault("remove", key); err != nil {
+		return err
+	}
+	for name := range m.nodes {
+		if name == key || strings.HasPrefix(name, key+string(filepath.Separator)) {
+			delete(m.nodes, name)
+		}
+	}
+	return nil
+}
+
+// Rename moves a file or a folder (and its content)
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	from, to := memKey(oldpath), memKey(newpath)
+	if err := m.fault("rename", from); err != nil {
+		return err
+	}
+	if m.nodes[from] == nil {
+		return &os.PathError{Op: "rename", Path: from, Err: os.ErrNotExist}
+	}
+	if to == from {
+		return nil
+	}
+	if strings.HasPrefix(to, from+string(filepath.Separator)) {
+		return &os.PathError{Op: "rename", Path: to, Err: os.ErrInvalid}
+	}
+	if parent := m.node(filepath.Dir(to)); parent == nil {
+		return &os.PathError{Op: "rename", Path: to, Err: os.ErrNotExist}
+	}
+	moved := map[string]*memNode{}
+	for name, n := range m.nodes {
+		if name == from || strings.HasPrefix(name, from+string(filepath.Separator)) {
+			moved[to+name[len(from):]] = n
+			delete(m.nodes, name)
+		}
+	}
+	for name, n := range moved {
+		m.nodes[name] = n
+	}
+	return nil
+}
+
+// Symlink makes newname a symbolic link to oldname
+// (relative to the folder of newname)
+func (m *MemFS) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(newname)
+	if err := m.fault("symlink", key); err != nil {
+		return err
+	}
+	if m.nodes[key] != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: key, Err: os.ErrExist}
+	}
+	if parent := m.node(m.resolve(filepath.Dir(key))); parent == nil || !parent.mode.IsDir() {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: key, Err: os.ErrNotExist}
+	}
+	m.nodes[key] = &memNode{data: []byte(oldname), mode: os.ModeSymlink | 0777, modTime: time.Now()}
+	return nil
+}
+
+// Readlink returns the target of a symbolic link
+func (m *MemFS) Readlink(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(name)
+	if err := m.fault("readlink", key); err != nil {
+		return "", err
+	}
+	n := m.nodes[key]
+	if n == nil {
+		return "", &os.PathError{Op: "readlink", Path: key, Err: os.ErrNotExist}
+	}
+	if n.mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: key, Err: os.ErrInvalid}
+	}
+	return string(n.data), nil
+}
+
+// Chtimes changes the modification time of a file or a folder
+func (m *MemFS) Chtimes(name string, atime, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memKey(name)
+	n := m.nodes[key]
+	if n == nil {
+		return &os.PathError{Op: "chtimes", Path: key, Err: os.ErrNotExist}
+	}
+	n.modTime = mtime
+	return nil
+}
+
+type memFile struct {
+	fs     *MemFS
+	name   string
+	node   *memNode
+	off    int64
+	append bool
+	write  bool
+	read   int
+}
+
+func (f *memFile) Name() string {
+	return f.name
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.off)
+	f.off += int64(n)
+	return n, err
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.node.mode.IsDir() {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: os.ErrInvalid}
+	}
+	return bytes.NewReader(f.node.data).ReadAt(p, off)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if !f.write {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+	}
+	if err := f.fs.fault("write", f.name); err != nil {
+		return 0, err
+	}
+	if f.append {
+		f.off = int64(len(f.node.data))
+	}
+	end := f.off + int64(len(p))
+	if end > int64(len(f.node.data)) {
+		data := make([]byte, end)
+		copy(data, f.node.data)
+		f.node.data = data
+	}
+	copy(f.node.data[f.off:], p)
+	f.off = end
+	f.node.modTime = time.Now()
+	return len(p), nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	return f.fs.fault("close", f.name)
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.fs.fault("stat", f.name); err != nil {
+		return nil, err
+	}
+	return &memFileInfo{name: filepath.Base(f.name), size: int64(len(f.node.data)), mode: f.node.mode, modTime: f.node.modTime}, nil
+}
+
+// Readdir returns the content of a folder, sorted by name
+// (n > 0 returns at most n entries per call, io.EOF at the end)
+func (f *memFile) Readdir(n int) ([]os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if !f.node.mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: ErrNotDir}
+	}
+	if err := f.fs.fault("readdir", f.name); err != nil {
+		return nil, err
+	}
+	names := f.fs.children(f.name)
+	if f.read > len(names) {
+		// content removed since the previous call
+		f.read = len(names)
+	}
+	res := []os.FileInfo{}
+	for _, name := range names[f.read:] {
+		if n > 0 && len(res) == n {
+			break
+		}
+		c := f.fs.nodes[name]
+		res = append(res, &memFileInfo{name: filepath.Base(name), size: int64(len(c.data)), mode: c.mode, modTime: c.modTime})
+	}
+	f.read += len(res)
+	if n > 0 && len(res) == 0 {
+		return res, io.EOF
+	}
+	return res, nil
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,182 @@
+package paths
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemFS(t *testing.T) {
+
+	Convey("Path operations run through an in-memory FS", t, func() {
+		SetBuffers(nil)
+		fs := NewMemFS()
+		root := NewPathFS(fs, filepath.FromSlash("/prgs/"))
+		So(root.Exists(), ShouldBeFalse)
+		So(root.Mkdir(), ShouldBeNil)
+		So(root.IsDir(), ShouldBeTrue)
+		file := root.Add("git").Add("a.txt")
+		So(file.FS(), ShouldEqual, fs)
+		_, err := file.OpenFile(false)
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+		So(file.Dir().Mkdir(), ShouldBeNil)
+		f, err := file.OpenFile(false)
+		So(err, ShouldBeNil)
+		f.Write([]byte("abc"))
+		f.Close()
+		f, _ = file.OpenFile(true)
+		f.Write([]byte("d"))
+		f.Close()
+		So(file.FileContent(), ShouldEqual, "abcd")
+		So(NewPathFS(fs, root.Add("git").NoSep().String()).EndsWithSeparator(), ShouldBeTrue)
+		fis, err := root.Add("git").ReadDir("")
+		So(err, ShouldBeNil)
+		So(len(fis), ShouldEqual, 1)
+		So(fis[0].Size(), ShouldEqual, int64(4))
+		So(NewPath(file.String()).Exists(), ShouldBeFalse)
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("Folders can be renamed and removed", func() {
+			src := root.Add("svn").SetDir()
+			So(src.Mkdir(), ShouldBeNil)
+			f, _ := src.Add("b.txt").OpenFile(false)
+			f.Write([]byte("b"))
+			f.Close()
+			err := src.Rename(src.Add("sub"))
+			So(err.Error(), ShouldContainSubstring, "invalid argument")
+			So(src.Add("b.txt").FileContent(), ShouldEqual, "b")
+			So(src.Add("sub").Exists(), ShouldBeFalse)
+			So(src.Rename(src), ShouldBeNil)
+			dest := root.Add("svn2")
+			So(src.Rename(dest), ShouldBeNil)
+			So(dest.Add("b.txt").FileContent(), ShouldEqual, "b")
+			So(src.Exists(), ShouldBeFalse)
+			err = dest.Remove()
+			So(errors.Is(err, os.ErrExist), ShouldBeTrue)
+			So(dest.DeleteFolder(), ShouldBeNil)
+			So(dest.Exists(), ShouldBeFalse)
+		})
+
+		Convey("A folder can be read while its content is removed", func() {
+			dir := root.Add("hg").SetDir()
+			So(dir.Mkdir(), ShouldBeNil)
+			for _, name := range []string{"a", "b", "c"} {
+				So(dir.Add(name).WriteFile(nil), ShouldBeNil)
+			}
+			d, err := dir.Open()
+			So(err, ShouldBeNil)
+			fis, err := d.Readdir(2)
+			So(len(fis), ShouldEqual, 2)
+			So(dir.DeleteFolder(), ShouldBeNil)
+			fis, err = d.Readdir(2)
+			So(len(fis), ShouldEqual, 0)
+			So(err, ShouldEqual, io.EOF)
+			d.Close()
+		})
+
+		Convey("Files can be copied, across FS", func() {
+			copied := root.Add("copies").SetDir().Add("b.txt")
+			copied.Dir().Mkdir()
+			So(file.Copy(copied), ShouldBeNil)
+			So(copied.FileContent(), ShouldEqual, "abcd")
+			other := NewPathFS(NewMemFS(), filepath.FromSlash("/other/"))
+			other.Mkdir()
+			So(file.Copy(other.Add("c.txt")), ShouldBeNil)
+			So(other.Add("c.txt").FileContent(), ShouldEqual, "abcd")
+			So(errors.Is(root.Add("none.txt").Copy(copied), ErrNotExist), ShouldBeTrue)
+			fs.Fail("write", copied.String(), os.ErrPermission)
+			So(errors.Is(file.Copy(copied), ErrPermission), ShouldBeTrue)
+			fs.Fail("write", copied.String(), nil)
+		})
+
+		Convey("Files can be written", func() {
+			written := root.Add("copies").SetDir().Add("w.txt")
+			written.Dir().Mkdir()
+			So(written.WriteFile([]byte("ef")), ShouldBeNil)
+			So(written.WriteFile([]byte("gh")), ShouldBeNil)
+			So(written.FileContent(), ShouldEqual, "gh")
+			fs.Fail("write", written.String(), os.ErrPermission)
+			So(errors.Is(written.WriteFile(nil), ErrPermission), ShouldBeTrue)
+			fs.Fail("write", written.String(), nil)
+		})
+
+		Convey("Folders can be linked", func() {
+			v1 := root.Add("links").Add("v1").SetDir()
+			So(v1.Mkdir(), ShouldBeNil)
+			So(v1.Add("a.txt").WriteFile([]byte("a")), ShouldBeNil)
+			latest := root.Add("links").Add("latest")
+			So(latest.Symlink("v1"), ShouldBeNil)
+			target, err := latest.Readlink()
+			So(err, ShouldBeNil)
+			So(target, ShouldEqual, "v1")
+			So(latest.IsDir(), ShouldBeTrue)
+			So(latest.Add("a.txt").FileContent(), ShouldEqual, "a")
+			So(latest.Symlink("v1"), ShouldNotBeNil)
+			_, err = v1.Readlink()
+			So(err, ShouldNotBeNil)
+			So(latest.Remove(), ShouldBeNil)
+			So(v1.Add("a.txt").Exists(), ShouldBeTrue)
+			_, err = latest.Readlink()
+			So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+			fs.Fail("symlink", latest.String(), os.ErrPermission)
+			So(errors.Is(latest.Symlink("v1"), ErrPermission), ShouldBeTrue)
+			fs.Fail("symlink", latest.String(), nil)
+			So(NoOutput(), ShouldBeTrue)
+		})
+
+		Convey("Faults can be injected", func() {
+			fs.Fail("write", file.String(), os.ErrPermission)
+			_, err := file.OpenFile(true)
+			So(errors.Is(err, ErrPermission), ShouldBeTrue)
+			_, err = file.Content()
+			So(err, ShouldBeNil)
+			fs.Fail("write", file.String(), nil)
+			_, err = file.OpenFile(true)
+			So(err, ShouldBeNil)
+			fs.Fail("stat", root.String(), errors.New("I/O error"))
+			So(root.CheckDir().Error(), ShouldEndWith, "I/O error")
+			fs.Fail("mkdir", root.Add("x").String(), os.ErrPermission)
+			So(errors.Is(root.Add("x").Add("y").Mkdir(), ErrPermission), ShouldBeTrue)
+		})
+
+		Convey("Archives are written and extracted in memory", func() {
+			archive := root.Add("git.zip")
+			files, err := root.Add("git").Compress(archive, Zip, nil)
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, []string{"a.txt"})
+			dest := root.Add("out").SetDir()
+			So(archive.Extract(dest), ShouldBeNil)
+			So(dest.Add("a.txt").FileContent(), ShouldEqual, "abcd")
+			entries, err := archive.List()
+			So(err, ShouldBeNil)
+			So(entries[0].String(), ShouldEqual, "a.txt (4)")
+
+			fs.Fail("write", root.Add("git.tar.gz").String(), os.ErrPermission)
+			_, err = root.Add("git").Compress(root.Add("git.tar.gz"), TarGz, nil)
+			So(errors.Is(err, ErrPermission), ShouldBeTrue)
+			So(root.Add("git.tar.gz").Exists(), ShouldBeFalse)
+		})
+
+		Convey("A MemFS is safe for concurrent use", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					f, _ := root.Add("git").Add(string(rune('a'+i)) + ".log").OpenFile(true)
+					f.Write([]byte("log"))
+					f.Close()
+				}(i)
+			}
+			wg.Wait()
+			fis, _ := root.Add("git").ReadDir(`\.log$`)
+			So(len(fis), ShouldEqual, 20)
+		})
+	})
+}
@@ -15,10 +15,10 @@ func ifioureadall(r io.Reader) ([]byte, error) {
 }
 
 // FileContent returns the content of a file, or "" is error.
-// error is on Stderr
+// error is on Stderr (see Content)
 func (p *Path) FileContent() string {
 	filepath := p
-	f, err := fosopen(filepath.String())
+	f, err := filepath.fs().Open(filepath.String())
 	if err != nil {
 		godbg.Pdbgf("Error while reading content of '%v': '%v'\n", filepath, err)
 		return ""
@@ -35,6 +35,21 @@ func (p *Path) FileContent() string {
 	return content
 }
 
+// Content returns the content of a file, like FileContent,
+// but returns the error (as an *Error).
+func (p *Path) Content() (string, error) {
+	f, err := p.fs().Open(p.String())
+	if err != nil {
+		return "", newError("read", p, err)
+	}
+	defer f.Close()
+	contents, err := fioureadall(bufio.NewReader(f))
+	if err != nil {
+		return "", newError("read", p, err)
+	}
+	return string(contents), nil
+}
+
 var fioureadfile func(filename string) ([]byte, error)
 
 func ifioureadfile(filename string) ([]byte, error) {
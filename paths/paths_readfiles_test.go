@@ -1,9 +1,9 @@
 package paths
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
 	"testing"
 
 	. "github.com/VonC/godbg"
@@ -24,15 +24,15 @@ func TestPathReadFiles(t *testing.T) {
     Error while reading content of '.\': 'read .\: The handle is invalid.'`)
 		})
 		Convey("FileContent can fail for a file", func() {
-			file := NewPath("xxx")
+			fs := NewMemFS()
+			fs.Fail("open", "xxx", errors.New("Error (Read) os.Open for 'xxx'"))
+			file := NewPathFS(fs, "xxx")
 			SetBuffers(nil)
-			fosopen = testrfosopen
 			content := file.FileContent()
 			So(content, ShouldBeEmpty)
 			So(OutString(), ShouldBeEmpty)
 			So(ErrString(), ShouldEqualNL, `  [*Path.FileContent] (func)
-    Error while reading content of 'xxx': 'Error (Read) os.Open for 'xxx''`)
-			fosopen = ifosopen
+    Error while reading content of 'xxx': 'open xxx: Error (Read) os.Open for 'xxx''`)
 		})
 
 		Convey("FileContent can fail reading a file", func() {
@@ -117,13 +117,6 @@ func TestPathReadFiles(t *testing.T) {
 	})
 }
 
-func testrfosopen(name string) (file *os.File, err error) {
-	if name == `xxx` {
-		return nil, fmt.Errorf("Error (Read) os.Open for '%s'", name)
-	}
-	return nil, nil
-}
-
 var pread *Path
 
 func testfioureadall(r io.Reader) ([]byte, error) {
@@ -0,0 +1,59 @@
+package paths
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// TopDir returns the single top-level folder of an archive,
+// empty if its entries are not all in one folder (or if it cannot be listed).
+func (p *Path) TopDir() string {
+	entries, err := p.List()
+	if err != nil || len(entries) == 0 {
+		return ""
+	}
+	top := strings.SplitN(entries[0].Name, "/", 2)[0]
+	content := false
+	for _, e := range entries {
+		switch {
+		case strings.HasPrefix(e.Name, top+"/"):
+			content = true
+		case e.Name != top || !e.IsDir:
+			return ""
+		}
+	}
+	if !content {
+		return ""
+	}
+	return top
+}
+
+// flatten moves the content of a folder to its parent, and removes it.
+// (the folder is renamed first, in case it has a child with its name)
+func flatten(fs FS, dir string) error {
+	tmp := dir + ".strip"
+	if err := fs.Rename(dir, tmp); err != nil {
+		return err
+	}
+	f, err := fs.Open(tmp)
+	if err != nil {
+		return err
+	}
+	fis, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	parent := filepath.Dir(dir)
+	for _, fi := range fis {
+		target := filepath.Join(parent, fi.Name())
+		if _, err := fs.Stat(target); err == nil {
+			return fmt.Errorf("'%s' already exists", target)
+		}
+		if err := fs.Rename(filepath.Join(tmp, fi.Name()), target); err != nil {
+			return err
+		}
+	}
+	return fs.Remove(tmp)
+}
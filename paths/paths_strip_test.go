@@ -0,0 +1,68 @@
+package paths
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStrip(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "strip")
+	defer os.RemoveAll(dir)
+	wrapped := filepath.Join(dir, "wrapped")
+	os.MkdirAll(filepath.Join(wrapped, "git-2.0", "bin"), 0755)
+	ioutil.WriteFile(filepath.Join(wrapped, "git-2.0", "bin", "git.exe"), []byte("git"), 0755)
+	os.MkdirAll(filepath.Join(wrapped, "git-2.0", "git-2.0"), 0755)
+	ioutil.WriteFile(filepath.Join(wrapped, "git-2.0", "git-2.0", "README"), []byte("readme"), 0644)
+	flat := filepath.Join(dir, "flat")
+	os.MkdirAll(filepath.Join(flat, "bin"), 0755)
+	ioutil.WriteFile(filepath.Join(flat, "bin", "git.exe"), []byte("git"), 0755)
+	ioutil.WriteFile(filepath.Join(flat, "README"), []byte("readme"), 0644)
+	wrappedZip := NewPath(filepath.Join(dir, "wrapped.zip"))
+	flatZip := NewPath(filepath.Join(dir, "flat.zip"))
+	NewPathDir(wrapped).Compress(wrappedZip, Zip, nil)
+	NewPathDir(flat).Compress(flatZip, Zip, nil)
+
+	Convey("A single top-level folder is detected", t, func() {
+		SetBuffers(nil)
+		So(wrappedZip.TopDir(), ShouldEqual, "git-2.0")
+		So(flatZip.TopDir(), ShouldEqual, "")
+		So(NewPath(filepath.Join(dir, "none.zip")).TopDir(), ShouldEqual, "")
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("It can be stripped during extraction", func() {
+			dest := NewPathDir(filepath.Join(dir, "dest1"))
+			dest.MkdirAll()
+			top, err := wrappedZip.Uncompress(dest, true)
+			So(err, ShouldBeNil)
+			So(top, ShouldEqual, "git-2.0")
+			So(dest.Add("bin").Add("git.exe").Exists(), ShouldBeTrue)
+			So(dest.Add("git-2.0").Add("README").Exists(), ShouldBeTrue)
+			So(NewPath(dest.Add("git-2.0").String()+".strip").Exists(), ShouldBeFalse)
+		})
+
+		Convey("It can be kept during extraction", func() {
+			dest := NewPathDir(filepath.Join(dir, "dest2"))
+			dest.MkdirAll()
+			top, err := wrappedZip.Uncompress(dest, false)
+			So(err, ShouldBeNil)
+			So(top, ShouldEqual, "git-2.0")
+			So(dest.Add("git-2.0").Add("bin").Add("git.exe").Exists(), ShouldBeTrue)
+		})
+
+		Convey("A flat archive is extracted as is", func() {
+			dest := NewPathDir(filepath.Join(dir, "dest3"))
+			dest.MkdirAll()
+			top, err := flatZip.Uncompress(dest, true)
+			So(err, ShouldBeNil)
+			So(top, ShouldEqual, "")
+			So(dest.Add("bin").Add("git.exe").Exists(), ShouldBeTrue)
+			So(dest.Add("README").Exists(), ShouldBeTrue)
+		})
+	})
+}
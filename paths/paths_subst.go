@@ -1,88 +1,241 @@
 package paths
 
 import (
+	"bufio"
+	"fmt"
+	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/VonC/godbg"
 )
 
-var subst map[string]string
+// Alias maps a short path (like a subst drive 'P:') to its long form
+type Alias struct {
+	Short string
+	Long  string
+}
+
+// AliasProvider provides path aliases (subst drives, mapped drives, ...)
+type AliasProvider interface {
+	Aliases() ([]*Alias, error)
+}
 
 var fcmdgetsubst func() (out string, err error)
 
 func ifcmdgetsubst() (sout string, err error) {
-	// godbg.Perrdbgf("invoking subst")
 	c := exec.Command("cmd", "/C", "subst")
 	out, err := c.Output()
 	sout = string(out)
 	return sout, err
 }
 
-func getSubst() map[string]string {
-	if subst != nil {
-		return subst
-	}
-	subst = make(map[string]string)
-	var substRx, _ = regexp.Compile(`(?ms)([A-Z]:\\): => ([A-Z]:.*?)$`)
+type substProvider struct{}
+
+// SubstProvider provides the drives defined with 'subst'
+func SubstProvider() AliasProvider {
+	return substProvider{}
+}
+
+var substRx = regexp.MustCompile(`(?ms)([A-Z]:\\): => ([A-Z]:.*?)$`)
+
+func (sp substProvider) Aliases() ([]*Alias, error) {
 	sout, err := fcmdgetsubst()
 	if err != nil {
-		godbg.Pdbgf("Error invoking subst\n'%v':\nerr='%v'\n", sout, err)
-		return nil
+		return nil, fmt.Errorf("error invoking subst\n'%v':\nerr='%v'", sout, err)
+	}
+	res := []*Alias{}
+	for _, m := range substRx.FindAllStringSubmatch(sout, -1) {
+		res = append(res, &Alias{Short: m[1], Long: strings.TrimSpace(m[2])})
+	}
+	return res, nil
+}
+
+// AliasesFile is the default name of an alias config file
+const AliasesFile = "aliases.conf"
+
+type fileProvider struct {
+	file string
+}
+
+// FileProvider reads aliases from a config file, one 'short=long' per line
+// ('#' starts a comment). A missing file defines no alias.
+func FileProvider(file string) AliasProvider {
+	return &fileProvider{file: file}
+}
+
+func (fp *fileProvider) Aliases() ([]*Alias, error) {
+	f, err := os.Open(fp.file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
-	// godbg.Perrdbgf("subst='%v'", sout)
-	matches := substRx.FindAllStringSubmatchIndex(sout, -1)
-	// godbg.Perrdbgf("matches OUT: '%v'\n", matches)
-	for _, m := range matches {
-		drive := sout[m[2]:m[3]]
-		substPath := strings.TrimSpace(sout[m[4]:m[5]])
-		subst[drive] = substPath
-		// godbg.Perrdbgf("drive='%v', substPath='%v'", drive, substPath)
+	defer f.Close()
+	res := []*Alias{}
+	scanner := bufio.NewScanner(f)
+	for nline := 1; scanner.Scan(); nline++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		elts := strings.SplitN(line, "=", 2)
+		if len(elts) != 2 || strings.TrimSpace(elts[0]) == "" || strings.TrimSpace(elts[1]) == "" {
+			return nil, fmt.Errorf("'%s' line %d: invalid alias '%s'", fp.file, nline, line)
+		}
+		res = append(res, &Alias{Short: strings.TrimSpace(elts[0]), Long: strings.TrimSpace(elts[1])})
 	}
-	// godbg.Perrdbgf("subst = '%v'", subst)
-	return subst
+	return res, scanner.Err()
+}
+
+type memProvider []*Alias
+
+// MemProvider provides fixed aliases (for tests)
+func MemProvider(aliases ...*Alias) AliasProvider {
+	return memProvider(aliases)
 }
 
-// NoSubst returns the path no using a subst path.
-// If no subst or matching subst, returns the same object.
-// If matching substs, returns new path with long form.
+func (mp memProvider) Aliases() ([]*Alias, error) {
+	return mp, nil
+}
+
+// AliasTable caches the aliases of its providers.
+// It is safe for concurrent use, and only calls its providers
+// on first use, or on Refresh.
+type AliasTable struct {
+	mu        sync.RWMutex
+	load      sync.Once
+	providers []AliasProvider
+	aliases   []*Alias
+	loaded    bool
+}
+
+// NewAliasTable returns an alias table, the first providers taking precedence
+func NewAliasTable(providers ...AliasProvider) *AliasTable {
+	return &AliasTable{providers: providers}
+}
+
+var aliases *AliasTable
+
+func init() {
+	fcmdgetsubst = ifcmdgetsubst
+	aliases = NewAliasTable(SubstProvider())
+}
+
+// SetAliases replaces the alias table used by Subst and NoSubst
+// (by default, only the 'subst' drives)
+func SetAliases(t *AliasTable) {
+	aliases = t
+}
+
+// Refresh reads the aliases of all providers again.
+// A provider in error is skipped: its error is returned, after
+// reading the other providers.
+func (t *AliasTable) Refresh() error {
+	res := []*Alias{}
+	var rerr error
+	for _, p := range t.providers {
+		as, err := p.Aliases()
+		if err != nil {
+			godbg.Pdbgf("Error reading aliases: '%v'", err)
+			if rerr == nil {
+				rerr = err
+			}
+			continue
+		}
+		for _, a := range as {
+			res = append(res, &Alias{Short: trimSep(a.Short), Long: trimSep(a.Long)})
+		}
+	}
+	t.mu.Lock()
+	t.aliases = res
+	t.loaded = true
+	t.mu.Unlock()
+	return rerr
+}
+
+// Aliases returns a copy of the aliases, read on first use
+// (only once, even by concurrent first callers)
+func (t *AliasTable) Aliases() []*Alias {
+	t.load.Do(func() {
+		t.mu.RLock()
+		loaded := t.loaded
+		t.mu.RUnlock()
+		if !loaded {
+			t.Refresh()
+		}
+	})
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	res := []*Alias{}
+	for _, a := range t.aliases {
+		res = append(res, &Alias{Short: a.Short, Long: a.Long})
+	}
+	return res
+}
+
+// Long returns a path with its aliased prefix replaced by its long form,
+// or the path unchanged if no alias matches.
+func (t *AliasTable) Long(path string) string {
+	return t.replace(path, func(a *Alias) (string, string) { return a.Short, a.Long })
+}
+
+// Short returns a path with its longest matching prefix replaced by its alias,
+// or the path unchanged if no alias matches.
+func (t *AliasTable) Short(path string) string {
+	return t.replace(path, func(a *Alias) (string, string) { return a.Long, a.Short })
+}
+
+func (t *AliasTable) replace(path string, fromTo func(a *Alias) (string, string)) string {
+	as := t.Aliases()
+	// the first provider wins on a tie: stable sort on the prefix length
+	sort.SliceStable(as, func(i, j int) bool {
+		fi, _ := fromTo(as[i])
+		fj, _ := fromTo(as[j])
+		return len(fi) > len(fj)
+	})
+	for _, a := range as {
+		from, to := fromTo(a)
+		if from == "" || !strings.HasPrefix(path, from) {
+			continue
+		}
+		if rest := path[len(from):]; rest == "" || rest[0] == '\\' || rest[0] == '/' {
+			return to + rest
+		}
+	}
+	return path
+}
+
+func trimSep(path string) string {
+	return strings.TrimRight(strings.TrimSpace(path), `\/`)
+}
+
+// NoSubst returns the path not using an alias (like a subst drive).
+// If no matching alias, returns the same object.
+// If matching alias, returns new path with long form.
 func (p *Path) NoSubst() *Path {
-	if len(getSubst()) == 0 || p.IsEmpty() {
+	if p.IsEmpty() {
 		return p
 	}
-	// godbg.Perrdbgf("No subst on path '%v'", p)
-	for drive, sp := range getSubst() {
-		// godbg.Perrdbgf("No subst drive='%v, sp='%v': p.path='%v'", drive, sp, p.path)
-		if strings.HasPrefix(p.path, drive) {
-			np := strings.Replace(p.path, drive, sp+"\\", -1)
-			// godbg.Perrdbgf("Reverse subst from '%v' to '%v'", p.path, np)
-			return NewPath(np)
-		}
+	if np := aliases.Long(p.path); np != p.path {
+		return NewPathFS(p.filesys, np)
 	}
 	return p
 }
 
-// Subst returns the path using a subst path.
-// If no subst ormatching subst, returns the same object.
-// If matching subst, returns new path with the short SUBSTed form.
+// Subst returns the path using an alias (like a subst drive).
+// If no matching alias, returns the same object.
+// If matching alias, returns new path with the short form.
 func (p *Path) Subst() *Path {
-	if len(getSubst()) == 0 || p.IsEmpty() {
+	if p.IsEmpty() {
 		return p
 	}
-	// godbg.Perrdbgf("Subst on path '%v'", p)
-	for drive, sp := range getSubst() {
-		// godbg.Perrdbgf("Subst drive='%v, sp='%v': p.path='%v'", drive, sp, p.path)
-		if strings.HasPrefix(p.path, sp) {
-			np := strings.Replace(p.path, sp, drive, -1)
-			np = strings.Replace(np, drive+"\\", drive, -1)
-			// godbg.Perrdbgf("Subst from '%v' to '%v'", p.path, np)
-			return NewPath(np)
-		}
+	if np := aliases.Short(p.path); np != p.path {
+		return NewPathFS(p.filesys, np)
 	}
 	return p
 }
-
-func init() {
-	fcmdgetsubst = ifcmdgetsubst
-}
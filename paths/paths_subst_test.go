@@ -2,7 +2,13 @@ package paths
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/VonC/godbg"
 	. "github.com/smartystreets/goconvey/convey"
@@ -13,7 +19,7 @@ func TestSubst(t *testing.T) {
 	Convey("Tests for NoSubst", t, func() {
 
 		Convey("Path is returned identical if no subst", func() {
-			subst = make(map[string]string)
+			SetAliases(NewAliasTable(MemProvider()))
 			p := NewPath(".")
 			ps := p.String()
 			SetBuffers(nil)
@@ -21,11 +27,9 @@ func TestSubst(t *testing.T) {
 			So(p, ShouldEqual, pp)
 			So(ps, ShouldEqual, pp.String())
 			So(NoOutput(), ShouldBeTrue)
-			subst = nil
 		})
 		Convey("Path is returned identical if path is empty", func() {
-			subst = make(map[string]string)
-			subst["a"] = "b"
+			SetAliases(NewAliasTable(MemProvider(&Alias{Short: "a", Long: "b"})))
 			var p *Path
 			SetBuffers(nil)
 			pp := p.NoSubst()
@@ -44,25 +48,25 @@ func TestSubst(t *testing.T) {
 			So(p, ShouldEqual, pp)
 			So(ps, ShouldEqual, pp.String())
 			So(NoOutput(), ShouldBeTrue)
-			subst = nil
 		})
 
 		Convey("subst can fail to execute", func() {
+			SetAliases(NewAliasTable(SubstProvider()))
 			p := NewPath("abc")
 			fcmdgetsubst = testfcmdgetsubst
 			SetBuffers(nil)
 			pp := p.NoSubst()
 			So(p, ShouldEqual, pp)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqualNL, `    [getSubst] (*Path.NoSubst) (func)
-      Error invoking subst
+			So(ErrString(), ShouldEqualNL, `    [*AliasTable.Refresh] (*AliasTable.Aliases) (*AliasTable.replace)
+      Error reading aliases: 'error invoking subst
 '':
-err='Error on subst command execution'`)
+err='Error on subst command execution''`)
 			fcmdgetsubst = ifcmdgetsubst
-			subst = nil
 		})
 
 		Convey("Path is returned in long form if subst is found", func() {
+			SetAliases(NewAliasTable(SubstProvider()))
 			p := NewPath("P:/paths/paths.go")
 			ps := p.String()
 			fcmdgetsubst = testfcmdgetsubst2
@@ -73,7 +77,6 @@ err='Error on subst command execution'`)
 			So(pp.String(), ShouldEqual, `C:\a\b\paths\paths.go`)
 			So(NoOutput(), ShouldBeTrue)
 			fcmdgetsubst = ifcmdgetsubst
-			subst = nil
 		})
 
 	})
@@ -81,7 +84,7 @@ err='Error on subst command execution'`)
 	Convey("Tests for Subst", t, func() {
 
 		Convey("Path is returned identical if no subst", func() {
-			subst = make(map[string]string)
+			SetAliases(NewAliasTable(MemProvider()))
 			p := NewPath(".")
 			ps := p.String()
 			SetBuffers(nil)
@@ -89,11 +92,9 @@ err='Error on subst command execution'`)
 			So(p, ShouldEqual, pp)
 			So(ps, ShouldEqual, pp.String())
 			So(NoOutput(), ShouldBeTrue)
-			subst = nil
 		})
 		Convey("Path is returned identical if path is empty", func() {
-			subst = make(map[string]string)
-			subst["a"] = "b"
+			SetAliases(NewAliasTable(MemProvider(&Alias{Short: "a", Long: "b"})))
 			var p *Path
 			SetBuffers(nil)
 			pp := p.Subst()
@@ -112,10 +113,10 @@ err='Error on subst command execution'`)
 			So(p, ShouldEqual, pp)
 			So(ps, ShouldEqual, pp.String())
 			So(NoOutput(), ShouldBeTrue)
-			subst = nil
 		})
 
 		Convey("Path is returned in short form if subst is found", func() {
+			SetAliases(NewAliasTable(SubstProvider()))
 			p := NewPath("C:/a/b/paths/paths.go")
 			ps := p.String()
 			fcmdgetsubst = testfcmdgetsubst2
@@ -126,19 +127,79 @@ err='Error on subst command execution'`)
 			So(pp.String(), ShouldEqual, `P:\paths\paths.go`)
 			So(NoOutput(), ShouldBeTrue)
 			fcmdgetsubst = ifcmdgetsubst
-			subst = nil
 		})
 
 	})
 
+	SetAliases(NewAliasTable(SubstProvider()))
+}
+
+func TestAliasTable(t *testing.T) {
+
+	Convey("An alias table maps paths both ways", t, func() {
+		SetBuffers(nil)
+		at := NewAliasTable(
+			MemProvider(&Alias{Short: `P:\`, Long: `C:\a\b`}, &Alias{Short: `Q:`, Long: `C:\a\b\c`}),
+			MemProvider(&Alias{Short: `R:`, Long: `C:\a\b`}))
+		So(at.Long(`P:\x`), ShouldEqual, `C:\a\b\x`)
+		So(at.Long(`Q:`), ShouldEqual, `C:\a\b\c`)
+		So(at.Long(`PP:\x`), ShouldEqual, `PP:\x`)
+		So(at.Short(`C:\a\b\c\d`), ShouldEqual, `Q:\d`)
+		So(at.Short(`C:\a\b\x`), ShouldEqual, `P:\x`)
+		So(at.Short(`C:\a\bc`), ShouldEqual, `C:\a\bc`)
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("Aliases are cached until refreshed", func() {
+			var first int32
+			fcmdgetsubst = func() (string, error) {
+				atomic.AddInt32(&first, 1)
+				time.Sleep(10 * time.Millisecond)
+				return `P:\: => C:\a\b`, nil
+			}
+			at := NewAliasTable(SubstProvider())
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					at.Long(`P:\x`)
+				}()
+			}
+			wg.Wait()
+			So(atomic.LoadInt32(&first), ShouldEqual, int32(1))
+			So(at.Long(`P:\x`), ShouldEqual, `C:\a\b\x`)
+			So(atomic.LoadInt32(&first), ShouldEqual, int32(1))
+			calls := 0
+			fcmdgetsubst = func() (string, error) {
+				calls++
+				return `P:\: => D:\e`, nil
+			}
+			So(at.Refresh(), ShouldBeNil)
+			So(calls, ShouldEqual, 1)
+			So(at.Long(`P:\x`), ShouldEqual, `D:\e\x`)
+			fcmdgetsubst = ifcmdgetsubst
+		})
+
+		Convey("Aliases can be read from a config file", func() {
+			dir, _ := ioutil.TempDir("", "aliases")
+			defer os.RemoveAll(dir)
+			file := filepath.Join(dir, AliasesFile)
+			ioutil.WriteFile(file, []byte("# mapped drives\nN: = \\\\server\\share\n\nS:=C:\\prgs\n"), 0644)
+			at := NewAliasTable(FileProvider(file), FileProvider(filepath.Join(dir, "none.conf")))
+			So(at.Aliases(), ShouldResemble, []*Alias{{Short: "N:", Long: `\\server\share`}, {Short: "S:", Long: `C:\prgs`}})
+			So(at.Short(`C:\prgs\go`), ShouldEqual, `S:\go`)
+			ioutil.WriteFile(file, []byte("N:\n"), 0644)
+			err := at.Refresh()
+			So(err.Error(), ShouldEqual, fmt.Sprintf("'%s' line 1: invalid alias 'N:'", file))
+			So(at.Aliases(), ShouldBeEmpty)
+		})
+	})
 }
 
 func testfcmdgetsubst() (sout string, err error) {
-	// godbg.Perrdbgf("invoking subst")
 	return "", fmt.Errorf("Error on subst command execution")
 }
 
 func testfcmdgetsubst2() (sout string, err error) {
-	ifcmdgetsubst()
 	return `P:\: => C:\a\b`, nil
 }
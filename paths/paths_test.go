@@ -1,8 +1,8 @@
 package paths
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -74,15 +74,16 @@ func TestPath(t *testing.T) {
 		})
 		Convey("IsDir() can fail on f.Stat()", func() {
 			// Errors
-			fstat = testerrfstat
-			p := NewPath("..")
+			fs := NewMemFS()
+			fs.MkdirAll("..", 0755)
+			fs.Fail("stat", "..", fmt.Errorf("fstat error on '..'"))
+			p := NewPathFS(fs, "..")
 			SetBuffers(nil)
 			So(p.IsDir(), ShouldBeFalse)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqual, `fstat error on '..'
+			So(ErrString(), ShouldEqual, `stat ..: fstat error on '..'
 `)
 			So(p.path, ShouldEqual, `..`)
-			fstat = ifstat
 		})
 	})
 
@@ -118,16 +119,17 @@ func TestPath(t *testing.T) {
 
 		Convey("Exists() can fail on os.Stat()", func() {
 			// Stat error on path
-			fosstat = testerrosfstat
-			p := NewPath("test")
+			fs := NewMemFS()
+			fs.Fail("stat", "test", errors.New("I/O error"))
+			p := NewPathFS(fs, "test")
 			SetBuffers(nil)
 			So(p.Exists(), ShouldBeFalse)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqual, `os.Stat() error on 'test'
+			So(ErrString(), ShouldEqual, `stat test: I/O error
 `)
 			So(p.path, ShouldEqual, `test`)
-			fosstat = ifosstat
 		})
+
 	})
 
 	Convey("Tests for Path.String()", t, func() {
@@ -358,13 +360,12 @@ func TestPath(t *testing.T) {
 	Convey("Tests for MkdirAll()", t, func() {
 
 		Convey("MkdirAll() when works without error", func() {
-			fosmkdirall = testfosmkdirall
-			p := NewPath("aaa")
+			p := NewPathFS(NewMemFS(), filepath.FromSlash("/aaa"))
 			SetBuffers(nil)
 			ok := p.MkdirAll()
 			So(ok, ShouldBeTrue)
+			So(p.IsDir(), ShouldBeTrue)
 			So(NoOutput(), ShouldBeTrue)
-			fosmkdirall = ifosmkdirall
 
 			p = NewPath(".")
 			SetBuffers(nil)
@@ -375,15 +376,15 @@ func TestPath(t *testing.T) {
 		})
 
 		Convey("MkdirAll() when works with error", func() {
-			fosmkdirall = testfosmkdirall
-			p := NewPath("err")
+			fs := NewMemFS()
+			fs.Fail("mkdir", "err", errors.New("testfosmkdirall error on path 'err'"))
+			p := NewPathFS(fs, "err")
 			SetBuffers(nil)
 			ok := p.MkdirAll()
 			So(ok, ShouldBeFalse)
 			So(OutString(), ShouldBeEmpty)
-			So(ErrString(), ShouldEqual, `Error creating folder for path 'err': 'testfosmkdirall error on path 'err''
+			So(ErrString(), ShouldEqual, `Error creating folder for path 'err': 'mkdir err: testfosmkdirall error on path 'err''
 `)
-			fosmkdirall = ifosmkdirall
 		})
 	})
 
@@ -395,65 +396,62 @@ func TestPath(t *testing.T) {
 			So(f, ShouldBeNil)
 		})
 		Convey("MustOpenFile() can append to an existing file", func() {
-			fosopenfile = testfosopenfile
-			fosremove = testfosremove
-			p := NewPath("paths_test.go")
+			fs := NewMemFS()
+			p := NewPathFS(fs, "paths_test.go")
+			p.WriteFile([]byte("a"))
 			SetBuffers(nil)
 			f := p.MustOpenFile(true)
 			defer f.Close()
 			So(f, ShouldNotBeNil)
 			So(f.Name(), ShouldEqual, "paths_test.go")
+			f.Write([]byte("b"))
+			So(p.FileContent(), ShouldEqual, "ab")
 			So(NoOutput(), ShouldBeTrue)
-			fosopenfile = ifosopenfile
-			fosremove = ifosremove
 		})
 
 		Convey("MustOpenFile() can create over an existing file", func() {
-			fosopenfile = testfosopenfile
-			fosremove = testfosremove
-			p := NewPath("paths_test.go")
+			fs := NewMemFS()
+			p := NewPathFS(fs, "paths_test.go")
+			p.WriteFile([]byte("a"))
 			SetBuffers(nil)
 			f := p.MustOpenFile(false)
 			defer f.Close()
 			So(f, ShouldNotBeNil)
 			So(f.Name(), ShouldEqual, "paths_test.go")
+			f.Write([]byte("b"))
+			So(p.FileContent(), ShouldEqual, "b")
 			So(NoOutput(), ShouldBeTrue)
-			fosopenfile = ifosopenfile
-			fosremove = ifosremove
 		})
 
 		Convey("MustOpenFile() can fail on append", func() {
-			fosopenfile = testfosopenfile
-			fosremove = testfosremove
-			p := NewPath("paths.go")
+			fs := NewMemFS()
+			p := NewPathFS(fs, "paths.go")
+			p.WriteFile([]byte("a"))
+			fs.Fail("write", "paths.go", fmt.Errorf("Error os.OpenFile O_APPEND for 'paths.go'"))
 			SetBuffers(nil)
-			var f *os.File
+			var f File
 			defer func() {
 				err := recover()
 				So(f, ShouldBeNil)
 				So(NoOutput(), ShouldBeTrue)
-				So(fmt.Sprintf("'%v'", err), ShouldEqual, "'Error os.OpenFile O_APPEND for 'paths.go''")
-				fosopenfile = ifosopenfile
-				fosremove = ifosremove
+				So(fmt.Sprintf("'%v'", err), ShouldEqual, "'write paths.go: Error os.OpenFile O_APPEND for 'paths.go''")
 			}()
 			f = p.MustOpenFile(true)
 		})
 
 		Convey("MustOpenFile() can fail on create", func() {
-			fosopenfile = testfosopenfile
-			fosremove = testfosremove
-			p := NewPath("xxx")
+			fs := NewMemFS()
+			p := NewPathFS(fs, "xxx")
+			fs.Fail("write", "xxx", fmt.Errorf("Error os.OpenFile O_CREATE for 'xxx'"))
 			SetBuffers(nil)
-			var f *os.File
+			var f File
 			defer func() {
 				err := recover()
 				So(f, ShouldBeNil)
 				So(OutString(), ShouldBeEmpty)
-				So(ErrString(), ShouldEqual, `open xxx: The system cannot find the file specified.
+				So(ErrString(), ShouldEqual, `open xxx: file does not exist
 `)
-				So(fmt.Sprintf("'%v'", err), ShouldEqual, "'Error os.OpenFile O_CREATE for 'xxx''")
-				fosopenfile = ifosopenfile
-				fosremove = ifosremove
+				So(fmt.Sprintf("'%v'", err), ShouldEqual, "'write xxx: Error os.OpenFile O_CREATE for 'xxx''")
 			}()
 			f = p.MustOpenFile(true)
 		})
@@ -838,36 +836,3 @@ func testfpabs(path string) (string, error) {
 	}
 	return filepath.Abs(path)
 }
-
-func testfosopenfile(name string, flag int, perm os.FileMode) (file *os.File, err error) {
-	if name == "paths_test.go" {
-		return ifosopenfile(name, flag, perm)
-	}
-	if name == "paths.go" && flag&os.O_APPEND != 0 {
-		return nil, fmt.Errorf("Error os.OpenFile O_APPEND for '%s'", name)
-	}
-	if name == "xxx" {
-		return nil, fmt.Errorf("Error os.OpenFile O_CREATE for '%s'", name)
-	}
-	return nil, nil
-}
-
-func testfosremove(name string) error {
-	ifosremove("xxx")
-	return nil
-}
-
-func testfosmkdirall(path string, perm os.FileMode) error {
-	if path == "err" {
-		return fmt.Errorf("testfosmkdirall error on path '%s'", path)
-	}
-	return nil
-}
-
-func testerrfstat(f *os.File) (fi os.FileInfo, err error) {
-	return nil, fmt.Errorf("fstat error on '%+v'", f.Name())
-}
-func testerrosfstat(name string) (fi os.FileInfo, err error) {
-	err = fmt.Errorf("os.Stat() error on '%s'", name)
-	return nil, err
-}
@@ -0,0 +1,228 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Matcher selects files or folders of a walked folder,
+// from their '/' separated path relative to that folder.
+type Matcher interface {
+	Match(rel string) bool
+	String() string
+}
+
+type globMatcher struct {
+	pattern string
+	segs    []string
+}
+
+// Glob compiles a glob pattern (see path.Match), where a '**' folder matches
+// any number of folders: 'bin/*.exe', '**/doc/*.html'.
+// A pattern without '/' is matched against the name only: '*.exe'.
+func Glob(pattern string) (Matcher, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("invalid pattern '': empty")
+	}
+	segs := strings.Split(pattern, "/")
+	for _, seg := range segs {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %v", pattern, err)
+		}
+	}
+	return &globMatcher{pattern: pattern, segs: segs}, nil
+}
+
+// Globs compiles several glob patterns, and fails on the first invalid one.
+func Globs(patterns ...string) ([]Matcher, error) {
+	res := []Matcher{}
+	for _, pattern := range patterns {
+		m, err := Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+func (g *globMatcher) Match(rel string) bool {
+	if len(g.segs) == 1 {
+		ok, _ := path.Match(g.pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegs(g.segs, strings.Split(rel, "/"))
+}
+
+func matchSegs(segs, elts []string) bool {
+	for len(segs) > 0 {
+		if segs[0] == "**" {
+			for i := 0; i <= len(elts); i++ {
+				if matchSegs(segs[1:], elts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(segs[0], elts[0]); !ok {
+			return false
+		}
+		segs, elts = segs[1:], elts[1:]
+	}
+	return len(elts) == 0
+}
+
+func (g *globMatcher) String() string {
+	return g.pattern
+}
+
+type rxMatcher struct {
+	rx *regexp.Regexp
+}
+
+// Regexp compiles a regular expression, matched against the whole
+// relative path: use '(^|/)' and '$' to match a name.
+func Regexp(pattern string) (Matcher, error) {
+	rx, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %v", pattern, err)
+	}
+	return &rxMatcher{rx: rx}, nil
+}
+
+func (r *rxMatcher) Match(rel string) bool {
+	return r.rx.MatchString(rel)
+}
+
+func (r *rxMatcher) String() string {
+	return r.rx.String()
+}
+
+func matchAny(rel string, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if m.Match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// WalkOptions selects what Walk visits. All fields are optional.
+type WalkOptions struct {
+	// Match are the files to visit (all if empty)
+	Match []Matcher
+	// Exclude are files or folders not to visit: an excluded folder is skipped
+	Exclude []Matcher
+	// MaxDepth is the depth of the walk, 1 for the folder content only, 0 for no limit
+	MaxDepth int
+	// Dirs is for visiting folders (matching Match) too, not only files
+	Dirs bool
+}
+
+// WalkFunc is called by Walk with the '/' separated path of a file,
+// relative to the walked folder.
+// Returning filepath.SkipDir for a folder skips its content.
+type WalkFunc func(rel string, fi os.FileInfo) error
+
+// Walk visits recursively, in name order, the files of a folder
+// selected by opts (nil for all files), and stops on the first fn error.
+// A folder which cannot be read is returned as an *Error.
+func (dir *Path) Walk(opts *WalkOptions, fn WalkFunc) error {
+	if opts == nil {
+		opts = &WalkOptions{}
+	}
+	if err := dir.CheckDir(); err != nil {
+		return err
+	}
+	root := dir.NoSep().String()
+	return walkFS(dir.fs(), root, func(fpath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return newError("read folder", &Path{path: fpath, filesys: dir.filesys}, err)
+		}
+		if fpath == root {
+			return nil
+		}
+		rel := filepath.ToSlash(fpath[len(root)+1:])
+		if matchAny(rel, opts.Exclude) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		selected := len(opts.Match) == 0 || matchAny(rel, opts.Match)
+		if !fi.IsDir() {
+			if selected {
+				return fn(rel, fi)
+			}
+			return nil
+		}
+		if opts.Dirs && selected {
+			if err = fn(rel, fi); err != nil {
+				return err
+			}
+		}
+		if opts.MaxDepth > 0 && strings.Count(rel, "/")+1 >= opts.MaxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// found is a file found by Find, named after its relative path
+type found struct {
+	os.FileInfo
+	rel string
+}
+
+func (f *found) Name() string {
+	return f.rel
+}
+
+// Find returns the files Walk visits, sorted by name: their Name()
+// is their '/' separated path relative to dir.
+func (dir *Path) Find(opts *WalkOptions) ([]os.FileInfo, error) {
+	res := []os.FileInfo{}
+	err := dir.Walk(opts, func(rel string, fi os.FileInfo) error {
+		res = append(res, &found{FileInfo: fi, rel: rel})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(byName(res))
+	return res, nil
+}
+
+// FindDateOrdered returns the files Walk visits, like Find,
+// but sorted chronologically (most recent to oldest).
+func (dir *Path) FindDateOrdered(opts *WalkOptions) ([]os.FileInfo, error) {
+	res, err := dir.Find(opts)
+	if err != nil {
+		return nil, err
+	}
+	sort.Stable(byDate(res))
+	return res, nil
+}
+
+// Size returns the total size of the files Walk visits.
+func (dir *Path) Size(opts *WalkOptions) (int64, error) {
+	var res int64
+	err := dir.Walk(opts, func(rel string, fi os.FileInfo) error {
+		if !fi.IsDir() {
+			res += fi.Size()
+		}
+		return nil
+	})
+	return res, err
+}
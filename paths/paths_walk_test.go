@@ -0,0 +1,138 @@
+package paths
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func names(fis []os.FileInfo) []string {
+	res := []string{}
+	for _, fi := range fis {
+		res = append(res, fi.Name())
+	}
+	return res
+}
+
+func testTree(fs FS, root *Path, files ...string) {
+	for _, file := range files {
+		p := NewPathFS(fs, filepath.Join(root.String(), filepath.FromSlash(file)))
+		p.Dir().Mkdir()
+		f, _ := p.OpenFile(false)
+		f.Write([]byte(file))
+		f.Close()
+	}
+}
+
+func TestWalk(t *testing.T) {
+
+	Convey("Glob and regexp patterns are compiled with an error", t, func() {
+		SetBuffers(nil)
+		_, err := Glob("bin/[a")
+		So(err.Error(), ShouldEqual, "invalid pattern 'bin/[a': syntax error in pattern")
+		_, err = Glob("")
+		So(err, ShouldNotBeNil)
+		_, err = Globs("*.exe", "a[")
+		So(err.Error(), ShouldStartWith, "invalid pattern 'a['")
+		_, err = Regexp("a(")
+		So(err.Error(), ShouldStartWith, "invalid pattern 'a(': ")
+		So(NewPath(".").GetFiles("a("), ShouldBeNil)
+		So(ErrString(), ShouldContainSubstring, "Invalid pattern 'a(' for dir './'")
+
+		g, _ := Glob("*.exe")
+		So(g.Match("bin/git.exe"), ShouldBeTrue)
+		So(g.String(), ShouldEqual, "*.exe")
+		g, _ = Glob("**/doc/*.html")
+		So(g.Match("doc/a.html"), ShouldBeTrue)
+		So(g.Match("share/git/doc/a.html"), ShouldBeTrue)
+		So(g.Match("doc/sub/a.html"), ShouldBeFalse)
+		g, _ = Glob("bin/**")
+		So(g.Match("bin/a/b"), ShouldBeTrue)
+		So(g.Match("lib/a"), ShouldBeFalse)
+		r, _ := Regexp(`(^|/)git\.exe$`)
+		So(r.Match("bin/git.exe"), ShouldBeTrue)
+		So(r.Match("bin/mygit.exe"), ShouldBeFalse)
+		So(r.String(), ShouldEqual, `(^|/)git\.exe$`)
+	})
+
+	Convey("A folder is walked recursively", t, func() {
+		SetBuffers(nil)
+		fs := NewMemFS()
+		root := NewPathFS(fs, filepath.FromSlash("/prgs/git/"))
+		testTree(fs, root, "bin/git.exe", "bin/sh.exe", "a.txt", "doc/html/index.html", "doc/README", "tmp/x.exe")
+
+		fis, err := root.Find(nil)
+		So(err, ShouldBeNil)
+		So(names(fis), ShouldResemble, []string{"a.txt", "bin/git.exe", "bin/sh.exe", "doc/README", "doc/html/index.html", "tmp/x.exe"})
+		size, err := root.Size(nil)
+		So(err, ShouldBeNil)
+		So(size, ShouldEqual, int64(5+11+10+19+10+9))
+
+		match, _ := Globs("*.exe")
+		exclude, _ := Globs("tmp")
+		fis, err = root.Find(&WalkOptions{Match: match, Exclude: exclude})
+		So(err, ShouldBeNil)
+		So(names(fis), ShouldResemble, []string{"bin/git.exe", "bin/sh.exe"})
+
+		fis, _ = root.Find(&WalkOptions{MaxDepth: 1})
+		So(names(fis), ShouldResemble, []string{"a.txt"})
+		fis, _ = root.Find(&WalkOptions{MaxDepth: 1, Dirs: true})
+		So(names(fis), ShouldResemble, []string{"a.txt", "bin", "doc", "tmp"})
+		exclude, _ = Globs("bin")
+		fis, _ = root.Find(&WalkOptions{MaxDepth: 2, Exclude: exclude})
+		So(names(fis), ShouldResemble, []string{"a.txt", "doc/README", "tmp/x.exe"})
+
+		visited := []string{}
+		err = root.Walk(nil, func(rel string, fi os.FileInfo) error {
+			visited = append(visited, rel)
+			if rel == "bin/git.exe" {
+				return filepath.SkipDir
+			}
+			if rel == "doc/html/index.html" {
+				return errors.New("stop")
+			}
+			return nil
+		})
+		So(err.Error(), ShouldEqual, "stop")
+		So(visited, ShouldResemble, []string{"a.txt", "bin/git.exe", "doc/README", "doc/html/index.html"})
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("Walk errors are typed", t, func() {
+		SetBuffers(nil)
+		fs := NewMemFS()
+		root := NewPathFS(fs, filepath.FromSlash("/prgs/git/"))
+		_, err := root.Find(nil)
+		So(errors.Is(err, ErrNotExist), ShouldBeTrue)
+		testTree(fs, root, "bin/git.exe")
+		bin := root.Add("bin")
+		fs.Fail("open", bin.String(), os.ErrPermission)
+		_, err = root.Find(nil)
+		So(errors.Is(err, ErrPermission), ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "unable to read folder '"+bin.NoSep().String()+"'")
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("Files can be found by date", t, func() {
+		SetBuffers(nil)
+		dir, err := ioutil.TempDir("", "senvgo_walk")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		root := NewPath(dir).SetDir()
+		testTree(OS, root, "a.txt", "b/c.txt", "d.txt")
+		now := time.Now()
+		os.Chtimes(filepath.Join(dir, "a.txt"), now, now.Add(-2*time.Hour))
+		os.Chtimes(filepath.Join(dir, "b", "c.txt"), now, now)
+		os.Chtimes(filepath.Join(dir, "d.txt"), now, now.Add(-time.Hour))
+		fis, err := root.FindDateOrdered(nil)
+		So(err, ShouldBeNil)
+		So(names(fis), ShouldResemble, []string{"b/c.txt", "d.txt", "a.txt"})
+		So(NoOutput(), ShouldBeTrue)
+	})
+}
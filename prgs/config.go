@@ -0,0 +1,210 @@
+package prgs
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VonC/senvgo/cmds"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/version"
+)
+
+// Setting is a 'name=value' entry of a program config,
+// as used by 'env', 'doskey' or 'addbin'.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+func (s *Setting) String() string {
+	return s.Name + "=" + s.Value
+}
+
+// config is the content of all config files of a configs folder:
+// programs, plus global 'key=value' settings (like 'order=')
+type config struct {
+	prgs    []*prg
+	globals map[string]string
+}
+
+var globalRx = regexp.MustCompile(`^([a-zA-Z0-9_]+)=(.*)$`)
+
+// readConfigs reads all config files of a folder, 'globals' first,
+// then the other files, in alphabetical order.
+// A missing folder means an empty config.
+func readConfigs(dir *paths.Path) (*config, error) {
+	if !dir.Exists() {
+		return &config{globals: make(map[string]string)}, nil
+	}
+	sglobal := ""
+	sconfig := ""
+	for _, file := range dir.GetNameOrderedFiles("") {
+		if file.IsDir() {
+			continue
+		}
+		content := dir.Add(file.Name()).FileContent()
+		if strings.HasSuffix(file.Name(), "globals") {
+			sglobal = sglobal + content + "\n"
+		} else {
+			sconfig = sconfig + content + "\n"
+		}
+	}
+	return readConfig(sglobal + sconfig)
+}
+
+// readConfig parses a config content made of '[name]' program sections,
+// each line being a 'key value' for the current program.
+// Lines outside a program section can be global 'key=value'.
+// '[cache...]' and '[paths]' sections are global ones.
+// 'env' values are validated as templates (see Template): an invalid one
+// is an error of its program only (see Prg.Err).
+func readConfig(sconfig string) (*config, error) {
+	res := &config{globals: make(map[string]string)}
+	var current *prg
+	scanner := bufio.NewScanner(strings.NewReader(sconfig))
+	nline := 0
+	for scanner.Scan() {
+		nline = nline + 1
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section '%s'", nline, line)
+			}
+			current = nil
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if strings.HasPrefix(name, "cache") || name == "paths" {
+				continue
+			}
+			if name == "" {
+				return nil, fmt.Errorf("line %d: empty program name", nline)
+			}
+			current = &prg{name: name}
+			res.prgs = append(res.prgs, current)
+			continue
+		}
+		if m := globalRx.FindStringSubmatch(line); m != nil {
+			res.globals[m[1]] = strings.TrimSpace(m[2])
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		key := line
+		value := ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			key = line[:i]
+			value = strings.TrimSpace(line[i:])
+		}
+		if err := current.set(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: prg '%s': %s", nline, current.name, err.Error())
+		}
+	}
+	res.validateTemplates()
+	return res, nil
+}
+
+// set records a config key for a program.
+// Unknown keys (like the 'page.' urls of the extractors) are kept as is.
+// 'invoke' and 'buildZip' are registered Go installers, or commands
+// run without shell (see cmds.Parse), like 'uninstcmd'.
+// 'strip' (flatten a single archive top folder, the default) is 'true' or 'false'.
+// 'version.rx' captures, with its first group, the version of an archive name.
+// 'version' pins the version to install (see version.Parse).
+// 'cache' is the number of archives kept in the program cache.
+// 'url.', 'name.' and 'folder.' keys are extractors (see extractorRx),
+// kept in order.
+func (p *prg) set(key, value string) error {
+	if m := extractorRx.FindStringSubmatch(key); m != nil {
+		if err := checkExtractor(m[2], value); err != nil {
+			return fmt.Errorf("invalid %s '%s': %v", key, value, err)
+		}
+		p.extractors = append(p.extractors, &Setting{Name: key, Value: value})
+	}
+	switch key {
+	case "env", "doskey", "addbin":
+		elts := strings.SplitN(value, "=", 2)
+		if len(elts) != 2 || strings.TrimSpace(elts[0]) == "" {
+			return fmt.Errorf("invalid %s '%s'", key, value)
+		}
+		s := &Setting{Name: strings.TrimSpace(elts[0]), Value: strings.TrimSpace(elts[1])}
+		switch key {
+		case "env":
+			p.envs = append(p.envs, s)
+		case "doskey":
+			p.doskeys = append(p.doskeys, s)
+		case "addbin":
+			p.addbins = append(p.addbins, s)
+		}
+		return nil
+	case "invoke", "buildZip", "uninstcmd":
+		if name := GoHook(value); name != "" {
+			if key == "uninstcmd" || !goHooks[name] {
+				return fmt.Errorf("unknown Go installer '%s' for '%s'", name, key)
+			}
+		} else if _, err := cmds.Parse(value); err != nil {
+			return fmt.Errorf("invalid %s '%s': %v", key, value, err)
+		}
+	case "strip":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("invalid strip '%s' (true or false)", value)
+		}
+	case "version.rx":
+		rx, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("invalid version.rx '%s': %v", value, err)
+		}
+		if rx.NumSubexp() == 0 {
+			return fmt.Errorf("invalid version.rx '%s': no group to capture the version", value)
+		}
+	case "version":
+		if _, err := version.Parse(value); err != nil {
+			return err
+		}
+	case "cache":
+		if n, err := strconv.Atoi(value); err != nil || n < 1 {
+			return fmt.Errorf("invalid cache '%s' (number of archives kept)", value)
+		}
+	case "dir":
+		p.dir = value
+	case "test":
+		p.test = value
+	case "path":
+		p.path = value
+	}
+	if p.keys == nil {
+		p.keys = make(map[string][]string)
+	}
+	p.keys[key] = append(p.keys[key], value)
+	return nil
+}
+
+// extractorRx is an extractor key: 'url', 'name' or 'folder',
+// and what it does: 'get' a page (or another extracted value, like '_url'),
+// match a 'rx' first group, 'prepend', 'append' or 'replace' ('<rx> with <s>').
+var extractorRx = regexp.MustCompile(`^(url|name|folder)\.(get|rx|prepend|append|replace)$`)
+
+func checkExtractor(kind, value string) error {
+	switch kind {
+	case "get":
+		if value == "" {
+			return fmt.Errorf("no page")
+		}
+	case "rx", "replace":
+		rx := value
+		if kind == "replace" {
+			rx = strings.SplitN(value, " with", 2)[0]
+		} else if strings.HasPrefix(rx, "$") {
+			rx = rx[1:]
+		}
+		if _, err := regexp.Compile(rx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package prgs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testConfig = `
+[cache]
+  cache 3
+[paths]
+  #addpaths git
+order=go git
+shells=cmd bash
+[go]
+  test			   bin/go.exe
+  doskey       go=
+  addbin       go.bat=bin\go.exe %*
+  env          GOROOT=_folderfull_
+  env          GOPATH=%PROG%\go
+[git]
+  test			  bin/git.exe
+  path        bin
+  doskey      gl=git lg -20
+[jdk8src]
+	dir 			jdk8
+	url.get         src
+	url.rx          href="(/technetwork/jdk8-downloads-\d+.html)"
+	folder.get      src
+	url.prepend     http://www.oracle.com
+`
+
+func TestConfig(t *testing.T) {
+
+	getenv = testTplEnv
+	defer func() { getenv = os.Getenv }()
+
+	Convey("A config can be parsed", t, func() {
+		SetBuffers(nil)
+		cfg, err := readConfig(testConfig)
+		So(err, ShouldBeNil)
+		So(len(cfg.prgs), ShouldEqual, 3)
+		So(cfg.globals["order"], ShouldEqual, "go git")
+		So(cfg.globals["shells"], ShouldEqual, "cmd bash")
+
+		Convey("Program keys are parsed", func() {
+			g := cfg.prgs[0]
+			So(g.Name(), ShouldEqual, "go")
+			So(g.Dir(), ShouldEqual, "go")
+			So(g.Test(), ShouldEqual, "bin/go.exe")
+			So(g.Path(), ShouldBeEmpty)
+			So(len(g.Envs()), ShouldEqual, 2)
+			So(g.Envs()[1].String(), ShouldEqual, `GOPATH=%PROG%\go`)
+			So(g.Doskeys()[0].Name, ShouldEqual, "go")
+			So(g.Doskeys()[0].Value, ShouldBeEmpty)
+			So(g.Addbins()[0].Value, ShouldEqual, `bin\go.exe %*`)
+			So(cfg.prgs[1].Path(), ShouldEqual, "bin")
+			src := cfg.prgs[2]
+			So(src.Dir(), ShouldEqual, "jdk8")
+			So(src.keys["url.rx"], ShouldResemble, []string{`href="(/technetwork/jdk8-downloads-\d+.html)"`})
+			So(src.Value("url.rx"), ShouldEqual, `href="(/technetwork/jdk8-downloads-\d+.html)"`)
+			So(src.Value("uninstcmd"), ShouldBeEmpty)
+			So(len(src.Extractors()), ShouldEqual, 4)
+			So(src.Extractors()[2].String(), ShouldEqual, "folder.get=src")
+			So(src.Extractors()[3].String(), ShouldEqual, "url.prepend=http://www.oracle.com")
+			So(g.Extractors(), ShouldBeEmpty)
+			So(NoOutput(), ShouldBeTrue)
+		})
+
+		Convey("Invalid configs are reported with their line", func() {
+			_, err := readConfig("[go\n")
+			So(err.Error(), ShouldEqual, "line 1: invalid section '[go'")
+			_, err = readConfig("\n[ ]\n")
+			So(err.Error(), ShouldEqual, "line 2: empty program name")
+			_, err = readConfig("[go]\n  env GOROOT\n")
+			So(err.Error(), ShouldEqual, "line 2: prg 'go': invalid env 'GOROOT'")
+			_, err = readConfig("[go]\n  strip yes\n")
+			So(err.Error(), ShouldEqual, "line 2: prg 'go': invalid strip 'yes' (true or false)")
+			_, err = readConfig("[go]\n  version.rx go(\n")
+			So(err.Error(), ShouldStartWith, "line 2: prg 'go': invalid version.rx 'go(': ")
+			_, err = readConfig("[go]\n  version.rx go1.*\n")
+			So(err.Error(), ShouldEqual, "line 2: prg 'go': invalid version.rx 'go1.*': no group to capture the version")
+			_, err = readConfig("[go]\n  version go1.x\n")
+			So(err.Error(), ShouldEqual, "line 2: prg 'go': invalid version 'go1.x'")
+			_, err = readConfig("[go]\n  url.rx go(\n")
+			So(err.Error(), ShouldStartWith, "line 2: prg 'go': invalid url.rx 'go(': ")
+			_, err = readConfig("[go]\n  name.replace [ with _\n")
+			So(err.Error(), ShouldStartWith, "line 2: prg 'go': invalid name.replace '[ with _': ")
+			_, err = readConfig("[go]\n  cache 0\n")
+			So(err.Error(), ShouldEqual, "line 2: prg 'go': invalid cache '0' (number of archives kept)")
+			So(NoOutput(), ShouldBeTrue)
+		})
+	})
+
+	Convey("Configs are read from a folder, globals first", t, func() {
+		SetBuffers(nil)
+		cfg, err := readConfigs(paths.NewPathDir("xxx_no_configs"))
+		So(err, ShouldBeNil)
+		So(len(cfg.prgs), ShouldEqual, 0)
+
+		dir, _ := ioutil.TempDir("", "configs")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(dir+"/go", []byte("[go]\n  test bin/go.exe\n"), 0644)
+		ioutil.WriteFile(dir+"/globals", []byte("order=go\n"), 0644)
+		cfg, err = readConfigs(paths.NewPathDir(dir))
+		So(err, ShouldBeNil)
+		So(len(cfg.prgs), ShouldEqual, 1)
+		So(cfg.globals["order"], ShouldEqual, "go")
+
+		Convey("Only programs in the global order are returned", func() {
+			_cfg = cfg
+			_cfg.prgs = append(_cfg.prgs, &prg{name: "git"})
+			So(len(dg.Get()), ShouldEqual, 1)
+			So(Global("order"), ShouldEqual, "go")
+			_cfg = nil
+		})
+	})
+}
@@ -0,0 +1,21 @@
+package prgs
+
+import "strings"
+
+// goHooks are the names of the Go-coded installers, registered by the installer package
+var goHooks = map[string]bool{}
+
+// RegisterGoHook declares a Go-coded installer, which configs can then use
+// with 'invoke go: <name>' or 'buildZip go: <name>'.
+func RegisterGoHook(name string) {
+	goHooks[name] = true
+}
+
+// GoHook returns the name of a 'go: <name>' value,
+// or an empty string if the value is not a Go hook.
+func GoHook(value string) string {
+	if !strings.HasPrefix(value, "go:") {
+		return ""
+	}
+	return strings.TrimSpace(value[len("go:"):])
+}
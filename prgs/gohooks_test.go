@@ -0,0 +1,39 @@
+package prgs
+
+import (
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGoHooks(t *testing.T) {
+
+	Convey("Configs can only reference registered Go installers", t, func() {
+		SetBuffers(nil)
+		So(GoHook("go: InstallJDK"), ShouldEqual, "InstallJDK")
+		So(GoHook("@FILE@ /S /D=@DEST@"), ShouldBeEmpty)
+
+		_, err := readConfig("[jdk8]\n  invoke  go: InstallJDK\n  buildZip go: BuildZipJDK\n")
+		So(err.Error(), ShouldEqual, "line 2: prg 'jdk8': unknown Go installer 'InstallJDK' for 'invoke'")
+		RegisterGoHook("InstallJDK")
+		_, err = readConfig("[jdk8]\n  invoke  go: InstallJDK\n  buildZip go: BuildZipJDK\n")
+		So(err.Error(), ShouldEqual, "line 3: prg 'jdk8': unknown Go installer 'BuildZipJDK' for 'buildZip'")
+		RegisterGoHook("BuildZipJDK")
+		cfg, err := readConfig("[jdk8]\n  invoke  go: InstallJDK\n  buildZip go: BuildZipJDK\n")
+		So(err, ShouldBeNil)
+		So(cfg.prgs[0].Value("invoke"), ShouldEqual, "go: InstallJDK")
+		_, err = readConfig("[node]\n  invoke  msiexec /i @FILE@\n")
+		So(err, ShouldBeNil)
+		_, err = readConfig("[wintab]\n  invoke  mkdir @DESTNS@ & copy @FILENS@ @DESTNS@\n")
+		So(err.Error(), ShouldEqual, "line 2: prg 'wintab': invalid invoke 'mkdir @DESTNS@ & copy @FILENS@ @DESTNS@': "+
+			"shell operator '&' in 'mkdir @DESTNS@ & copy @FILENS@ @DESTNS@': commands are run without shell")
+		_, err = readConfig("[node]\n  uninstcmd  del @FILE@\n")
+		So(err.Error(), ShouldEqual, "line 2: prg 'node': invalid uninstcmd 'del @FILE@': cmd builtin 'del' in 'del @FILE@': commands are run without shell")
+		_, err = readConfig("[node]\n  uninstcmd  go: InstallJDK\n")
+		So(err.Error(), ShouldEqual, "line 2: prg 'node': unknown Go installer 'InstallJDK' for 'uninstcmd'")
+		delete(goHooks, "InstallJDK")
+		delete(goHooks, "BuildZipJDK")
+		So(NoOutput(), ShouldBeTrue)
+	})
+}
@@ -2,28 +2,48 @@ package prgs
 
 import (
 	"io"
+
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
 )
 
 // PathWriter computes final PATH of a collection of programs
 type PathWriter interface {
-	// WritePath writes in a writer `set PATH=`... with all prgs PATH.
+	// WritePath writes in a writer the PATH with all prgs PATH added.
 	// Note: not all programs have a path
 	WritePath(prgs []Prg, w io.Writer) error
 }
 
-type pathWriter struct{}
+// pathWriter adds programs path to an initial PATH,
+// which is left untouched since a PathList is immutable.
+type pathWriter struct {
+	path *envs.PathList
+	// root is where programs are installed, %PRGS2% if nil (see envs.Prgsenv)
+	root *paths.Path
+}
 
+// WritePath adds the path of each program within its 'latest' folder
+// (like '%PRGS2%/go/latest/bin'), which stays valid after an update.
 func (pw *pathWriter) WritePath(prgs []Prg, w io.Writer) error {
-	for _, prg := range prgs {
-		if _, err := w.Write([]byte(prg.Name())); err != nil {
+	root := pw.root
+	if root == nil {
+		var err error
+		if root, err = envs.Prgsenv(); err != nil {
 			return err
 		}
 	}
-	return nil
+	path := pw.path
+	for _, prg := range prgs {
+		if prg.Path() != "" {
+			path = path.Add(root.Add(prg.Dir()).Add("latest").SetDir().Add(prg.Path()).NoSep().String())
+		}
+	}
+	_, err := w.Write([]byte(path.String()))
+	return err
 }
 
 var pw *pathWriter
 
 func init() {
-	pw = &pathWriter{}
+	pw = &pathWriter{path: envs.NewPathList()}
 }
@@ -4,15 +4,31 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+
 	. "github.com/VonC/godbg"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
-type testPrg struct{ name string }
+type testPrg struct {
+	Prg
+	name string
+	path string
+}
 
 func (tp *testPrg) Name() string { return tp.name }
+func (tp *testPrg) Path() string { return tp.path }
+func (tp *testPrg) Dir() string  { return tp.name }
+
+// latest is the path of a program in its 'latest' folder, under 'root'
+func latest(name, path string) string {
+	return filepath.Join("root", name, "latest", path)
+}
 
 type testPathWriter struct{ b *bytes.Buffer }
 
@@ -20,7 +36,7 @@ type testWriter struct{ w io.Writer }
 
 func (tw *testWriter) Write(p []byte) (n int, err error) {
 	s := string(p)
-	if s == "prg2" {
+	if strings.Contains(s, "prg2") {
 		return 0, fmt.Errorf("Error writing '%s'", s)
 	}
 	return tw.w.Write(p)
@@ -35,14 +51,29 @@ func (tpw *testPathWriter) WritePath(prgss []Prg, w io.Writer) error {
 
 func TestPathWriter(t *testing.T) {
 	tpw := &testPathWriter{b: bytes.NewBuffer(nil)}
-	prgs := []Prg{&testPrg{name: "prg1"}, &testPrg{name: "prg2"}}
+	prgs := []Prg{&testPrg{name: "prg1", path: "bin"}, &testPrg{name: "prg0"}, &testPrg{name: "prg2", path: "bin"}}
+	pw.root = paths.NewPathDir("root")
+	defer func() { pw.root = nil }()
 	Convey("Tests for Path Writer", t, func() {
 
 		Convey("A Path writer writes any empty path if no prgs", func() {
 			SetBuffers(nil)
+			err := tpw.WritePath(nil, tpw.b)
+			So(err, ShouldBeNil)
+			So(tpw.b.String(), ShouldBeEmpty)
+			So(NoOutput(), ShouldBeTrue)
+		})
+
+		Convey("A Path writer adds prgs path to its initial PATH", func() {
+			SetBuffers(nil)
+			tpw.b = bytes.NewBuffer(nil)
+			initial := pw.path
+			pw.path = envs.NewPathList(latest("prg1", "bin"), "other")
 			err := tpw.WritePath(prgs, tpw.b)
 			So(err, ShouldBeNil)
-			So(tpw.b.String(), ShouldEqual, "prg1prg2")
+			So(tpw.b.String(), ShouldEqual, strings.Join([]string{latest("prg1", "bin"), "other", latest("prg2", "bin")}, envs.PathListSeparator))
+			So(pw.path.Len(), ShouldEqual, 2)
+			pw.path = initial
 			So(NoOutput(), ShouldBeTrue)
 		})
 
@@ -51,8 +82,8 @@ func TestPathWriter(t *testing.T) {
 			tpw.b = bytes.NewBuffer(nil)
 			tw := &testWriter{w: tpw.b}
 			err := tpw.WritePath(prgs, tw)
-			So(err.Error(), ShouldEqual, "Error writing 'prg2'")
-			So(tpw.b.String(), ShouldEqual, "prg1")
+			So(err.Error(), ShouldEqual, "Error writing '"+strings.Join([]string{latest("prg1", "bin"), latest("prg2", "bin")}, envs.PathListSeparator)+"'")
+			So(tpw.b.String(), ShouldBeEmpty)
 			So(NoOutput(), ShouldBeTrue)
 		})
 	})
@@ -1,17 +1,57 @@
 package prgs
 
-import "github.com/VonC/senvgo/envs"
-import "github.com/VonC/senvgo/paths"
+import (
+	"strings"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/envs"
+)
 
 // Prg is a Program, with all its data (no behavior)
 type prg struct {
-	name string
+	name    string
+	dir     string
+	test    string
+	path    string
+	envs    []*Setting
+	doskeys []*Setting
+	addbins []*Setting
+	// extractors are the 'url.', 'name.' and 'folder.' keys, in config order
+	extractors []*Setting
+	// keys are all the config keys, including the ones without accessor
+	keys map[string][]string
+	// err is the config error of the program, if any
+	err error
 }
 
 // Prg defines what kind of service a program has to provide
 type Prg interface {
 	// Name is the name of a program to install, acts as an id
 	Name() string
+	// Dir is the folder name of the program under %PRGS2%
+	// (its name, unless a 'dir' is set, shared with another program)
+	Dir() string
+	// Test is the file, relative to the program folder, which proves
+	// the program is installed
+	Test() string
+	// Path is the subfolder, relative to the program folder,
+	// to add to PATH. Empty means no PATH contribution
+	Path() string
+	// Envs are the environment variables to set ('env' keys)
+	Envs() []*Setting
+	// Doskeys are the aliases to define ('doskey' keys)
+	Doskeys() []*Setting
+	// Addbins are the shims to add in %PRGS2%/bin ('addbin' keys)
+	Addbins() []*Setting
+	// Extractors are the 'url.', 'name.' and 'folder.' keys, in config order:
+	// the chain resolving the latest upstream release (see upstream.Resolve)
+	Extractors() []*Setting
+	// Value is the first value of any config key (like 'uninstcmd'),
+	// empty if the key is not set
+	Value(key string) string
+	// Err is the config error of the program (like an 'env' referencing
+	// an undefined environment variable), nil if it can be installed
+	Err() error
 }
 
 // PGetter gets programs (from an internal config)
@@ -24,19 +64,62 @@ type defaultGetter struct{}
 var dg defaultGetter
 var getter PGetter
 var _prgs []Prg
+var _cfg *config
 
 func init() {
 	dg = defaultGetter{}
 	getter = dg
 }
+
+// Get reads all configs from %PRGS2%/configs.
+// Only returns programs listed in the global 'order=' if there is one.
 func (df defaultGetter) Get() []Prg {
 	if _prgs != nil && len(_prgs) > 0 {
 		return _prgs
 	}
-	var p *paths.Path
-	p = envs.Prgsenv()
-	p.Add("")
-	return []Prg{}
+	cfg := getConfig()
+	res := []Prg{}
+	order := strings.Fields(cfg.globals["order"])
+	for _, p := range cfg.prgs {
+		if len(order) == 0 || contains(order, p.name) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func getConfig() *config {
+	if _cfg != nil {
+		return _cfg
+	}
+	root, err := envs.Prgsenv()
+	if err != nil {
+		godbg.Pdbgf("No configs: '%v'", err)
+		return &config{globals: make(map[string]string)}
+	}
+	dir := root.Add("configs")
+	cfg, err := readConfigs(dir)
+	if err != nil {
+		godbg.Pdbgf("Error while reading configs in '%v': '%v'", dir, err)
+		cfg = &config{globals: make(map[string]string)}
+	}
+	_cfg = cfg
+	return _cfg
+}
+
+// Global returns the value of a global 'key=value' config setting,
+// or an empty string if not set.
+func Global(key string) string {
+	return getConfig().globals[key]
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
 }
 
 // Getter returns a object able to get a list of Prgs
@@ -47,3 +130,45 @@ func Getter() PGetter {
 func (p *prg) Name() string {
 	return p.name
 }
+
+func (p *prg) Dir() string {
+	if p.dir != "" {
+		return p.dir
+	}
+	return p.name
+}
+
+func (p *prg) Test() string {
+	return p.test
+}
+
+func (p *prg) Path() string {
+	return p.path
+}
+
+func (p *prg) Envs() []*Setting {
+	return p.envs
+}
+
+func (p *prg) Doskeys() []*Setting {
+	return p.doskeys
+}
+
+func (p *prg) Addbins() []*Setting {
+	return p.addbins
+}
+
+func (p *prg) Extractors() []*Setting {
+	return p.extractors
+}
+
+func (p *prg) Value(key string) string {
+	if values := p.keys[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (p *prg) Err() error {
+	return p.err
+}
@@ -1,7 +1,6 @@
 package prgs
 
 import (
-	"os"
 	"os/exec"
 	"strings"
 	"testing"
@@ -19,23 +18,15 @@ func (tg testGetter) Get() []Prg {
 }
 func TestMain(t *testing.T) {
 
-	envs.Prgsenvname = "PRGSTEST"
-
 	Convey("Prerequisite: Prgsenv is set", t, func() {
 		SetBuffers(nil)
-		defer func() {
-			if r := recover(); r != nil {
-				p := getRootPath().Add("test2/")
-				if err := os.Setenv(envs.Prgsenvname, p.String()); err != nil {
-					panic(err)
-				}
-				p = envs.Prgsenv()
-				So(p.String(), ShouldEndWith, `\test2\`)
-				So(len(p.String()), ShouldEqual, 9)
-			}
-		}()
-		p := envs.Prgsenv()
-		So(p.String(), ShouldEqual, `..\test2\`)
+		p := getRootPath().Add("test2/")
+		So(p.MkdirAll(), ShouldBeTrue)
+		envs.SetRootFlag(p.String())
+		p, err := envs.Prgsenv()
+		So(err, ShouldBeNil)
+		So(p.String(), ShouldEndWith, `\test2\`)
+		So(len(p.String()), ShouldEqual, 9)
 	})
 
 	Convey("prgs can get prgs", t, func() {
@@ -0,0 +1,185 @@
+package prgs
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/VonC/senvgo/envs"
+)
+
+// Ref is a reference within a Template:
+// either a program variable, or an environment variable.
+type Ref struct {
+	// Prg is the name of the referenced program, empty for the current one
+	Prg string
+	// Var is a program variable: 'folderfull', 'folder' or 'version'
+	Var string
+	// Env is an environment variable name, like 'PRGS2'
+	Env string
+}
+
+func (r *Ref) String() string {
+	if r.Env != "" {
+		return "%" + r.Env + "%"
+	}
+	if r.Prg != "" {
+		return "_" + r.Prg + ":" + r.Var + "_"
+	}
+	return "_" + r.Var + "_"
+}
+
+// Template is a config value with references to resolve:
+//   - '_folderfull_': full path of the program folder
+//   - '_folder_': name of the program folder
+//   - '_version_': version of the program
+//   - '_jdk8:folderfull_': same variables, for another program (here 'jdk8')
+//   - '%PRGS2%', '%PROG%': environment variables ('%%' for a literal '%')
+type Template struct {
+	raw   string
+	parts []*tplPart
+}
+
+// tplPart is either a literal, or a reference
+type tplPart struct {
+	lit string
+	ref *Ref
+}
+
+var tplVars = map[string]bool{"folderfull": true, "folder": true, "version": true}
+
+var tplRx = regexp.MustCompile(`%%|%([^%\s]*)%|_(?:([a-zA-Z0-9.+-]+):)?([a-z]+)_`)
+
+// getenv is the environment a template is validated against
+var getenv func(key string) string
+
+func init() {
+	getenv = os.Getenv
+}
+
+// ParseTemplate parses a value, returning an error for an unterminated
+// '%' or an unknown program variable.
+// A '_word_' which is not a program variable is kept as is.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{raw: s}
+	last := 0
+	for _, m := range tplRx.FindAllStringSubmatchIndex(s, -1) {
+		t.addLit(s[last:m[0]])
+		last = m[1]
+		match := s[m[0]:m[1]]
+		switch {
+		case match == "%%":
+			t.addLit("%")
+		case strings.HasPrefix(match, "%"):
+			if m[3]-m[2] == 0 {
+				return nil, fmt.Errorf("empty environment variable in '%s'", s)
+			}
+			t.parts = append(t.parts, &tplPart{ref: &Ref{Env: s[m[2]:m[3]]}})
+		default:
+			prg := ""
+			if m[4] >= 0 {
+				prg = s[m[4]:m[5]]
+			}
+			v := s[m[6]:m[7]]
+			if !tplVars[v] {
+				if prg != "" {
+					return nil, fmt.Errorf("unknown variable '%s' for program '%s' in '%s'", v, prg, s)
+				}
+				t.addLit(match)
+				continue
+			}
+			t.parts = append(t.parts, &tplPart{ref: &Ref{Prg: prg, Var: v}})
+		}
+	}
+	rest := s[last:]
+	if strings.Contains(rest, "%") {
+		return nil, fmt.Errorf("unterminated '%%' in '%s'", s)
+	}
+	t.addLit(rest)
+	return t, nil
+}
+
+func (t *Template) addLit(s string) {
+	if s == "" {
+		return
+	}
+	if n := len(t.parts); n > 0 && t.parts[n-1].ref == nil {
+		t.parts[n-1].lit = t.parts[n-1].lit + s
+		return
+	}
+	t.parts = append(t.parts, &tplPart{lit: s})
+}
+
+// Refs returns all references of a template
+func (t *Template) Refs() []*Ref {
+	res := []*Ref{}
+	for _, p := range t.parts {
+		if p.ref != nil {
+			res = append(res, p.ref)
+		}
+	}
+	return res
+}
+
+// Expand resolves all references of a template through a resolver.
+func (t *Template) Expand(resolve func(ref *Ref) (string, error)) (string, error) {
+	res := ""
+	for _, p := range t.parts {
+		if p.ref == nil {
+			res = res + p.lit
+			continue
+		}
+		v, err := resolve(p.ref)
+		if err != nil {
+			return "", fmt.Errorf("unable to resolve '%v' in '%s': %v", p.ref, t.raw, err)
+		}
+		res = res + v
+	}
+	return res, nil
+}
+
+func (t *Template) String() string {
+	return t.raw
+}
+
+// validateTemplates checks, for all 'env' values of a config,
+// that they are valid templates whose references exist:
+// other programs must be in the config, environment variables must be set,
+// either by senvgo (%PRGS2%), by an 'env' of a program, or in the environment.
+// An invalid value only invalidates its program (see Prg.Err):
+// the other programs can still be installed.
+func (c *config) validateTemplates() {
+	names := map[string]bool{}
+	vars := map[string]bool{envs.Prgsenvname: true}
+	for _, p := range c.prgs {
+		names[p.name] = true
+		for _, e := range p.envs {
+			vars[e.Name] = true
+		}
+	}
+	for _, p := range c.prgs {
+		for _, e := range p.envs {
+			if err := validateTemplate(e.Value, names, vars); err != nil {
+				p.err = fmt.Errorf("env '%s': %v", e.Name, err)
+				break
+			}
+		}
+	}
+}
+
+func validateTemplate(value string, names, vars map[string]bool) error {
+	t, err := ParseTemplate(value)
+	if err != nil {
+		return err
+	}
+	for _, ref := range t.Refs() {
+		switch {
+		case ref.Env != "" && !vars[ref.Env] && getenv(ref.Env) == "":
+			return fmt.Errorf("undefined environment variable '%s'", ref.Env)
+		case ref.Prg != "" && !names[ref.Prg]:
+			return fmt.Errorf("unknown program '%s'", ref.Prg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package prgs
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testTplEnv(key string) string {
+	if key == "PROG" {
+		return `C:\prog`
+	}
+	return ""
+}
+
+func TestTemplate(t *testing.T) {
+
+	Convey("A template is parsed in literals and references", t, func() {
+		SetBuffers(nil)
+		tpl, err := ParseTemplate(`%PROG%\_folder_-_jdk8:version_ 100%% my_lib_dir`)
+		So(err, ShouldBeNil)
+		So(tpl.String(), ShouldEqual, `%PROG%\_folder_-_jdk8:version_ 100%% my_lib_dir`)
+		refs := tpl.Refs()
+		So(len(refs), ShouldEqual, 3)
+		So(refs[0].String(), ShouldEqual, "%PROG%")
+		So(refs[1].String(), ShouldEqual, "_folder_")
+		So(refs[2].String(), ShouldEqual, "_jdk8:version_")
+
+		Convey("A template expands its references", func() {
+			res, err := tpl.Expand(func(ref *Ref) (string, error) {
+				return "<" + ref.Prg + ref.Var + ref.Env + ">", nil
+			})
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, `<PROG>\<folder>-<jdk8version> 100% my_lib_dir`)
+			_, err = tpl.Expand(func(ref *Ref) (string, error) {
+				return "", fmt.Errorf("not installed")
+			})
+			So(err.Error(), ShouldEqual, `unable to resolve '%PROG%' in '%PROG%\_folder_-_jdk8:version_ 100%% my_lib_dir': not installed`)
+			So(NoOutput(), ShouldBeTrue)
+		})
+
+		Convey("Invalid templates are rejected", func() {
+			_, err := ParseTemplate("50% off")
+			So(err.Error(), ShouldEqual, "unterminated '%' in '50% off'")
+			_, err = ParseTemplate("a%%b")
+			So(err, ShouldBeNil)
+			_, err = ParseTemplate("_jdk8:home_")
+			So(err.Error(), ShouldEqual, "unknown variable 'home' for program 'jdk8' in '_jdk8:home_'")
+		})
+	})
+
+	Convey("Config env values are validated at load time, per program", t, func() {
+		SetBuffers(nil)
+		getenv = testTplEnv
+		cfg, err := readConfig(testConfig)
+		So(err, ShouldBeNil)
+		for _, p := range cfg.prgs {
+			So(p.Err(), ShouldBeNil)
+		}
+		cfg, err = readConfig("[a]\n  env A=%UNSET%\\x\n[b]\n  env B=%PRGS2%\\b;%A%;%PROG%\n")
+		So(err, ShouldBeNil)
+		So(cfg.prgs[0].Err().Error(), ShouldEqual, "env 'A': undefined environment variable 'UNSET'")
+		So(cfg.prgs[1].Err(), ShouldBeNil)
+		cfg, _ = readConfig("[a]\n  env A=_jdk8:folderfull_\n")
+		So(cfg.prgs[0].Err().Error(), ShouldEqual, "env 'A': unknown program 'jdk8'")
+		cfg, _ = readConfig("[a]\n  env A=_jdk8:folderfull_\n[jdk8]\n")
+		So(cfg.prgs[0].Err(), ShouldBeNil)
+		cfg, _ = readConfig("[a]\n  env A=100%\n")
+		So(cfg.prgs[0].Err().Error(), ShouldEqual, "env 'A': unterminated '%' in '100%'")
+		getenv = os.Getenv
+	})
+
+	Convey("The shipped configs load, with only their programs using an unset variable in error", t, func() {
+		SetBuffers(nil)
+		getenv = func(key string) string { return "" }
+		// registered by the installer package
+		hooks := []string{"InstallJDK", "InstallJDKsrc", "BuildZipJDK", "InstallExe", "InstallKitty"}
+		for _, name := range hooks {
+			RegisterGoHook(name)
+		}
+		cfg, err := readConfigs(paths.NewPathDir("../configs"))
+		So(err, ShouldBeNil)
+		So(len(cfg.prgs), ShouldBeGreaterThan, 30)
+		for _, p := range cfg.prgs {
+			if p.name == "go" {
+				So(p.Err().Error(), ShouldEqual, "env 'GOPATH': undefined environment variable 'PROG'")
+			} else {
+				So(p.Err(), ShouldBeNil)
+			}
+		}
+		for _, name := range hooks {
+			delete(goHooks, name)
+		}
+		getenv = os.Getenv
+	})
+}
@@ -0,0 +1,29 @@
+package prgs
+
+import (
+	"regexp"
+
+	"github.com/VonC/senvgo/version"
+)
+
+// VersionOf returns the version of a program archive or folder name:
+// the first group of its 'version.rx', or else the first version found
+// in the name (see version.Find), nil if there is none.
+func VersionOf(p Prg, name string) *version.Version {
+	value := p.Value("version.rx")
+	if value == "" {
+		return version.Find(name)
+	}
+	rx, err := regexp.Compile(value)
+	if err != nil {
+		return nil
+	}
+	m := rx.FindStringSubmatch(name)
+	if len(m) < 2 {
+		return nil
+	}
+	if v, err := version.Parse(m[1]); err == nil {
+		return v
+	}
+	return version.Find(m[1])
+}
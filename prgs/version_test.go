@@ -0,0 +1,27 @@
+package prgs
+
+import (
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVersionOf(t *testing.T) {
+
+	Convey("The version of a name is found in it, or captured by 'version.rx'", t, func() {
+		SetBuffers(nil)
+		p := &prg{name: "go"}
+		So(VersionOf(p, "go1.10rc1.windows-amd64.zip").String(), ShouldEqual, "1.10rc1")
+		So(VersionOf(p, "jdk1.8.0_40").String(), ShouldEqual, "1.8.0_40")
+		So(VersionOf(p, "go-tip"), ShouldBeNil)
+
+		p.keys = map[string][]string{"version.rx": {`amd(\d+)`}}
+		So(VersionOf(p, "go1.10.windows-amd64.zip").String(), ShouldEqual, "64")
+		So(VersionOf(p, "go1.10.windows-386.zip"), ShouldBeNil)
+		p.keys["version.rx"] = []string{`go(\S+)\.zip`}
+		So(VersionOf(p, "go1.10.windows-amd64.zip").String(), ShouldEqual, "1.10")
+		p.keys["version.rx"] = []string{`(`}
+		So(VersionOf(p, "go1.10.zip"), ShouldBeNil)
+	})
+}
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	"github.com/VonC/godbg"
 	"github.com/VonC/godbg/exit"
+	"github.com/VonC/senvgo/bundle"
+	"github.com/VonC/senvgo/envs"
 	"github.com/VonC/senvgo/installer"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/lock"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/paths"
 	"github.com/VonC/senvgo/prgs"
+	"github.com/VonC/senvgo/shells"
+	"github.com/VonC/senvgo/upstream"
 )
 
 var exiter *exit.Exit
@@ -17,34 +26,431 @@ type newInstallerFunc func(prgs.Prg) installer.Inst
 
 var newInstaller newInstallerFunc
 
+type newLockedInstallerFunc func(prgs.Prg, *lockfile.Entry) installer.Inst
+
+var newLockedInstaller newLockedInstallerFunc
+
+type findRootFunc func() (*envs.Root, error)
+
+var findRoot findRootFunc
+
+// command is a senvgo command ('senvgo <command> <args>'), returning its exit status
+type command func(args []string) int
+
+var commands map[string]command
+
+type writeEnvFunc func(ps []prgs.Prg) error
+
+var writeEnv writeEnvFunc
+
+type acquireLockFunc func(root *paths.Path, mode lock.Mode, wait bool) (*lock.Lock, error)
+
+var acquireLock acquireLockFunc
+
+// readOnly are the commands which only need a shared lock on the programs root
+var readOnly = map[string]bool{}
+
+var rootFlag = flag.String("root", "", "folder where programs are installed\nDefault to %PRGS2%, the 'root=' of a senvgo.conf next to senvgo, or ~/prgs")
+var waitFlag = flag.Bool("wait", false, "wait for another senvgo to release its lock on the programs root\nDefault to fail at once")
+var offlineFlag = flag.Bool("offline", false, "download nothing: upstream pages and archives only come from the cache\n(see 'senvgo bundle import' to seed it)")
+var shellsFlag = flag.String("shells", "", "env scripts to generate, comma separated (cmd, powershell, bash, fish)\nDefault to the configs/globals 'shells=', or 'cmd'")
+
 func init() {
 	exiter = exit.Default()
 	prgsGetter = prgs.Getter()
 	newInstaller = installer.New
+	newLockedInstaller = installer.NewLocked
+	writeEnv = writeEnvScripts
+	findRoot = envs.FindRoot
+	acquireLock = lock.Acquire
+	commands = map[string]command{
+		"remove":   remove,
+		"history":  history,
+		"outdated": outdated,
+		"install":  install,
+		"lock":     lockPrgs,
+		"bundle":   bundlePrgs,
+	}
+	readOnly["history"] = true
+	readOnly["outdated"] = true
 }
 
 func main() {
 	godbg.Pdbgf("senvgo")
+	flag.Parse()
+	envs.SetRootFlag(*rootFlag)
+	upstream.SetOffline(*offlineFlag)
 	// http://stackoverflow.com/questions/18963984/exit-with-error-code-in-go
-	status = run()
+	status = run(flag.Args())
 	exiter.Exit(status)
 }
 
-func run() int {
-	prgs := prgsGetter.Get()
-	nbprgs := len(prgs)
+// run installs all programs, or executes a command.
+func run(args []string) int {
+	root, err := findRoot()
+	if err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to find where to install programs: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(godbg.Out(), "Programs root %v\n", root)
+	paths.SetAliases(paths.NewAliasTable(paths.SubstProvider(), paths.FileProvider(root.Path.Add(paths.AliasesFile).String())))
+	var cmd command
+	mode := lock.Exclusive
+	if len(args) > 0 {
+		var ok bool
+		if cmd, ok = commands[args[0]]; !ok {
+			fmt.Fprintf(godbg.Out(), "Unknown command '%s'\n", args[0])
+			return 1
+		}
+		if readOnly[args[0]] {
+			mode = lock.Shared
+		}
+	}
+	l, err := acquireLock(root.Path, mode, *waitFlag)
+	if err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to lock programs root: %v\n", err)
+		return 1
+	}
+	defer l.Release()
+	// version folders an earlier run did not purge: '<prg>/<version>.trash'
+	if mode == lock.Exclusive {
+		if err = root.Path.PurgeTrash(2); err != nil {
+			godbg.Pdbgf("Unable to purge deleted folders: %v", err)
+		}
+	}
+	if cmd != nil {
+		return cmd(args[1:])
+	}
+	ps := prgsGetter.Get()
+	nbprgs := len(ps)
 	if nbprgs == 0 {
 		fmt.Fprintf(godbg.Out(), "No program to install: nothing to do")
 		return 0
 	}
-	for i, prg := range prgs {
+	installed := []prgs.Prg{}
+	for i, prg := range ps {
 		inst := newInstaller(prg)
 		fmt.Fprintf(godbg.Out(), "'%s' (%d/%d)... ", prg.Name(), i+1, nbprgs)
-		if inst.IsInstalled() {
+		if err := prg.Err(); err != nil {
+			fmt.Fprintf(godbg.Out(), "invalid config: %v\n", err)
+		} else if inst.IsInstalled() {
 			fmt.Fprintf(godbg.Out(), "already installed: nothing to do\n")
+			installed = append(installed, prg)
 		} else if inst.HasFailed() {
 			fmt.Fprintf(godbg.Out(), "already failed to install\n")
 		}
 	}
+	if err = writeEnv(installed); err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to write env scripts: %v\n", err)
+		return 1
+	}
 	return 0
 }
+
+// remove uninstalls programs, and writes the env scripts
+// of the remaining installed programs.
+func remove(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(godbg.Out(), "Usage: senvgo remove <name>...\n")
+		return 1
+	}
+	ps := prgsGetter.Get()
+	removed := map[string]bool{}
+	for _, name := range args {
+		var p prgs.Prg
+		for _, prg := range ps {
+			if prg.Name() == name {
+				p = prg
+			}
+		}
+		if p == nil {
+			fmt.Fprintf(godbg.Out(), "Unknown program '%s'\n", name)
+			return 1
+		}
+		if err := newInstaller(p).Uninstall(); err != nil {
+			fmt.Fprintf(godbg.Out(), "Unable to remove '%s': %v\n", name, err)
+			return 1
+		}
+		removed[name] = true
+		fmt.Fprintf(godbg.Out(), "'%s' removed\n", name)
+	}
+	installed := []prgs.Prg{}
+	for _, prg := range ps {
+		if !removed[prg.Name()] && newInstaller(prg).IsInstalled() {
+			installed = append(installed, prg)
+		}
+	}
+	if err := writeEnv(installed); err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to write env scripts: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// history prints the journal of installs and uninstalls,
+// of all programs or of one program.
+func history(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintf(godbg.Out(), "Usage: senvgo history [name]\n")
+		return 1
+	}
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+	root, err := envs.Prgsenv()
+	if err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to read history: %v\n", err)
+		return 1
+	}
+	events, err := journal.Open(root).Events(name)
+	if err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to read history: %v\n", err)
+		return 1
+	}
+	if len(events) == 0 {
+		fmt.Fprintf(godbg.Out(), "No history\n")
+		return 0
+	}
+	for _, e := range events {
+		fmt.Fprintf(godbg.Out(), "%v\n", e)
+	}
+	return 0
+}
+
+// outdated prints, for all programs or the ones named, their installed
+// version folder, the latest one upstream (from the newest cached page when
+// a page cannot be downloaded, or with --offline), or in cache for a program
+// without extractors, and what installing would do.
+func outdated(args []string) int {
+	selected, ok := selectPrgs(args)
+	if !ok {
+		return 1
+	}
+	res := 0
+	fmt.Fprintf(godbg.Out(), "%-12s %-30s %-30s %s\n", "Program", "Current", "Latest", "Action")
+	for _, p := range selected {
+		s, err := newInstaller(p).Status()
+		if s == nil {
+			s = &installer.Status{Action: installer.ActionUnknown}
+		}
+		action := s.Action
+		if err != nil {
+			action = fmt.Sprintf("%s: %v", action, err)
+			res = 1
+		}
+		fmt.Fprintf(godbg.Out(), "%-12s %-30s %-30s %s\n", p.Name(), orNone(s.Current), orNone(s.Latest), action)
+	}
+	return res
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// selectPrgs returns the programs named, all programs if none is.
+// It reports an unknown program.
+func selectPrgs(names []string) ([]prgs.Prg, bool) {
+	ps := prgsGetter.Get()
+	if len(names) == 0 {
+		return ps, true
+	}
+	res := []prgs.Prg{}
+	for _, name := range names {
+		var p prgs.Prg
+		for _, prg := range ps {
+			if prg.Name() == name {
+				p = prg
+			}
+		}
+		if p == nil {
+			fmt.Fprintf(godbg.Out(), "Unknown program '%s'\n", name)
+			return nil, false
+		}
+		res = append(res, p)
+	}
+	return res, true
+}
+
+// install installs programs (all, or the ones named), and writes the env scripts
+// of the installed programs.
+// The archive of the latest release upstream is downloaded in cache if needed.
+// With --locked, it installs the releases of the lockfile (see lockPrgs),
+// and only the programs it has, when none is named.
+// With --offline, nothing is downloaded: a program whose archive is not
+// in cache fails with installer.ErrNotInCache.
+func install(args []string) int {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	fs.SetOutput(godbg.Out())
+	locked := fs.Bool("locked", false, "install the releases recorded in "+lockfile.FileName)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	selected, ok := selectPrgs(fs.Args())
+	if !ok {
+		return 1
+	}
+	var lf *lockfile.File
+	if *locked {
+		root, err := envs.Prgsenv()
+		if err == nil {
+			lf, err = lockfile.Load(root)
+		}
+		if err != nil {
+			fmt.Fprintf(godbg.Out(), "Unable to read lockfile: %v\n", err)
+			return 1
+		}
+		if fs.NArg() == 0 {
+			selected = []prgs.Prg{}
+			for _, p := range prgsGetter.Get() {
+				if lf.Get(p.Name()) != nil {
+					selected = append(selected, p)
+				}
+			}
+		}
+	}
+	res := 0
+	for _, p := range selected {
+		if err := p.Err(); err != nil {
+			fmt.Fprintf(godbg.Out(), "Unable to install '%s': invalid config: %v\n", p.Name(), err)
+			res = 1
+			continue
+		}
+		inst := newInstaller(p)
+		if lf != nil {
+			e := lf.Get(p.Name())
+			if e == nil {
+				fmt.Fprintf(godbg.Out(), "'%s' is not in lockfile '%v'\n", p.Name(), lf)
+				res = 1
+				continue
+			}
+			inst = newLockedInstaller(p, e)
+		}
+		if err := inst.Install(); err != nil {
+			fmt.Fprintf(godbg.Out(), "Unable to install '%s': %v\n", p.Name(), err)
+			res = 1
+			continue
+		}
+		fmt.Fprintf(godbg.Out(), "'%s' installed\n", p.Name())
+	}
+	installed := []prgs.Prg{}
+	for _, prg := range prgsGetter.Get() {
+		if newInstaller(prg).IsInstalled() {
+			installed = append(installed, prg)
+		}
+	}
+	if err := writeEnv(installed); err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to write env scripts: %v\n", err)
+		return 1
+	}
+	return res
+}
+
+// lockPrgs records in the lockfile, for all programs or the ones named,
+// the release an install would install: archive, version folder,
+// version and archive checksum.
+// The lockfile is only written if all programs could be resolved.
+func lockPrgs(args []string) int {
+	selected, ok := selectPrgs(args)
+	if !ok {
+		return 1
+	}
+	root, err := envs.Prgsenv()
+	var lf *lockfile.File
+	if err == nil {
+		lf, err = lockfile.Load(root)
+	}
+	if err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to read lockfile: %v\n", err)
+		return 1
+	}
+	for _, p := range selected {
+		e, err := newInstaller(p).Resolve()
+		if err != nil {
+			fmt.Fprintf(godbg.Out(), "Unable to lock '%s': %v\n", p.Name(), err)
+			return 1
+		}
+		lf.Set(e)
+		fmt.Fprintf(godbg.Out(), "'%s' locked to '%s'\n", p.Name(), e.Folder)
+	}
+	if err = lf.Save(); err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to write lockfile: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// bundlePrgs exports in a bundle file the configs, lockfile and locked
+// archives of programs (all locked programs if none is named),
+// or imports such a bundle in an offline programs root (see bundle.Import).
+func bundlePrgs(args []string) int {
+	if len(args) < 2 || (args[0] != "export" && args[0] != "import") || (args[0] == "import" && len(args) > 2) {
+		fmt.Fprintf(godbg.Out(), "Usage: senvgo bundle export <file> [name]...\n       senvgo bundle import <file>\n")
+		return 1
+	}
+	root, err := envs.Prgsenv()
+	if err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to %s bundle: %v\n", args[0], err)
+		return 1
+	}
+	file := paths.NewPath(args[1])
+	var entries []*lockfile.Entry
+	if args[0] == "export" {
+		entries, err = bundle.Export(root, file, args[2:])
+	} else {
+		entries, err = bundle.Import(root, file)
+	}
+	if err != nil {
+		fmt.Fprintf(godbg.Out(), "Unable to %s bundle: %v\n", args[0], err)
+		return 1
+	}
+	for _, e := range entries {
+		fmt.Fprintf(godbg.Out(), "'%s' %sed (%s)\n", e.Name, args[0], e.Folder)
+	}
+	return 0
+}
+
+// writeEnvScripts writes in %PRGS2% the env and aliases scripts
+// of the selected shells, and in %PRGS2%/bin the shims,
+// for all installed programs.
+func writeEnvScripts(ps []prgs.Prg) error {
+	names := *shellsFlag
+	if names == "" {
+		names = prgs.Global("shells")
+	}
+	if names == "" {
+		names = "cmd"
+	}
+	shs, err := shells.Parse(names)
+	if err != nil {
+		return err
+	}
+	root, err := envs.Prgsenv()
+	if err != nil {
+		return err
+	}
+	bin := root.Add("bin").SetDir()
+	shims, err := shells.Shims(ps, root)
+	if err != nil {
+		return err
+	}
+	path := envs.Path()
+	for _, dir := range path.Missing() {
+		fmt.Fprintf(godbg.Err(), "Warning: PATH folder '%s' does not exist\n", dir)
+	}
+	path = path.Add(bin.NoSep().String())
+	env, err := shells.NewEnv(ps, root, path)
+	if err != nil {
+		return err
+	}
+	if !bin.Exists() && !bin.MkdirAll() {
+		return fmt.Errorf("unable to create bin folder '%v'", bin)
+	}
+	if err = shells.WriteShims(shims, bin); err != nil {
+		return err
+	}
+	return shells.WriteScripts(env, root, shs)
+}
@@ -1,20 +1,39 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/VonC/godbg"
 	"github.com/VonC/godbg/exit"
+	"github.com/VonC/senvgo/envs"
 	"github.com/VonC/senvgo/installer"
+	"github.com/VonC/senvgo/journal"
+	"github.com/VonC/senvgo/lock"
+	"github.com/VonC/senvgo/lockfile"
+	"github.com/VonC/senvgo/paths"
 	"github.com/VonC/senvgo/prgs"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 type testGetter0Prg struct{}
-type testPrg struct{ name string }
+type testPrg struct {
+	prgs.Prg
+	name string
+}
 
 func (tp *testPrg) Name() string { return tp.name }
+func (tp *testPrg) Err() error {
+	if strings.HasPrefix(tp.name, "prge") {
+		return fmt.Errorf("env 'A': undefined environment variable 'UNSET'")
+	}
+	return nil
+}
 
 func (tg0 testGetter0Prg) Get() []prgs.Prg {
 	return []prgs.Prg{}
@@ -28,12 +47,19 @@ func (tg3 testGetter3Prgs) Get() []prgs.Prg {
 	return []prgs.Prg{&testPrg{name: prefix + "1"}, &testPrg{name: prefix + "2"}, &testPrg{name: prefix + "3"}}
 }
 
-type testInst struct{ p prgs.Prg }
+type testInst struct {
+	p prgs.Prg
+	e *lockfile.Entry
+}
 
 func newTestInst(p prgs.Prg) installer.Inst {
 	return &testInst{p: p}
 }
 
+func newTestLockedInst(p prgs.Prg, e *lockfile.Entry) installer.Inst {
+	return &testInst{p: p, e: e}
+}
+
 func (ti *testInst) IsInstalled() bool {
 	return strings.HasPrefix(ti.p.Name(), "prgi")
 }
@@ -41,9 +67,74 @@ func (ti *testInst) HasFailed() bool {
 	return strings.HasPrefix(ti.p.Name(), "prgf")
 }
 func (ti *testInst) Install() error {
+	if strings.HasSuffix(ti.p.Name(), "3") {
+		return fmt.Errorf("no archive")
+	}
+	if ti.e != nil {
+		installs = append(installs, ti.p.Name()+"@"+ti.e.Folder)
+	} else {
+		installs = append(installs, ti.p.Name())
+	}
+	return nil
+}
+func (ti *testInst) Uninstall() error {
+	if strings.HasSuffix(ti.p.Name(), "3") {
+		return fmt.Errorf("uninstcmd failed")
+	}
+	uninstalled = append(uninstalled, ti.p.Name())
 	return nil
 }
 
+func (ti *testInst) Status() (*installer.Status, error) {
+	switch {
+	case strings.HasSuffix(ti.p.Name(), "3"):
+		return &installer.Status{Current: "v3", Action: installer.ActionUnknown}, fmt.Errorf("no archive")
+	case ti.IsInstalled():
+		return &installer.Status{Current: "v1", Latest: "v2", Action: installer.ActionUpdate}, nil
+	}
+	return &installer.Status{Latest: "v2", Action: installer.ActionInstall}, nil
+}
+
+func (ti *testInst) Resolve() (*lockfile.Entry, error) {
+	if strings.HasSuffix(ti.p.Name(), "3") {
+		return nil, fmt.Errorf("no archive")
+	}
+	return &lockfile.Entry{Name: ti.p.Name(), Archive: ti.p.Name() + ".zip", Folder: ti.p.Name() + "-v2", Checksum: "sha256:00"}, nil
+}
+
+var uninstalled []string
+var installs []string
+
+var envWritten []prgs.Prg
+var envErr error
+
+func testWriteEnv(ps []prgs.Prg) error {
+	envWritten = ps
+	return envErr
+}
+
+var rootErr error
+
+func testFindRoot() (*envs.Root, error) {
+	if rootErr != nil {
+		return nil, rootErr
+	}
+	return &envs.Root{Path: paths.NewPathDir("prgs"), Source: "test"}, nil
+}
+
+var lockModes []lock.Mode
+var lockErr error
+
+func testAcquireLock(root *paths.Path, mode lock.Mode, wait bool) (*lock.Lock, error) {
+	lockModes = append(lockModes, mode)
+	if lockErr != nil {
+		return nil, lockErr
+	}
+	return &lock.Lock{}, nil
+}
+
+var rootLine = "Programs root 'prgs" + string(os.PathSeparator) + "' (from test)\n"
+
 func TestMain(t *testing.T) {
 
 	exiter = exit.New(func(int) {})
@@ -53,6 +144,10 @@ func TestMain(t *testing.T) {
 		prefix = "prg"
 		prgsGetter = testGetter0Prg{}
 		newInstaller = newTestInst
+		newLockedInstaller = newTestLockedInst
+		writeEnv = testWriteEnv
+		findRoot = testFindRoot
+		acquireLock = testAcquireLock
 		main()
 		So(ErrString(), ShouldEqualNL, `  [main] (func)
     senvgo
@@ -62,7 +157,7 @@ func TestMain(t *testing.T) {
 		Convey("No prg means no prgs installed", func() {
 			SetBuffers(nil)
 			main()
-			So(OutString(), ShouldEqual, `No program to install: nothing to do`)
+			So(OutString(), ShouldEqual, rootLine+`No program to install: nothing to do`)
 			So(ErrString(), ShouldEqualNL, `  [main] (func)
     senvgo
 `)
@@ -70,7 +165,7 @@ func TestMain(t *testing.T) {
 			prgsGetter = testGetter3Prgs{}
 			SetBuffers(nil)
 			main()
-			So(OutString(), ShouldNotEqual, `No program to install: nothing to do`)
+			So(OutString(), ShouldNotEqual, rootLine+`No program to install: nothing to do`)
 		})
 
 		Convey("A program already installed means nothing to do", func() {
@@ -78,20 +173,191 @@ func TestMain(t *testing.T) {
 			prgsGetter = testGetter3Prgs{}
 			SetBuffers(nil)
 			main()
-			So(OutString(), ShouldEqual, `'prgi1' (1/3)... already installed: nothing to do
+			So(OutString(), ShouldEqual, rootLine+`'prgi1' (1/3)... already installed: nothing to do
 'prgi2' (2/3)... already installed: nothing to do
 'prgi3' (3/3)... already installed: nothing to do
 `)
+			So(len(envWritten), ShouldEqual, 3)
 		})
 		Convey("A program already failed means nothing to do", func() {
 			prefix = "prgf"
 			prgsGetter = testGetter3Prgs{}
 			SetBuffers(nil)
 			main()
-			So(OutString(), ShouldEqual, `'prgf1' (1/3)... already failed to install
+			So(OutString(), ShouldEqual, rootLine+`'prgf1' (1/3)... already failed to install
 'prgf2' (2/3)... already failed to install
 'prgf3' (3/3)... already failed to install
 `)
+			So(len(envWritten), ShouldEqual, 0)
+		})
+		Convey("A program with an invalid config is reported", func() {
+			prefix = "prge"
+			prgsGetter = testGetter3Prgs{}
+			SetBuffers(nil)
+			main()
+			So(OutString(), ShouldStartWith, rootLine+`'prge1' (1/3)... invalid config: env 'A': undefined environment variable 'UNSET'
+'prge2' (2/3)... invalid config: `)
+			So(len(envWritten), ShouldEqual, 0)
+			SetBuffers(nil)
+			So(run([]string{"install", "prge1"}), ShouldEqual, 1)
+			So(OutString(), ShouldEqual, rootLine+"Unable to install 'prge1': invalid config: env 'A': undefined environment variable 'UNSET'\n")
+		})
+		Convey("Env scripts writing can fail", func() {
+			prefix = "prgi"
+			prgsGetter = testGetter3Prgs{}
+			envErr = fmt.Errorf("unknown shell 'tcsh'")
+			SetBuffers(nil)
+			main()
+			So(OutString(), ShouldEndWith, "Unable to write env scripts: unknown shell 'tcsh'\n")
+			So(exiter.Status(), ShouldEqual, 1)
+			envErr = nil
+		})
+		Convey("No root means nothing installed", func() {
+			rootErr = fmt.Errorf("root 'x' (from flag --root) does not exist")
+			SetBuffers(nil)
+			main()
+			So(OutString(), ShouldEqual, "Unable to find where to install programs: root 'x' (from flag --root) does not exist\n")
+			So(exiter.Status(), ShouldEqual, 1)
+			rootErr = nil
+		})
+		Convey("A root locked by another senvgo means nothing installed", func() {
+			lockModes = nil
+			lockErr = fmt.Errorf("'prgs' is locked: exclusive lock held by pid 12")
+			SetBuffers(nil)
+			main()
+			So(OutString(), ShouldEqual, rootLine+"Unable to lock programs root: 'prgs' is locked: exclusive lock held by pid 12\n")
+			So(exiter.Status(), ShouldEqual, 1)
+			So(lockModes, ShouldResemble, []lock.Mode{lock.Exclusive})
+			lockErr = nil
+		})
+	})
+
+	Convey("senvgo commands", t, func() {
+		SetBuffers(nil)
+		prefix = "prgi"
+		prgsGetter = testGetter3Prgs{}
+		So(run([]string{"xxx"}), ShouldEqual, 1)
+		So(OutString(), ShouldEqual, rootLine+"Unknown command 'xxx'\n")
+
+		Convey("read-only commands only take a shared lock", func() {
+			lockModes = nil
+			commands["ro"] = func(args []string) int { return 0 }
+			readOnly["ro"] = true
+			So(run([]string{"ro"}), ShouldEqual, 0)
+			So(run([]string{"remove"}), ShouldEqual, 1)
+			So(lockModes, ShouldResemble, []lock.Mode{lock.Shared, lock.Exclusive})
+			delete(commands, "ro")
+			delete(readOnly, "ro")
+		})
+
+		Convey("remove uninstalls a program and writes env scripts for the others", func() {
+			SetBuffers(nil)
+			uninstalled = nil
+			So(run([]string{"remove", "prgi2"}), ShouldEqual, 0)
+			So(OutString(), ShouldEqual, rootLine+"'prgi2' removed\n")
+			So(uninstalled, ShouldResemble, []string{"prgi2"})
+			So(len(envWritten), ShouldEqual, 2)
+			So(envWritten[1].Name(), ShouldEqual, "prgi3")
+		})
+
+		Convey("history prints the journal, of all or one program", func() {
+			dir, _ := ioutil.TempDir("", "senvgo_history")
+			defer os.RemoveAll(dir)
+			envs.SetRootFlag(dir)
+			SetBuffers(nil)
+			So(run([]string{"history"}), ShouldEqual, 0)
+			So(OutString(), ShouldEqual, rootLine+"No history\n")
+			j := journal.Open(paths.NewPathDir(dir))
+			t := time.Date(2015, 4, 1, 10, 0, 0, 0, time.UTC)
+			j.Record(&journal.Event{Time: t, Program: "git", Folder: "PortableGit-2.0", Action: journal.Install, Outcome: journal.OK})
+			j.Record(&journal.Event{Time: t, Program: "go", Folder: "go1.4", Action: journal.Install, Outcome: journal.Failed, Error: "no go.exe"})
+			SetBuffers(nil)
+			So(run([]string{"history", "go"}), ShouldEqual, 0)
+			So(OutString(), ShouldEqual, rootLine+"2015-04-01 10:00:00 install    go         failed   go1.4: no go.exe\n")
+			SetBuffers(nil)
+			So(run([]string{"history"}), ShouldEqual, 0)
+			So(strings.Count(OutString(), "\n"), ShouldEqual, 3)
+			So(run([]string{"history", "a", "b"}), ShouldEqual, 1)
+			So(OutString(), ShouldEndWith, "Usage: senvgo history [name]\n")
+			envs.SetRootFlag("")
+		})
+
+		Convey("outdated compares installed and latest versions", func() {
+			SetBuffers(nil)
+			So(run([]string{"outdated", "prgi1", "prgi2"}), ShouldEqual, 0)
+			So(OutString(), ShouldEqual, rootLine+
+				"Program      Current                        Latest                         Action\n"+
+				"prgi1        v1                             v2                             update\n"+
+				"prgi2        v1                             v2                             update\n")
+			SetBuffers(nil)
+			So(run([]string{"outdated"}), ShouldEqual, 1)
+			So(OutString(), ShouldEndWith, "prgi3        v3                             -                              unknown: no archive\n")
+			SetBuffers(nil)
+			So(run([]string{"outdated", "prgi4"}), ShouldEqual, 1)
+			So(OutString(), ShouldEqual, rootLine+"Unknown program 'prgi4'\n")
+		})
+
+		Convey("lock records the releases to install, install --locked installs them", func() {
+			dir, _ := ioutil.TempDir("", "senvgo_lock")
+			defer os.RemoveAll(dir)
+			envs.SetRootFlag(dir)
+			SetBuffers(nil)
+			So(run([]string{"lock"}), ShouldEqual, 1)
+			So(OutString(), ShouldEqual, rootLine+"'prgi1' locked to 'prgi1-v2'\n'prgi2' locked to 'prgi2-v2'\nUnable to lock 'prgi3': no archive\n")
+			_, err := os.Stat(dir + string(os.PathSeparator) + lockfile.FileName)
+			So(os.IsNotExist(err), ShouldBeTrue)
+			SetBuffers(nil)
+			So(run([]string{"lock", "prgi1", "prgi2"}), ShouldEqual, 0)
+			lf, _ := lockfile.Load(paths.NewPathDir(dir))
+			So(lf.Names(), ShouldResemble, []string{"prgi1", "prgi2"})
+
+			SetBuffers(nil)
+			installs = nil
+			So(run([]string{"install", "--locked"}), ShouldEqual, 0)
+			So(installs, ShouldResemble, []string{"prgi1@prgi1-v2", "prgi2@prgi2-v2"})
+			So(OutString(), ShouldEqual, rootLine+"'prgi1' installed\n'prgi2' installed\n")
+			So(len(envWritten), ShouldEqual, 3)
+			SetBuffers(nil)
+			So(run([]string{"install", "--locked", "prgi3"}), ShouldEqual, 1)
+			So(OutString(), ShouldEqual, rootLine+"'prgi3' is not in lockfile '"+dir+string(os.PathSeparator)+lockfile.FileName+"'\n")
+
+			SetBuffers(nil)
+			installs = nil
+			So(run([]string{"install"}), ShouldEqual, 1)
+			So(installs, ShouldResemble, []string{"prgi1", "prgi2"})
+			So(OutString(), ShouldEndWith, "Unable to install 'prgi3': no archive\n")
+			So(run([]string{"install", "--xxx"}), ShouldEqual, 1)
+			So(run([]string{"install", "--offline"}), ShouldEqual, 1)
+			So(flag.Lookup("offline"), ShouldNotBeNil)
+			envs.SetRootFlag("")
+		})
+
+		Convey("bundle exports and imports locked programs", func() {
+			dir, _ := ioutil.TempDir("", "senvgo_bundle")
+			defer os.RemoveAll(dir)
+			envs.SetRootFlag(dir)
+			SetBuffers(nil)
+			So(run([]string{"bundle", "export"}), ShouldEqual, 1)
+			So(run([]string{"bundle", "xxx", "b.zip"}), ShouldEqual, 1)
+			So(run([]string{"bundle", "import", "b.zip", "go"}), ShouldEqual, 1)
+			So(strings.Count(OutString(), "Usage: senvgo bundle export <file> [name]..."), ShouldEqual, 3)
+			SetBuffers(nil)
+			So(run([]string{"bundle", "export", dir + "/b.zip"}), ShouldEqual, 1)
+			So(OutString(), ShouldStartWith, rootLine+"Unable to export bundle: no program in lockfile '")
+			SetBuffers(nil)
+			So(run([]string{"bundle", "import", dir + "/b.zip"}), ShouldEqual, 1)
+			So(OutString(), ShouldStartWith, rootLine+"Unable to import bundle: unable to extract bundle '")
+			envs.SetRootFlag("")
+		})
+
+		Convey("remove reports unknown programs and uninstall errors", func() {
+			SetBuffers(nil)
+			So(run([]string{"remove"}), ShouldEqual, 1)
+			So(run([]string{"remove", "prgi4"}), ShouldEqual, 1)
+			So(run([]string{"remove", "prgi3"}), ShouldEqual, 1)
+			So(OutString(), ShouldEqual, rootLine+"Usage: senvgo remove <name>...\n"+
+				rootLine+"Unknown program 'prgi4'\n"+
+				rootLine+"Unable to remove 'prgi3': uninstcmd failed\n")
 		})
 	})
 }
@@ -0,0 +1,162 @@
+package shells
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+)
+
+// Env is what environment scripts have to define:
+// the PATH, environment variables and aliases
+type Env struct {
+	Path    []string
+	Vars    []*prgs.Setting
+	Aliases []*Alias
+}
+
+// Shell writes an environment script for a given shell
+type Shell interface {
+	// Name is the name of the shell, as selected by the user
+	Name() string
+	// Ext is the extension of the environment script for that shell
+	Ext() string
+	// Write writes the environment script, properly quoted for the shell.
+	// It ends by sourcing the aliases script, expected in the same folder.
+	Write(env *Env, w io.Writer) error
+	// WriteAliases writes the aliases script for the shell
+	WriteAliases(aliases []*Alias, w io.Writer) error
+}
+
+var shells = map[string]Shell{}
+var shellNames = []string{}
+
+func register(sh Shell, aliases ...string) {
+	shellNames = append(shellNames, sh.Name())
+	for _, name := range append([]string{sh.Name()}, aliases...) {
+		shells[name] = sh
+	}
+}
+
+// Get returns the Shell registered for a name (like 'cmd', 'powershell',
+// 'bash' or 'fish'), or an error if no shell is known by that name.
+func Get(name string) (Shell, error) {
+	sh, ok := shells[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown shell '%s' (expected one of %s)", name, strings.Join(shellNames, ", "))
+	}
+	return sh, nil
+}
+
+// Parse returns the Shells for a comma or space separated list of names.
+// Each shell is returned only once, even if named with two aliases.
+func Parse(names string) ([]Shell, error) {
+	res := []Shell{}
+	seen := map[Shell]bool{}
+	for _, name := range strings.FieldsFunc(names, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		sh, err := Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[sh] {
+			seen[sh] = true
+			res = append(res, sh)
+		}
+	}
+	return res, nil
+}
+
+// NewEnv computes the environment of a collection of programs installed
+// under root: 'path' is the initial PATH, to which each program 'path'
+// is added (in its 'latest' folder) if not already there,
+// followed by their 'env' and 'doskey'.
+// 'env' values are expanded (see prgs.Template).
+// Two programs defining the same alias is an error.
+func NewEnv(ps []prgs.Prg, root *paths.Path, path *envs.PathList) (*Env, error) {
+	env := &Env{}
+	if path == nil {
+		path = envs.NewPathList()
+	}
+	for _, p := range ps {
+		latest := Latest(root, p)
+		if p.Path() != "" {
+			path = path.Add(latest.Add(p.Path()).NoSep().String())
+		}
+		r := &resolver{root: root, prg: p, prgs: ps}
+		for _, ve := range p.Envs() {
+			value, err := r.expand(ve.Value)
+			if err != nil {
+				return nil, fmt.Errorf("prg '%s': env '%s': %v", p.Name(), ve.Name, err)
+			}
+			env.Vars = append(env.Vars, &prgs.Setting{Name: ve.Name, Value: value})
+		}
+	}
+	aliases, err := Aliases(ps, root)
+	if err != nil {
+		return nil, err
+	}
+	env.Path = path.Dirs()
+	env.Aliases = aliases
+	return env, nil
+}
+
+// Latest returns the 'latest' folder of a program installed under root
+func Latest(root *paths.Path, p prgs.Prg) *paths.Path {
+	return root.Add(p.Dir()).Add("latest").SetDir()
+}
+
+var foscreate func(file *paths.Path) (io.WriteCloser, error)
+
+// ifoscreate creates a file on its FS (see paths.NewPathFS)
+func ifoscreate(file *paths.Path) (io.WriteCloser, error) {
+	f, err := file.OpenFile(false)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// WriteScripts writes in dir one 'env' and one 'aliases' script per shell
+// (env.bat and aliases.bat, env.ps1 and aliases.ps1, ...),
+// replacing any existing one.
+func WriteScripts(env *Env, dir *paths.Path, shs []Shell) error {
+	for _, sh := range shs {
+		sh := sh
+		err := writeFile(dir.Add("env"+sh.Ext()), func(w io.Writer) error {
+			return sh.Write(env, w)
+		})
+		if err != nil {
+			return err
+		}
+		err = writeFile(dir.Add("aliases"+sh.Ext()), func(w io.Writer) error {
+			return sh.WriteAliases(env.Aliases, w)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(file *paths.Path, write func(w io.Writer) error) error {
+	f, err := foscreate(file)
+	if err != nil {
+		return fmt.Errorf("unable to create '%v': '%v'", file, err)
+	}
+	err = write(f)
+	if errc := f.Close(); err == nil {
+		err = errc
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write '%v': '%v'", file, err)
+	}
+	return nil
+}
+
+func init() {
+	foscreate = ifoscreate
+}
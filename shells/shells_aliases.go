@@ -0,0 +1,195 @@
+package shells
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+)
+
+// ArgsAll is the alias argument standing for all the arguments
+// passed to the alias ('$*' for doskey, '%*' for a batch)
+const ArgsAll = "$*"
+
+// Alias is a command alias (from a 'doskey' config)
+// or a shim in %PRGS2%/bin (from an 'addbin' config).
+type Alias struct {
+	Name string
+	// Prg is the name of the program defining the alias
+	Prg string
+	// Args is the command line, one argument per element.
+	// An ArgsAll element is replaced by the alias arguments.
+	// No Args means the alias is to be removed.
+	Args []string
+}
+
+// Aliases returns the 'doskey' aliases of programs installed under root,
+// or an error if two programs claim the same alias name.
+func Aliases(ps []prgs.Prg, root *paths.Path) ([]*Alias, error) {
+	return collect(ps, root, "alias", func(p prgs.Prg) []*prgs.Setting { return p.Doskeys() })
+}
+
+// Shims returns the 'addbin' shims of programs installed under root,
+// or an error if two programs claim the same shim name.
+func Shims(ps []prgs.Prg, root *paths.Path) ([]*Alias, error) {
+	return collect(ps, root, "shim", func(p prgs.Prg) []*prgs.Setting { return p.Addbins() })
+}
+
+// collect compares names case-insensitively, as Windows does for doskey and files.
+// A program redefining its own alias simply replaces it.
+func collect(ps []prgs.Prg, root *paths.Path, kind string, settings func(p prgs.Prg) []*prgs.Setting) ([]*Alias, error) {
+	res := []*Alias{}
+	byName := map[string]*Alias{}
+	for _, p := range ps {
+		latest := Latest(root, p)
+		for _, s := range settings(p) {
+			a := &Alias{Name: s.Name, Prg: p.Name(), Args: Args(s.Value, latest)}
+			key := strings.ToLower(a.Name)
+			if other, ok := byName[key]; ok {
+				if other.Prg != a.Prg {
+					return nil, fmt.Errorf("%s '%s' claimed by both '%s' and '%s'", kind, a.Name, other.Prg, a.Prg)
+				}
+				*other = *a
+				continue
+			}
+			byName[key] = a
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
+
+// Args splits a command line in arguments (double quotes grouping spaces),
+// expanding a leading '~' to the program folder ('/' or '\' separated),
+// and both '$*' and '%*' to ArgsAll.
+// A first argument which is a relative path with a folder
+// (like 'bin\go.exe') is relative to the program folder as well.
+func Args(cmd string, folder *paths.Path) []string {
+	res := []string{}
+	for i, arg := range split(cmd) {
+		switch {
+		case arg == "$*" || arg == "%*":
+			arg = ArgsAll
+		case strings.HasPrefix(arg, "~"):
+			arg = folder.Add(strings.Replace(arg[1:], `\`, "/", -1)).String()
+		case i == 0 && strings.ContainsAny(arg, `/\`) && !isAbs(arg):
+			arg = folder.Add(strings.Replace(arg, `\`, "/", -1)).String()
+		}
+		res = append(res, arg)
+	}
+	return res
+}
+
+func isAbs(arg string) bool {
+	return filepath.IsAbs(arg) || drivePathRx.MatchString(arg) || strings.HasPrefix(arg, `\`) || strings.HasPrefix(arg, "/")
+}
+
+func split(cmd string) []string {
+	res := []string{}
+	arg := ""
+	inArg := false
+	inQuotes := false
+	for _, r := range cmd {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if inArg {
+				res = append(res, arg)
+			}
+			arg = ""
+			inArg = false
+		default:
+			arg = arg + string(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		res = append(res, arg)
+	}
+	return res
+}
+
+var fchmod func(name string, mode os.FileMode) error
+
+func ifchmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// WriteShims writes each shim in the bin folder, as a batch for a '.bat'
+// or '.cmd' name, a PowerShell script for '.ps1', and an executable
+// sh script for a name without extension.
+// Existing shims are overwritten: they are regenerated at each run.
+func WriteShims(shims []*Alias, bin *paths.Path) error {
+	sorted := append([]*Alias{}, shims...)
+	sort.Sort(byAliasName(sorted))
+	for _, shim := range sorted {
+		var write func(shim *Alias, w io.Writer) error
+		switch strings.ToLower(filepath.Ext(shim.Name)) {
+		case ".bat", ".cmd":
+			write = cmdShim
+		case ".ps1":
+			write = psShim
+		case "":
+			write = shShim
+		default:
+			return fmt.Errorf("shim '%s' of '%s': unsupported extension", shim.Name, shim.Prg)
+		}
+		if len(shim.Args) == 0 {
+			return fmt.Errorf("shim '%s' of '%s': empty command", shim.Name, shim.Prg)
+		}
+		file := bin.Add(shim.Name)
+		err := writeFile(file, func(w io.Writer) error { return write(shim, w) })
+		if err != nil {
+			return err
+		}
+		if filepath.Ext(shim.Name) == "" {
+			if err = fchmod(file.String(), 0755); err != nil {
+				return fmt.Errorf("unable to make '%v' executable: '%v'", file, err)
+			}
+		}
+	}
+	return nil
+}
+
+type byAliasName []*Alias
+
+func (a byAliasName) Len() int           { return len(a) }
+func (a byAliasName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+func (a byAliasName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+func cmdShim(shim *Alias, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	args := []string{}
+	for _, arg := range shim.Args {
+		args = append(args, cmdBatchArg(arg))
+	}
+	lw.printf("@echo off\r")
+	lw.printf("%s\r", strings.Join(args, " "))
+	return lw.err
+}
+
+func psShim(shim *Alias, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	lw.printf("& %s", psArgs(shim.Args))
+	lw.printf("exit $LASTEXITCODE")
+	return lw.err
+}
+
+func shShim(shim *Alias, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	lw.printf("#!/bin/sh")
+	lw.printf("exec %s", shArgs(shim.Args))
+	return lw.err
+}
+
+func init() {
+	fchmod = ifchmod
+}
@@ -0,0 +1,101 @@
+package shells
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAliases(t *testing.T) {
+
+	root := paths.NewPathDir("/prgs")
+	node := &testPrg{name: "node", doskeys: []*prgs.Setting{{Name: "node", Value: ""}},
+		addbins: []*prgs.Setting{{Name: "node.bat", Value: "~node.exe $*"}}}
+	npm := &testPrg{name: "npm", doskeys: []*prgs.Setting{{Name: "node", Value: ""}},
+		addbins: []*prgs.Setting{{Name: "npm.bat", Value: "~node/npm.bat $*"}}}
+	goprg := &testPrg{name: "go", addbins: []*prgs.Setting{{Name: "go.bat", Value: `bin\go.exe %*`}, {Name: "go", Value: `bin/go "$*" $*`}}}
+
+	Convey("Aliases and shims are collected per program", t, func() {
+		SetBuffers(nil)
+		shims, err := Shims([]prgs.Prg{node, npm, goprg}, root)
+		So(err, ShouldBeNil)
+		So(len(shims), ShouldEqual, 4)
+		So(shims[0].Prg, ShouldEqual, "node")
+		So(shims[0].Args, ShouldResemble, []string{paths.NewPath("/prgs/node/latest/node.exe").String(), ArgsAll})
+		So(shims[1].Args, ShouldResemble, []string{paths.NewPath("/prgs/npm/latest/node/npm.bat").String(), ArgsAll})
+		So(shims[2].Args, ShouldResemble, []string{paths.NewPath("/prgs/go/latest/bin/go.exe").String(), ArgsAll})
+		So(shims[3].Args, ShouldResemble, []string{paths.NewPath("/prgs/go/latest/bin/go").String(), "$*", ArgsAll})
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("Two programs claiming the same name fail loudly", func() {
+			aliases, err := Aliases([]prgs.Prg{node, npm}, root)
+			So(aliases, ShouldBeNil)
+			So(err.Error(), ShouldEqual, "alias 'node' claimed by both 'node' and 'npm'")
+			npm2 := &testPrg{name: "npm", addbins: []*prgs.Setting{{Name: "Node.BAT", Value: "x"}}}
+			_, err = Shims([]prgs.Prg{node, npm2}, root)
+			So(err.Error(), ShouldEqual, "shim 'Node.BAT' claimed by both 'node' and 'npm'")
+		})
+
+		Convey("A program can redefine its own alias", func() {
+			git := &testPrg{name: "git", doskeys: []*prgs.Setting{{Name: "gl", Value: "git lg"}, {Name: "gl", Value: "git lg -20"}}}
+			aliases, err := Aliases([]prgs.Prg{git}, root)
+			So(err, ShouldBeNil)
+			So(len(aliases), ShouldEqual, 1)
+			So(aliases[0].Args, ShouldResemble, []string{"git", "lg", "-20"})
+		})
+	})
+
+	Convey("Command lines are split on spaces, except within double quotes", t, func() {
+		So(Args(`a "b c" %*`, root), ShouldResemble, []string{"a", "b c", ArgsAll})
+		So(Args(`  `, root), ShouldBeEmpty)
+		So(Args(`""`, root), ShouldResemble, []string{""})
+		So(Args(`C:\WINDOWS\system32\msiexec.exe /x`, root), ShouldResemble, []string{`C:\WINDOWS\system32\msiexec.exe`, "/x"})
+		So(Args(`/usr/bin/env a/b`, root), ShouldResemble, []string{`/usr/bin/env`, "a/b"})
+	})
+
+	Convey("Shims are written in the bin folder", t, func() {
+		SetBuffers(nil)
+		bin := paths.NewPathDir("testbin")
+		So(bin.MkdirAll(), ShouldBeTrue)
+		defer os.RemoveAll("testbin")
+		shims := []*Alias{
+			{Name: "go.bat", Prg: "go", Args: []string{`C:\p f\go.exe`, "a&b", "50%", ArgsAll}},
+			{Name: "go.ps1", Prg: "go", Args: []string{`C:\p f\go.exe`, ArgsAll}},
+			{Name: "go", Prg: "go", Args: []string{`/p f/go`, ArgsAll}},
+		}
+		So(WriteShims(shims, bin), ShouldBeNil)
+		So(bin.Add("go.bat").FileContent(), ShouldEqual, "@echo off\r\n\"C:\\p f\\go.exe\" a^&b 50%% %*\r\n")
+		So(bin.Add("go.ps1").FileContent(), ShouldEqual, "& 'C:\\p f\\go.exe' @args\nexit $LASTEXITCODE\n")
+		So(bin.Add("go").FileContent(), ShouldEqual, "#!/bin/sh\nexec '/p f/go' \"$@\"\n")
+		fi, _ := os.Stat(bin.Add("go").String())
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0755))
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("Shims can be invalid", func() {
+			err := WriteShims([]*Alias{{Name: "go.exe", Prg: "go", Args: []string{"x"}}}, bin)
+			So(err.Error(), ShouldEqual, "shim 'go.exe' of 'go': unsupported extension")
+			err = WriteShims([]*Alias{{Name: "go.bat", Prg: "go"}}, bin)
+			So(err.Error(), ShouldEqual, "shim 'go.bat' of 'go': empty command")
+		})
+
+		Convey("Shims writing can fail", func() {
+			fchmod = testfchmod
+			err := WriteShims(shims, bin)
+			So(err.Error(), ShouldEndWith, "executable: 'Error (chmod)'")
+			fchmod = ifchmod
+			foscreate = testfoscreate
+			err = WriteShims(shims, bin)
+			So(err.Error(), ShouldEndWith, "go': 'Error (create) for go'")
+			foscreate = ifoscreate
+		})
+	})
+}
+
+func testfchmod(name string, mode os.FileMode) error {
+	return fmt.Errorf("Error (chmod)")
+}
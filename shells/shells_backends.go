@@ -0,0 +1,264 @@
+package shells
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// cmd writes an env.bat, for cmd.exe
+type cmd struct{}
+
+// powershell writes an env.ps1, to be dot-sourced
+type powershell struct{}
+
+// bash writes an env.sh, to be sourced by bash or zsh
+type bash struct{}
+
+// fish writes an env.fish, to be sourced by fish
+type fish struct{}
+
+func init() {
+	register(&cmd{})
+	register(&powershell{}, "ps", "pwsh", "ps1")
+	register(&bash{}, "sh", "zsh")
+	register(&fish{})
+}
+
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) printf(format string, args ...interface{}) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = fmt.Fprintf(lw.w, format+"\n", args...)
+}
+
+func (c *cmd) Name() string { return "cmd" }
+func (c *cmd) Ext() string  { return ".bat" }
+
+// Write uses 'set "VAR=value"', which protects '&', '^' and spaces,
+// while '%' is doubled in order to not be expanded.
+func (c *cmd) Write(env *Env, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	lw.printf("@echo off")
+	if len(env.Path) > 0 {
+		lw.printf(`set "PATH=%s"`, cmdPercent(strings.Join(env.Path, ";")))
+	}
+	for _, v := range env.Vars {
+		lw.printf(`set "%s=%s"`, v.Name, cmdPercent(v.Value))
+	}
+	lw.printf(`call "%%~dp0aliases%s"`, c.Ext())
+	return lw.err
+}
+
+// WriteAliases writes doskey lines: they are not quoted,
+// hence their special characters are escaped (see doskeyArg).
+func (c *cmd) WriteAliases(aliases []*Alias, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	lw.printf("@echo off")
+	for _, a := range aliases {
+		args := []string{}
+		for _, arg := range a.Args {
+			if arg == ArgsAll {
+				args = append(args, "$*")
+			} else {
+				args = append(args, doskeyArg(strings.Replace(arg, "$", "$$", -1)))
+			}
+		}
+		lw.printf("doskey %s=%s", a.Name, strings.Join(args, " "))
+	}
+	return lw.err
+}
+
+func cmdPercent(s string) string {
+	return strings.Replace(s, "%", "%%", -1)
+}
+
+var cmdSpecialRx = regexp.MustCompile(`([\^&|<>()])`)
+
+// cmdBatchArg double quotes an argument with spaces,
+// or escapes its special characters with '^'.
+func cmdBatchArg(arg string) string {
+	if arg == ArgsAll {
+		return "%*"
+	}
+	arg = cmdPercent(arg)
+	if strings.ContainsAny(arg, " \t") {
+		return `"` + arg + `"`
+	}
+	return cmdSpecialRx.ReplaceAllString(arg, "^$1")
+}
+
+// doskeyArg is cmdBatchArg for a doskey macro: the batch line is parsed once
+// when defining the macro, then the macro again when run, so an unquoted
+// special character is escaped twice ('&' as '^^^&', stored as '^&').
+func doskeyArg(arg string) string {
+	if arg == ArgsAll || strings.ContainsAny(arg, " \t") {
+		return cmdBatchArg(arg)
+	}
+	return cmdSpecialRx.ReplaceAllString(cmdPercent(arg), "^^^$1")
+}
+
+func (ps *powershell) Name() string { return "powershell" }
+func (ps *powershell) Ext() string  { return ".ps1" }
+
+// Write uses single-quoted strings, which are not expanded by PowerShell:
+// only the single quote itself needs to be doubled.
+func (ps *powershell) Write(env *Env, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	if len(env.Path) > 0 {
+		quoted := []string{}
+		for _, p := range env.Path {
+			quoted = append(quoted, psQuote(p))
+		}
+		lw.printf("$env:PATH = @(%s) -join [IO.Path]::PathSeparator", strings.Join(quoted, ", "))
+	}
+	for _, v := range env.Vars {
+		lw.printf("${env:%s} = %s", v.Name, psQuote(v.Value))
+	}
+	lw.printf(". (Join-Path $PSScriptRoot 'aliases%s')", ps.Ext())
+	return lw.err
+}
+
+// WriteAliases writes functions, since a PowerShell alias cannot take arguments.
+func (ps *powershell) WriteAliases(aliases []*Alias, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	for _, a := range aliases {
+		if len(a.Args) == 0 {
+			lw.printf(`Remove-Item -Path Function:\%s, Alias:\%s -ErrorAction SilentlyContinue`, a.Name, a.Name)
+			continue
+		}
+		lw.printf("function global:%s { & %s }", a.Name, psArgs(a.Args))
+	}
+	return lw.err
+}
+
+func psArgs(args []string) string {
+	res := []string{}
+	for _, arg := range args {
+		if arg == ArgsAll {
+			res = append(res, "@args")
+		} else {
+			res = append(res, psQuote(arg))
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+func psQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func (b *bash) Name() string { return "bash" }
+func (b *bash) Ext() string  { return ".sh" }
+
+// Write uses single-quoted strings, which are not expanded by the shell.
+// PATH is ':' separated: Windows drive paths are converted to '/c/...'.
+func (b *bash) Write(env *Env, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	if len(env.Path) > 0 {
+		unix := []string{}
+		for _, p := range env.Path {
+			unix = append(unix, unixPath(p))
+		}
+		lw.printf("export PATH=%s", shQuote(strings.Join(unix, ":")))
+	}
+	for _, v := range env.Vars {
+		lw.printf("export %s=%s", v.Name, shQuote(v.Value))
+	}
+	lw.printf(`. "$(dirname "${BASH_SOURCE[0]:-$0}")/aliases%s"`, b.Ext())
+	return lw.err
+}
+
+// WriteAliases writes functions, in order to place arguments anywhere.
+func (b *bash) WriteAliases(aliases []*Alias, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	for _, a := range aliases {
+		if len(a.Args) == 0 {
+			lw.printf("unalias %s 2>/dev/null; unset -f %s 2>/dev/null", a.Name, a.Name)
+			continue
+		}
+		lw.printf("%s() { %s; }", a.Name, shArgs(a.Args))
+	}
+	lw.printf("true")
+	return lw.err
+}
+
+func shArgs(args []string) string {
+	res := []string{}
+	for _, arg := range args {
+		if arg == ArgsAll {
+			res = append(res, `"$@"`)
+		} else {
+			res = append(res, shQuote(arg))
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+func shQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+var drivePathRx = regexp.MustCompile(`^([a-zA-Z]):[\\/]?`)
+
+// unixPath converts 'C:\a\b' in '/c/a/b', leaving non-drive paths untouched
+func unixPath(p string) string {
+	m := drivePathRx.FindStringSubmatch(p)
+	if m == nil {
+		return p
+	}
+	return "/" + strings.ToLower(m[1]) + "/" + strings.Replace(p[len(m[0]):], `\`, "/", -1)
+}
+
+func (f *fish) Name() string { return "fish" }
+func (f *fish) Ext() string  { return ".fish" }
+
+// Write uses single-quoted strings, where only '\' and the single quote
+// need to be escaped. PATH is a list in fish.
+func (f *fish) Write(env *Env, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	if len(env.Path) > 0 {
+		quoted := []string{}
+		for _, p := range env.Path {
+			quoted = append(quoted, fishQuote(unixPath(p)))
+		}
+		lw.printf("set -gx PATH %s", strings.Join(quoted, " "))
+	}
+	for _, v := range env.Vars {
+		lw.printf("set -gx %s %s", v.Name, fishQuote(v.Value))
+	}
+	lw.printf("source (dirname (status --current-filename))/aliases%s", f.Ext())
+	return lw.err
+}
+
+// WriteAliases writes functions, fish aliases being functions anyway.
+func (f *fish) WriteAliases(aliases []*Alias, w io.Writer) error {
+	lw := &lineWriter{w: w}
+	for _, a := range aliases {
+		if len(a.Args) == 0 {
+			lw.printf("functions -e %s", a.Name)
+			continue
+		}
+		args := []string{}
+		for _, arg := range a.Args {
+			if arg == ArgsAll {
+				args = append(args, "$argv")
+			} else {
+				args = append(args, fishQuote(arg))
+			}
+		}
+		lw.printf("function %s; %s; end", a.Name, strings.Join(args, " "))
+	}
+	return lw.err
+}
+
+func fishQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
@@ -0,0 +1,100 @@
+package shells
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/prgs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testEnv = &Env{
+	Path: []string{`C:\prgs\git\latest\bin`, `C:\Program Files\x`},
+	Vars: []*prgs.Setting{{Name: "A", Value: `50% & ^ $HOME 'q'`}},
+	Aliases: []*Alias{
+		{Name: "gl", Args: []string{"git", "lg", "-20"}},
+		{Name: "7z", Args: []string{`C:\p f\7z.exe`, "a&b", "$HOME", ArgsAll}},
+		{Name: "go"},
+	},
+}
+
+func write(name string) string {
+	sh, _ := Get(name)
+	b := bytes.NewBuffer(nil)
+	So(sh.Write(testEnv, b), ShouldBeNil)
+	So(sh.WriteAliases(testEnv.Aliases, b), ShouldBeNil)
+	return b.String()
+}
+
+type failWriter struct{}
+
+func (fw *failWriter) Write(p []byte) (n int, err error) {
+	return 0, fmt.Errorf("Error writing '%s'", string(p))
+}
+
+func TestBackends(t *testing.T) {
+
+	Convey("cmd backend writes an env.bat and aliases.bat", t, func() {
+		SetBuffers(nil)
+		So(write("cmd"), ShouldEqual, `@echo off
+set "PATH=C:\prgs\git\latest\bin;C:\Program Files\x"
+set "A=50%% & ^ $HOME 'q'"
+call "%~dp0aliases.bat"
+@echo off
+doskey gl=git lg -20
+doskey 7z="C:\p f\7z.exe" a^^^&b $$HOME $*
+doskey go=
+`)
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("doskey macros keep their special characters escaped once defined", func() {
+			So(doskeyArg("a&b"), ShouldEqual, "a^^^&b")
+			So(doskeyArg("x|y>z"), ShouldEqual, "x^^^|y^^^>z")
+			So(doskeyArg("50%"), ShouldEqual, "50%%")
+			So(doskeyArg("a & b"), ShouldEqual, `"a & b"`)
+			So(cmdBatchArg("a&b"), ShouldEqual, "a^&b")
+		})
+	})
+
+	Convey("powershell backend writes an env.ps1 and aliases.ps1", t, func() {
+		So(write("powershell"), ShouldEqual, `$env:PATH = @('C:\prgs\git\latest\bin', 'C:\Program Files\x') -join [IO.Path]::PathSeparator
+${env:A} = '50% & ^ $HOME ''q'''
+. (Join-Path $PSScriptRoot 'aliases.ps1')
+function global:gl { & 'git' 'lg' '-20' }
+function global:7z { & 'C:\p f\7z.exe' 'a&b' '$HOME' @args }
+Remove-Item -Path Function:\go, Alias:\go -ErrorAction SilentlyContinue
+`)
+	})
+
+	Convey("bash backend writes an env.sh and aliases.sh", t, func() {
+		So(write("bash"), ShouldEqual, `export PATH='/c/prgs/git/latest/bin:/c/Program Files/x'
+export A='50% & ^ $HOME '\''q'\'''
+. "$(dirname "${BASH_SOURCE[0]:-$0}")/aliases.sh"
+gl() { 'git' 'lg' '-20'; }
+7z() { 'C:\p f\7z.exe' 'a&b' '$HOME' "$@"; }
+unalias go 2>/dev/null; unset -f go 2>/dev/null
+true
+`)
+		So(unixPath("/usr/bin"), ShouldEqual, "/usr/bin")
+	})
+
+	Convey("fish backend writes an env.fish and aliases.fish", t, func() {
+		So(write("fish"), ShouldEqual, `set -gx PATH '/c/prgs/git/latest/bin' '/c/Program Files/x'
+set -gx A '50% & ^ $HOME \'q\''
+source (dirname (status --current-filename))/aliases.fish
+function gl; 'git' 'lg' '-20'; end
+function 7z; 'C:\\p f\\7z.exe' 'a&b' '$HOME' $argv; end
+functions -e go
+`)
+	})
+
+	Convey("A backend stops at the first write error", t, func() {
+		for _, name := range shellNames {
+			sh, _ := Get(name)
+			So(sh.Write(testEnv, &failWriter{}), ShouldNotBeNil)
+			So(sh.WriteAliases(testEnv.Aliases, &failWriter{}), ShouldNotBeNil)
+		}
+	})
+}
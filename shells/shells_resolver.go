@@ -0,0 +1,85 @@
+package shells
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+)
+
+// resolver resolves template references for a program installed under root,
+// the other programs it can reference being the installed ones.
+type resolver struct {
+	root *paths.Path
+	prg  prgs.Prg
+	prgs []prgs.Prg
+}
+
+var freadlink func(name string) (string, error)
+
+func ifreadlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+var fgetenv func(key string) string
+
+func (r *resolver) expand(value string) (string, error) {
+	t, err := prgs.ParseTemplate(value)
+	if err != nil {
+		return "", err
+	}
+	return t.Expand(r.resolve)
+}
+
+// resolve returns, for a program:
+//   - folderfull: its 'latest' folder, which stays valid after an update
+//   - folder: the name of the folder 'latest' links to
+//   - version: the version within that folder name (see prgs.VersionOf)
+func (r *resolver) resolve(ref *prgs.Ref) (string, error) {
+	if ref.Env != "" {
+		if ref.Env == envs.Prgsenvname {
+			return r.root.NoSep().String(), nil
+		}
+		v := fgetenv(ref.Env)
+		if v == "" {
+			return "", fmt.Errorf("undefined environment variable '%s'", ref.Env)
+		}
+		return v, nil
+	}
+	p := r.prg
+	if ref.Prg != "" {
+		p = nil
+		for _, prg := range r.prgs {
+			if prg.Name() == ref.Prg {
+				p = prg
+			}
+		}
+		if p == nil {
+			return "", fmt.Errorf("program '%s' is not installed", ref.Prg)
+		}
+	}
+	latest := Latest(r.root, p)
+	if ref.Var == "folderfull" {
+		return latest.NoSep().String(), nil
+	}
+	target, err := freadlink(latest.NoSep().String())
+	if err != nil {
+		return "", fmt.Errorf("no folder for '%s': %v", p.Name(), err)
+	}
+	folder := paths.NewPath(target).Base()
+	if ref.Var == "folder" {
+		return folder, nil
+	}
+	version := prgs.VersionOf(p, folder)
+	if version == nil {
+		return "", fmt.Errorf("no version in folder '%s' of '%s'", folder, p.Name())
+	}
+	return version.String(), nil
+}
+
+func init() {
+	freadlink = ifreadlink
+	fgetenv = os.Getenv
+}
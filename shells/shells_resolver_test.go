@@ -0,0 +1,79 @@
+package shells
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testfreadlink(name string) (string, error) {
+	if paths.NewPath(name).Dir().Base() == "jdk8" {
+		return paths.NewPath("/prgs/jdk8/jdk1.8.0_40").String(), nil
+	}
+	if paths.NewPath(name).Dir().Base() == "ads" {
+		return paths.NewPath("/prgs/ads/ads").String(), nil
+	}
+	return "", fmt.Errorf("not a link")
+}
+
+func testfgetenv(key string) string {
+	if key == "PROG" {
+		return "/prog"
+	}
+	return ""
+}
+
+func TestResolver(t *testing.T) {
+
+	root := paths.NewPathDir("/prgs")
+	jdk8 := &testPrg{name: "jdk8"}
+	ads := &testPrg{name: "ads"}
+	goprg := &testPrg{name: "go"}
+	r := &resolver{root: root, prg: goprg, prgs: []prgs.Prg{goprg, jdk8, ads}}
+
+	Convey("Template references are resolved against installed programs", t, func() {
+		SetBuffers(nil)
+		freadlink = testfreadlink
+		fgetenv = testfgetenv
+
+		v, err := r.expand(`_folderfull_|%PRGS2%|%PROG%`)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, paths.NewPath("/prgs/go/latest").String()+"|"+root.NoSep().String()+"|/prog")
+		v, err = r.expand(`_jdk8:folder_ _jdk8:version_`)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, "jdk1.8.0_40 1.8.0_40")
+		jdk8.values = map[string]string{"version.rx": `jdk1\.(\d+)`}
+		v, _ = r.expand(`_jdk8:version_`)
+		So(v, ShouldEqual, "8")
+		jdk8.values = nil
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("Unresolved references are errors", func() {
+			_, err = r.expand(`%UNSET%`)
+			So(err.Error(), ShouldEqual, "unable to resolve '%UNSET%' in '%UNSET%': undefined environment variable 'UNSET'")
+			_, err = r.expand(`_node:folderfull_`)
+			So(err.Error(), ShouldEndWith, "program 'node' is not installed")
+			_, err = r.expand(`_folder_`)
+			So(err.Error(), ShouldEndWith, "no folder for 'go': not a link")
+			_, err = r.expand(`_ads:version_`)
+			So(err.Error(), ShouldEndWith, "no version in folder 'ads' of 'ads'")
+			_, err = r.expand(`%`)
+			So(err.Error(), ShouldEqual, "unterminated '%' in '%'")
+		})
+
+		Convey("NewEnv reports env values which cannot be resolved", func() {
+			bad := &testPrg{name: "bad", envs: []*prgs.Setting{{Name: "X", Value: "%UNSET%"}}}
+			env, err := NewEnv([]prgs.Prg{bad}, root, nil)
+			So(env, ShouldBeNil)
+			So(err.Error(), ShouldStartWith, "prg 'bad': env 'X': unable to resolve")
+		})
+
+		freadlink = ifreadlink
+		fgetenv = os.Getenv
+	})
+}
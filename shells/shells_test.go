@@ -0,0 +1,130 @@
+package shells
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/envs"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testPrg struct {
+	prgs.Prg
+	name    string
+	path    string
+	envs    []*prgs.Setting
+	doskeys []*prgs.Setting
+	addbins []*prgs.Setting
+	values  map[string]string
+}
+
+func (tp *testPrg) Name() string             { return tp.name }
+func (tp *testPrg) Dir() string              { return tp.name }
+func (tp *testPrg) Path() string             { return tp.path }
+func (tp *testPrg) Envs() []*prgs.Setting    { return tp.envs }
+func (tp *testPrg) Doskeys() []*prgs.Setting { return tp.doskeys }
+func (tp *testPrg) Addbins() []*prgs.Setting { return tp.addbins }
+func (tp *testPrg) Value(key string) string  { return tp.values[key] }
+
+func TestShells(t *testing.T) {
+
+	Convey("Shells can be selected by name", t, func() {
+		SetBuffers(nil)
+		sh, err := Get("PowerShell")
+		So(err, ShouldBeNil)
+		So(sh.Name(), ShouldEqual, "powershell")
+		sh, err = Get("zsh")
+		So(err, ShouldBeNil)
+		So(sh.Name(), ShouldEqual, "bash")
+		sh, err = Get("tcsh")
+		So(sh, ShouldBeNil)
+		So(err.Error(), ShouldEqual, "unknown shell 'tcsh' (expected one of cmd, powershell, bash, fish)")
+
+		Convey("A list of shells can be parsed, without duplicates", func() {
+			shs, err := Parse("cmd, ps,pwsh bash")
+			So(err, ShouldBeNil)
+			So(len(shs), ShouldEqual, 3)
+			So(shs[1].Name(), ShouldEqual, "powershell")
+			shs, err = Parse("cmd,csh")
+			So(shs, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+			So(NoOutput(), ShouldBeTrue)
+		})
+	})
+
+	Convey("An Env is computed from programs", t, func() {
+		SetBuffers(nil)
+		root := paths.NewPathDir("/prgs")
+		git := &testPrg{name: "git", path: "bin", doskeys: []*prgs.Setting{{Name: "gl", Value: "git lg -20"}}}
+		peazip := &testPrg{name: "peazip", doskeys: []*prgs.Setting{{Name: "7z", Value: `~res\7z\7z.exe $*`}}}
+		goprg := &testPrg{name: "go", path: "bin", envs: []*prgs.Setting{{Name: "GOROOT", Value: "_folderfull_"}},
+			doskeys: []*prgs.Setting{{Name: "go", Value: ""}}}
+		env, err := NewEnv([]prgs.Prg{git, peazip, goprg}, root, envs.NewPathList("/usr/bin", "", "/usr/bin"))
+		So(err, ShouldBeNil)
+		So(env.Path, ShouldResemble, []string{"/usr/bin", paths.NewPath("/prgs/git/latest/bin").String(), paths.NewPath("/prgs/go/latest/bin").String()})
+		So(len(env.Vars), ShouldEqual, 1)
+		So(env.Vars[0].Value, ShouldEqual, paths.NewPath("/prgs/go/latest").String())
+		So(len(env.Aliases), ShouldEqual, 3)
+		So(env.Aliases[0].Args, ShouldResemble, []string{"git", "lg", "-20"})
+		So(env.Aliases[1].Args, ShouldResemble, []string{paths.NewPath("/prgs/peazip/latest/res/7z/7z.exe").String(), ArgsAll})
+		So(env.Aliases[2].Args, ShouldBeEmpty)
+		So(NoOutput(), ShouldBeTrue)
+
+		Convey("Two programs defining the same alias is an error", func() {
+			npm := &testPrg{name: "npm", doskeys: []*prgs.Setting{{Name: "GL", Value: ""}}}
+			env, err := NewEnv([]prgs.Prg{git, npm}, root, nil)
+			So(env, ShouldBeNil)
+			So(err.Error(), ShouldEqual, "alias 'GL' claimed by both 'git' and 'npm'")
+		})
+	})
+
+	Convey("Scripts are written per shell", t, func() {
+		SetBuffers(nil)
+		dir := paths.NewPathDir("testscripts")
+		So(dir.MkdirAll(), ShouldBeTrue)
+		defer os.RemoveAll("testscripts")
+		shs, _ := Parse("cmd,fish")
+		env := &Env{Path: []string{"a"}}
+		err := WriteScripts(env, dir, shs)
+		So(err, ShouldBeNil)
+		So(dir.Add("env.bat").FileContent(), ShouldEqual, "@echo off\nset \"PATH=a\"\ncall \"%~dp0aliases.bat\"\n")
+		So(dir.Add("aliases.bat").FileContent(), ShouldEqual, "@echo off\n")
+		So(dir.Add("env.fish").FileContent(), ShouldEqual, "set -gx PATH 'a'\nsource (dirname (status --current-filename))/aliases.fish\n")
+		So(dir.Add("aliases.fish").Exists(), ShouldBeTrue)
+
+		Convey("Script writing can fail", func() {
+			foscreate = testfoscreate
+			err := WriteScripts(env, dir, shs)
+			So(err.Error(), ShouldEndWith, "env.bat': 'Error (create) for env.bat'")
+			foscreate = ifoscreate
+			shs = []Shell{&failShell{}}
+			err = WriteScripts(env, dir, shs)
+			So(err.Error(), ShouldEndWith, "env.fail': 'write failure'")
+			shs = []Shell{&failAliasesShell{}}
+			err = WriteScripts(env, dir, shs)
+			So(err.Error(), ShouldEndWith, "aliases.fail': 'write aliases failure'")
+			So(NoOutput(), ShouldBeTrue)
+		})
+	})
+}
+
+func testfoscreate(file *paths.Path) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("Error (create) for %s", file.Base())
+}
+
+type failShell struct{}
+type failAliasesShell struct{ failShell }
+
+func (f *failAliasesShell) Write(env *Env, w io.Writer) error { return nil }
+
+func (f *failShell) Name() string                      { return "fail" }
+func (f *failShell) Ext() string                       { return ".fail" }
+func (f *failShell) Write(env *Env, w io.Writer) error { return fmt.Errorf("write failure") }
+func (f *failShell) WriteAliases(aliases []*Alias, w io.Writer) error {
+	return fmt.Errorf("write aliases failure")
+}
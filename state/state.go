@@ -0,0 +1,102 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/VonC/senvgo/paths"
+)
+
+// FileName is the name of the state file, in %PRGS2%
+const FileName = "senvgo.state.json"
+
+// Entry is what is recorded for an installed program
+type Entry struct {
+	// Name is the program name
+	Name string `json:"name"`
+	// Folder is the version folder, under the program folder, 'latest' links to
+	Folder string `json:"folder"`
+	// Version is the installed version, if known
+	Version string `json:"version,omitempty"`
+	// Archive is the cached archive it was installed from, if known
+	Archive string `json:"archive,omitempty"`
+	// Installed is when the program was installed
+	Installed time.Time `json:"installed"`
+}
+
+// Store records which programs are installed, in %PRGS2%/senvgo.state.json
+type Store struct {
+	file    *paths.Path
+	entries map[string]*Entry
+}
+
+// Load reads the state of a root folder.
+// A root without state file has an empty state.
+func Load(root *paths.Path) (*Store, error) {
+	s := &Store{file: root.Add(FileName), entries: make(map[string]*Entry)}
+	data, err := s.file.Content()
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read state '%v': %v", s.file, err)
+	}
+	entries := []*Entry{}
+	if err = json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, fmt.Errorf("invalid state '%v': %v", s.file, err)
+	}
+	for _, e := range entries {
+		s.entries[e.Name] = e
+	}
+	return s, nil
+}
+
+// Get returns the entry of a program, nil if not installed
+func (s *Store) Get(name string) *Entry {
+	return s.entries[name]
+}
+
+// Set records an installed program, replacing any previous entry
+func (s *Store) Set(e *Entry) {
+	s.entries[e.Name] = e
+}
+
+// Remove forgets an installed program
+func (s *Store) Remove(name string) {
+	delete(s.entries, name)
+}
+
+// Names returns the names of all installed programs, sorted
+func (s *Store) Names() []string {
+	res := []string{}
+	for name := range s.entries {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Save writes the state, through a temporary file renamed once complete,
+// in order to never leave a partial state.
+func (s *Store) Save() error {
+	entries := []*Entry{}
+	for _, name := range s.Names() {
+		entries = append(entries, s.entries[name])
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := paths.NewPathFS(s.file.FS(), s.file.String()+".tmp")
+	if err = tmp.WriteFile(append(data, '\n')); err != nil {
+		return fmt.Errorf("unable to write state '%v': %v", s.file, err)
+	}
+	if err = tmp.Rename(s.file); err != nil {
+		return fmt.Errorf("unable to write state '%v': %v", s.file, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestState(t *testing.T) {
+
+	fs := paths.NewMemFS()
+	root := paths.NewPathFS(fs, filepath.FromSlash("/prgs/"))
+	root.Mkdir()
+
+	Convey("A root without state file has an empty state", t, func() {
+		SetBuffers(nil)
+		s, err := Load(root)
+		So(err, ShouldBeNil)
+		So(s.Names(), ShouldBeEmpty)
+		So(s.Get("go"), ShouldBeNil)
+
+		Convey("A state can be saved and loaded back", func() {
+			installed := time.Date(2015, 3, 8, 10, 0, 0, 0, time.UTC)
+			s.Set(&Entry{Name: "go", Folder: "go1.4.2", Version: "1.4.2", Installed: installed})
+			s.Set(&Entry{Name: "git", Folder: "PortableGit-1.9.5"})
+			So(s.Save(), ShouldBeNil)
+			s, err = Load(root)
+			So(err, ShouldBeNil)
+			So(s.Names(), ShouldResemble, []string{"git", "go"})
+			So(s.Get("go").Folder, ShouldEqual, "go1.4.2")
+			So(s.Get("go").Installed.Equal(installed), ShouldBeTrue)
+
+			s.Remove("go")
+			So(s.Save(), ShouldBeNil)
+			s, _ = Load(root)
+			So(s.Names(), ShouldResemble, []string{"git"})
+			So(root.Add(FileName+".tmp").Exists(), ShouldBeFalse)
+			So(NoOutput(), ShouldBeTrue)
+		})
+
+		Convey("State errors are reported", func() {
+			tmp := root.Add(FileName + ".tmp").String()
+			fs.Fail("write", tmp, fmt.Errorf("disk full"))
+			err := s.Save()
+			So(err.Error(), ShouldStartWith, "unable to write state '"+root.Add(FileName).String()+"'")
+			So(err.Error(), ShouldEndWith, ": disk full")
+			fs.Fail("write", tmp, nil)
+
+			root.Add(FileName).WriteFile([]byte("{"))
+			_, err = Load(root)
+			So(err.Error(), ShouldStartWith, "invalid state")
+			fs.Fail("open", root.Add(FileName).String(), fmt.Errorf("access denied"))
+			_, err = Load(root)
+			So(err.Error(), ShouldStartWith, "unable to read state")
+			fs.Fail("open", root.Add(FileName).String(), nil)
+			root.Add(FileName).Remove()
+		})
+	})
+}
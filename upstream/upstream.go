@@ -0,0 +1,209 @@
+package upstream
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/prgs"
+)
+
+// Release is the latest release of a program, as resolved upstream
+// by its extractor chain
+type Release struct {
+	// URL is the archive url
+	URL string
+	// Archive is the archive name
+	Archive string
+	// Folder is the version folder, empty without 'folder.' extractors
+	Folder string
+}
+
+// chain resolves the 'url', 'name' and 'folder' values of a program,
+// each from its extractors (see prgs.Prg Extractors)
+type chain struct {
+	p         prgs.Prg
+	c         *cache.Cache
+	steps     map[string][]*prgs.Setting
+	values    map[string]string
+	resolving map[string]bool
+	// pages are the pages read so far, by url
+	pages map[string]string
+	// matches are the 'rx' first groups, in order, for '_$1_', '_$2_', ...
+	matches []string
+}
+
+// is64 checks if the 64 bits 'arch' pattern applies
+var is64 = strings.HasSuffix(runtime.GOARCH, "64")
+
+// Resolve runs the extractor chain of a program: its 'url', 'name'
+// (the archive name) and 'folder' values are each resolved by their
+// extractors, in config order, starting from an empty value:
+//   - 'get' reads a page: an url, a 'page.<id>' of the program, or else
+//     the url the value is so far (like a download page link);
+//     '_url', '_name' and '_folder' get another value instead
+//   - 'rx' keeps the first group of its first match ('$<rx>': its last match)
+//   - 'prepend', 'append' add a string, 'replace' is '<rx> with <string>'
+//
+// Extractors can refer to the 'arch' of the program ('_$arch_', see Expand)
+// and to earlier 'rx' matches ('_$1_', '_$2_', ...).
+// Pages are read once, through the cache (see page).
+// Without 'name.' extractors, the archive name is the url base.
+func Resolve(p prgs.Prg, c *cache.Cache) (*Release, error) {
+	ch := &chain{p: p, c: c, steps: map[string][]*prgs.Setting{}, values: map[string]string{}, resolving: map[string]bool{}, pages: map[string]string{}}
+	for _, s := range p.Extractors() {
+		variable := s.Name[:strings.Index(s.Name, ".")]
+		ch.steps[variable] = append(ch.steps[variable], s)
+	}
+	if len(ch.steps) == 0 {
+		return nil, fmt.Errorf("no extractor for '%s'", p.Name())
+	}
+	res := &Release{}
+	var err error
+	if res.URL, err = ch.value("url"); err != nil {
+		return nil, err
+	}
+	if res.Archive, err = ch.value("name"); err != nil {
+		return nil, err
+	}
+	if res.Archive == "" && res.URL != "" {
+		res.Archive, _ = url.QueryUnescape(path.Base(res.URL))
+	}
+	if res.Archive == "" {
+		return nil, fmt.Errorf("no archive name extracted for '%s'", p.Name())
+	}
+	if res.Folder, err = ch.value("folder"); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Expand replaces, in a config value of a program, '_$arch_' by its 'arch'
+// ('<32 bits pattern>,<64 bits pattern>') for the current architecture.
+func Expand(p prgs.Prg, s string) string {
+	arch := strings.SplitN(p.Value("arch"), ",", 2)
+	if len(arch) < 2 || !strings.Contains(s, "_$arch_") {
+		return s
+	}
+	a := arch[0]
+	if is64 {
+		a = arch[1]
+	}
+	return strings.Replace(s, "_$arch_", strings.TrimSpace(a), -1)
+}
+
+func (ch *chain) expand(s string) string {
+	s = Expand(ch.p, s)
+	for i, m := range ch.matches {
+		s = strings.Replace(s, fmt.Sprintf("_$%d_", i+1), m, -1)
+	}
+	return s
+}
+
+// value resolves a value ('url', 'name' or 'folder') once.
+// A folder has no spaces ('_' instead).
+func (ch *chain) value(variable string) (string, error) {
+	if v, ok := ch.values[variable]; ok {
+		return v, nil
+	}
+	if ch.resolving[variable] {
+		return "", fmt.Errorf("'%s' of '%s' depends on itself", variable, ch.p.Name())
+	}
+	ch.resolving[variable] = true
+	defer delete(ch.resolving, variable)
+	data := ""
+	for _, s := range ch.steps[variable] {
+		var err error
+		switch s.Name[len(variable)+1:] {
+		case "get":
+			data, err = ch.get(s.Value, data)
+		case "rx":
+			data, err = ch.match(s.Value, data)
+		case "prepend":
+			data = ch.expand(s.Value) + data
+		case "append":
+			data = data + ch.expand(s.Value)
+		case "replace":
+			elts := strings.SplitN(s.Value, " with", 2)
+			var rx *regexp.Regexp
+			if rx, err = regexp.Compile(ch.expand(elts[0])); err == nil {
+				with := ""
+				if len(elts) == 2 {
+					with = strings.TrimSpace(elts[1])
+				}
+				data = rx.ReplaceAllString(data, with)
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s '%s' of '%s': %w", s.Name, s.Value, ch.p.Name(), err)
+		}
+	}
+	if variable == "folder" {
+		data = strings.Replace(data, " ", "_", -1)
+	}
+	ch.values[variable] = data
+	return data, nil
+}
+
+// get returns the content of a page, or another value ('_url', ...)
+func (ch *chain) get(id, data string) (string, error) {
+	switch id {
+	case "_url", "_name", "_folder":
+		v, err := ch.value(id[1:])
+		if err == nil && id == "_url" {
+			if u, uerr := url.QueryUnescape(v); uerr == nil {
+				v = u
+			}
+		}
+		return v, err
+	}
+	u := id
+	if !isURL(u) {
+		u = ch.p.Value("page." + id)
+	}
+	if u == "" && isURL(data) {
+		u = data
+	}
+	if u == "" {
+		return "", fmt.Errorf("no page '%s'", id)
+	}
+	return ch.page(ch.expand(u))
+}
+
+// match returns the first group of the first (or last) match of rx in data
+func (ch *chain) match(rx, data string) (string, error) {
+	last := strings.HasPrefix(rx, "$")
+	if last {
+		rx = rx[1:]
+	}
+	r, err := regexp.Compile(ch.expand(rx))
+	if err != nil {
+		return "", err
+	}
+	ms := r.FindAllStringSubmatch(data, -1)
+	if len(ms) == 0 || len(ms[0]) < 2 {
+		return "", fmt.Errorf("no match in '%s'", abbrev(data))
+	}
+	m := ms[0][1]
+	if last {
+		m = ms[len(ms)-1][1]
+	}
+	ch.matches = append(ch.matches, m)
+	return m, nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// abbrev shortens a (page) content for an error message
+func abbrev(s string) string {
+	if len(s) > 80 {
+		return fmt.Sprintf("%s... (%d bytes)", s[:60], len(s))
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package upstream
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/paths"
+)
+
+// Timeout is the time limit of an upstream request
+var Timeout = 30 * time.Second
+
+var fhttpget func(url, cookie string) ([]byte, error)
+
+// offline is set by the --offline flag: nothing is downloaded
+var offline bool
+
+// SetOffline sets whether pages and archives are only read from the cache
+// (--offline): no page nor archive is downloaded.
+func SetOffline(b bool) {
+	offline = b
+}
+
+// Offline checks if pages and archives are only read from the cache
+// (see SetOffline).
+func Offline() bool {
+	return offline
+}
+
+func init() {
+	fhttpget = httpget
+}
+
+// httpget downloads an url, with a cookie ('<name>;<value>') if not empty
+func httpget(url, cookie string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cookie != "" {
+		elts := strings.SplitN(cookie, ";", 2)
+		req.AddCookie(&http.Cookie{Name: elts[0], Value: elts[len(elts)-1]})
+	}
+	resp, err := (&http.Client{Timeout: Timeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET '%s': %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// page returns the content of an url page of the program: downloaded,
+// and cached (see cache.AddPage), or else its newest cached page.
+func (ch *chain) page(url string) (string, error) {
+	if content, ok := ch.pages[url]; ok {
+		return content, nil
+	}
+	content, err := ch.read(url)
+	if err == nil {
+		ch.pages[url] = content
+	}
+	return content, err
+}
+
+// read downloads a page, or returns its newest cached page if it cannot
+// be downloaded. Offline, it only returns its newest cached page.
+func (ch *chain) read(url string) (string, error) {
+	name := ch.p.Name()
+	if offline {
+		cached := ch.c.Page(name, url)
+		if cached == nil {
+			return "", fmt.Errorf("page '%s' (offline): %w", url, cache.ErrNotInCache)
+		}
+		return cached.Content()
+	}
+	data, err := fhttpget(url, ch.p.Value("cookie"))
+	if err == nil {
+		if _, cerr := ch.c.AddPage(name, url, data); cerr != nil {
+			godbg.Pdbgf("Unable to cache page '%s' of '%s': %v", url, name, cerr)
+		}
+		return string(data), nil
+	}
+	cached := ch.c.Page(name, url)
+	if cached == nil {
+		return "", fmt.Errorf("unable to get page '%s', and none in cache: %v", url, err)
+	}
+	godbg.Pdbgf("Unable to get page '%s' of '%s' (%v): using cached '%v'", url, name, err, cached)
+	return cached.Content()
+}
+
+// Download downloads an archive url in dst, with a cookie ('<name>;<value>')
+// if not empty, through a '<dst>.part' file: dst only exists once complete.
+// Offline, it fails with cache.ErrNotInCache.
+func Download(url, cookie string, dst *paths.Path) error {
+	if offline {
+		return fmt.Errorf("'%s' not downloaded (offline): %w", url, cache.ErrNotInCache)
+	}
+	data, err := fhttpget(url, cookie)
+	if err != nil {
+		return err
+	}
+	dir := dst.Dir()
+	if err = dir.Mkdir(); err != nil {
+		return err
+	}
+	part := dir.Add(dst.Base() + ".part")
+	if err = part.WriteFile(data); err != nil {
+		part.Remove()
+		return err
+	}
+	return part.Rename(dst)
+}
@@ -0,0 +1,63 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/paths"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPages(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		c, err := r.Cookie("oraclelicense")
+		if err != nil {
+			fmt.Fprint(w, "no cookie")
+			return
+		}
+		fmt.Fprint(w, "cookie "+c.Value)
+	}))
+	defer ts.Close()
+
+	Convey("Pages are downloaded, with the program cookie", t, func() {
+		SetBuffers(nil)
+		data, err := httpget(ts.URL+"/page", "")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "no cookie")
+		data, _ = httpget(ts.URL+"/page", "oraclelicense;accept-securebackup-cookie")
+		So(string(data), ShouldEqual, "cookie accept-securebackup-cookie")
+		_, err = httpget(ts.URL+"/missing", "")
+		So(err.Error(), ShouldEqual, "GET '"+ts.URL+"/missing': 404 Not Found")
+	})
+
+	Convey("Archives are downloaded, unless offline", t, func() {
+		SetBuffers(nil)
+		fs := paths.NewMemFS()
+		dst := paths.NewPathFS(fs, filepath.FromSlash("/prgs/cache/jdk8/jdk-8u40-windows-x64.exe"))
+		So(Download(ts.URL+"/jdk", "oraclelicense;accept-securebackup-cookie", dst), ShouldBeNil)
+		So(dst.FileContent(), ShouldEqual, "cookie accept-securebackup-cookie")
+		So(dst.Dir().Add("jdk-8u40-windows-x64.exe.part").Exists(), ShouldBeFalse)
+
+		missing := dst.Dir().Add("jdk-8u31-windows-x64.exe")
+		So(Download(ts.URL+"/missing", "", missing), ShouldNotBeNil)
+		So(missing.Exists(), ShouldBeFalse)
+
+		SetOffline(true)
+		defer SetOffline(false)
+		So(Offline(), ShouldBeTrue)
+		err := Download(ts.URL+"/jdk", "", missing)
+		So(errors.Is(err, cache.ErrNotInCache), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "'"+ts.URL+"/jdk' not downloaded (offline): not in cache")
+	})
+}
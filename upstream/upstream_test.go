@@ -0,0 +1,197 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	"github.com/VonC/senvgo/cache"
+	"github.com/VonC/senvgo/paths"
+	"github.com/VonC/senvgo/prgs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testPrg struct {
+	prgs.Prg
+	name       string
+	values     map[string]string
+	extractors []*prgs.Setting
+}
+
+func (tp *testPrg) Name() string                { return tp.name }
+func (tp *testPrg) Value(key string) string     { return tp.values[key] }
+func (tp *testPrg) Extractors() []*prgs.Setting { return tp.extractors }
+
+// testConfig returns a program from its config keys, one per line
+func testConfig(name, config string) *testPrg {
+	p := &testPrg{name: name, values: map[string]string{}}
+	for _, line := range strings.Split(strings.TrimSpace(config), "\n") {
+		kv := strings.Fields(line)
+		key, value := kv[0], strings.Join(kv[1:], " ")
+		if strings.HasPrefix(key, "url.") || strings.HasPrefix(key, "name.") || strings.HasPrefix(key, "folder.") {
+			p.extractors = append(p.extractors, &prgs.Setting{Name: key, Value: value})
+		} else if _, ok := p.values[key]; !ok {
+			p.values[key] = value
+		}
+	}
+	return p
+}
+
+var pages map[string]string
+var gets []string
+
+func testhttpget(url, cookie string) ([]byte, error) {
+	gets = append(gets, url+" "+cookie)
+	if page, ok := pages[url]; ok {
+		return []byte(page), nil
+	}
+	return nil, fmt.Errorf("no such host")
+}
+
+const gitConfig = `
+page.rel    https://github.com/msysgit/msysgit/releases
+url.get     rel
+url.rx      (/msysgit/msysgit/releases/download/Git-.*?/PortableGit-.*?.7z)
+url.prepend https://github.com
+name.get    _url
+name.rx     /download/Git-.*?/(PortableGit-.*?.7z)
+folder.get  _name
+folder.rx   (PortableGit-.*?).7z
+`
+
+const jdkConfig = `
+arch        i586,x64
+page.src    http://www.oracle.com/technetwork/java/javase/downloads/index.html
+folder.get  src
+folder.rx   >(Java SE 8(?:u\d*)?)<
+url.get     src
+url.rx      href="(/technetwork/java/javase/downloads/jdk8-downloads-\d+.html)"
+url.prepend http://www.oracle.com
+url.get     dwnl
+url.rx      $(http://download.oracle.com/[^"]+jdk-\d(?:u\d+)?-windows-_$arch_.exe)
+url.replace ^http://download with http://edelivery
+name.get    _url
+name.rx     (jdk-\d(?:u\d+)?-windows-_$arch_.exe)
+cookie      oraclelicense;accept-securebackup-cookie
+`
+
+func TestResolve(t *testing.T) {
+
+	fhttpget = testhttpget
+	defer func() { fhttpget = httpget }()
+
+	Convey("The url, archive and folder of a release are extracted from its pages", t, func() {
+		SetBuffers(nil)
+		c := cache.Default(paths.NewPathFS(paths.NewMemFS(), filepath.FromSlash("/prgs/")))
+		gets = nil
+		pages = map[string]string{
+			"https://github.com/msysgit/msysgit/releases": `<a href="/msysgit/msysgit/releases/download/Git-1.9.5-preview20150319/PortableGit-1.9.5-preview20150319.7z">`,
+		}
+		r, err := Resolve(testConfig("git", gitConfig), c)
+		So(err, ShouldBeNil)
+		So(r, ShouldResemble, &Release{
+			URL:     "https://github.com/msysgit/msysgit/releases/download/Git-1.9.5-preview20150319/PortableGit-1.9.5-preview20150319.7z",
+			Archive: "PortableGit-1.9.5-preview20150319.7z",
+			Folder:  "PortableGit-1.9.5-preview20150319",
+		})
+		So(gets, ShouldResemble, []string{"https://github.com/msysgit/msysgit/releases "})
+		So(c.Page("git", "https://github.com/msysgit/msysgit/releases").FileContent(), ShouldStartWith, `<a href="/msysgit`)
+
+		Convey("A page which cannot be downloaded is read from the cache", func() {
+			pages = map[string]string{}
+			r, err := Resolve(testConfig("git", gitConfig), c)
+			So(err, ShouldBeNil)
+			So(r.Archive, ShouldEqual, "PortableGit-1.9.5-preview20150319.7z")
+			So(ErrString(), ShouldContainSubstring, "no such host")
+
+			_, err = Resolve(testConfig("gow", strings.Replace(gitConfig, "msysgit/msysgit", "bmatzelle/gow", 1)), c)
+			So(err.Error(), ShouldEqual, "url.get 'rel' of 'gow': unable to get page 'https://github.com/bmatzelle/gow/releases', and none in cache: no such host")
+		})
+
+		Convey("Offline, pages are only read from the cache", func() {
+			SetOffline(true)
+			defer SetOffline(false)
+			gets = nil
+			r, err := Resolve(testConfig("git", gitConfig), c)
+			So(err, ShouldBeNil)
+			So(r.Archive, ShouldEqual, "PortableGit-1.9.5-preview20150319.7z")
+			So(gets, ShouldBeEmpty)
+
+			_, err = Resolve(testConfig("gow", strings.Replace(gitConfig, "msysgit/msysgit", "bmatzelle/gow", 1)), c)
+			So(errors.Is(err, cache.ErrNotInCache), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "url.get 'rel' of 'gow': page 'https://github.com/bmatzelle/gow/releases' (offline): not in cache")
+		})
+	})
+
+	Convey("Pages can be chained, with the arch, the last match and a replace", t, func() {
+		SetBuffers(nil)
+		c := cache.Default(paths.NewPathFS(paths.NewMemFS(), filepath.FromSlash("/prgs/")))
+		gets = nil
+		pages = map[string]string{
+			"http://www.oracle.com/technetwork/java/javase/downloads/index.html": `<a href="/technetwork/java/javase/downloads/jdk8-downloads-2133151.html">Java SE 8u40</a>`,
+			"http://www.oracle.com/technetwork/java/javase/downloads/jdk8-downloads-2133151.html": `
+"http://download.oracle.com/otn-pub/java/jdk/8u31-b13/jdk-8u31-windows-x64.exe"
+"http://download.oracle.com/otn-pub/java/jdk/8u40-b26/jdk-8u40-windows-i586.exe"
+"http://download.oracle.com/otn-pub/java/jdk/8u40-b26/jdk-8u40-windows-x64.exe"`,
+		}
+		defer func(b bool) { is64 = b }(is64)
+		is64 = true
+		r, err := Resolve(testConfig("jdk8", jdkConfig), c)
+		So(err, ShouldBeNil)
+		So(r, ShouldResemble, &Release{
+			URL:     "http://edelivery.oracle.com/otn-pub/java/jdk/8u40-b26/jdk-8u40-windows-x64.exe",
+			Archive: "jdk-8u40-windows-x64.exe",
+			Folder:  "Java_SE_8u40",
+		})
+		So(len(gets), ShouldEqual, 2)
+		So(gets[1], ShouldEndWith, "jdk8-downloads-2133151.html oraclelicense;accept-securebackup-cookie")
+
+		is64 = false
+		r, _ = Resolve(testConfig("jdk8", jdkConfig), c)
+		So(r.Archive, ShouldEqual, "jdk-8u40-windows-i586.exe")
+		So(Expand(testConfig("jdk8", jdkConfig), "jdk-_$arch_"), ShouldEqual, "jdk-i586")
+		So(Expand(testConfig("git", gitConfig), "git-_$arch_"), ShouldEqual, "git-_$arch_")
+	})
+
+	Convey("Names can be built from earlier matches", t, func() {
+		SetBuffers(nil)
+		c := cache.Default(paths.NewPathFS(paths.NewMemFS(), filepath.FromSlash("/prgs/")))
+		pages = map[string]string{"http://www.autoitscript.com/site/autoit/downloads/": `<a href="/cgi-bin/getfile.pl?autoit3/autoit-v3.zip">Latest version: v3.3.12.0</a>`}
+		p := testConfig("autoit", `
+page.autoit     http://www.autoitscript.com/site/autoit/downloads/
+url.get         autoit
+url.rx          f="(/cgi-bin/getfile.pl\?autoit3/autoit-v3.zip)">
+url.prepend     http://www.autoitscript.com
+name.get        autoit
+name.rx         Latest version: (v\d.*?)<
+name.replace    [.] with -
+name.prepend    Autoit_
+name.append     .zip
+folder.get      _url
+folder.rx       autoit(\d)
+folder.append   _$2_
+`)
+		r, err := Resolve(p, c)
+		So(err, ShouldBeNil)
+		So(r, ShouldResemble, &Release{URL: "http://www.autoitscript.com/cgi-bin/getfile.pl?autoit3/autoit-v3.zip", Archive: "Autoit_v3-3-12-0.zip", Folder: "3v3.3.12.0"})
+
+		Convey("Extractors report what they cannot extract", func() {
+			p.extractors[1].Value = "(/none)"
+			_, err = Resolve(p, c)
+			So(err.Error(), ShouldStartWith, `url.rx '(/none)' of 'autoit': no match in '<a href="/cgi-bin/`)
+			So(err.Error(), ShouldEndWith, "... (81 bytes)'")
+
+			_, err = Resolve(testConfig("loop", "name.get _folder\nfolder.get _name"), c)
+			So(err.Error(), ShouldEqual, "name.get '_folder' of 'loop': folder.get '_name' of 'loop': 'name' of 'loop' depends on itself")
+			_, err = Resolve(testConfig("nopage", "url.get rel"), c)
+			So(err.Error(), ShouldEqual, "url.get 'rel' of 'nopage': no page 'rel'")
+			_, err = Resolve(testConfig("noname", "folder.prepend x"), c)
+			So(err.Error(), ShouldEqual, "no archive name extracted for 'noname'")
+			_, err = Resolve(&testPrg{name: "none"}, c)
+			So(err.Error(), ShouldEqual, "no extractor for 'none'")
+		})
+	})
+}
@@ -0,0 +1,121 @@
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a program version: numbers ('1.4.2', '8u40' as 8.40),
+// and an optional pre-release ('rc1', '-beta.2'), older than the release.
+type Version struct {
+	raw    string
+	nums   []int
+	pre    int
+	preNum int
+}
+
+// pre-release kinds, in order: a release (none) is newer than any pre-release
+var preKinds = map[string]int{
+	"dev": 1, "snapshot": 1,
+	"alpha": 2, "a": 2,
+	"beta": 3, "b": 3,
+	"pre": 4, "preview": 4,
+	"rc": 5,
+}
+
+const none = 6
+
+// pattern is numbers, a Java update ('8u40', '1.8.0_40'), a pre-release, and semver build metadata
+const pattern = `(\d+(?:\.\d+)*)(?:[u_](\d+))?(?:[-._]?(dev|snapshot|alpha|beta|preview|pre|rc|a|b)(?:[-._]?(\d+))?\b)?(?:\+[\w.-]*)?`
+
+var rxVersion = regexp.MustCompile(`(?i)` + pattern)
+var rxFull = regexp.MustCompile(`(?i)^v?` + pattern + `$`)
+
+// Parse parses a version: semver ('1.2.3-rc.1+build'), dotted numbers
+// ('2.7.6', 'v0.12'), Java updates ('8u40', '1.8.0_40'), with an optional
+// pre-release ('go1.5rc1' as '1.5rc1', '1.0-beta2').
+func Parse(s string) (*Version, error) {
+	m := rxFull.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, fmt.Errorf("invalid version '%s'", s)
+	}
+	return newVersion(strings.TrimSpace(s), m), nil
+}
+
+// Find returns the first version within a name (like an archive name:
+// 'go1.4.2.windows-amd64.zip' has the version '1.4.2'), nil if there is none.
+func Find(name string) *Version {
+	m := rxVersion.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	return newVersion(m[0], m)
+}
+
+func newVersion(raw string, m []string) *Version {
+	v := &Version{raw: raw, pre: none}
+	for _, n := range strings.Split(m[1], ".") {
+		i, _ := strconv.Atoi(n)
+		v.nums = append(v.nums, i)
+	}
+	if m[2] != "" {
+		u, _ := strconv.Atoi(m[2])
+		v.nums = append(v.nums, u)
+	}
+	if m[3] != "" {
+		v.pre = preKinds[strings.ToLower(m[3])]
+		v.preNum, _ = strconv.Atoi(m[4])
+	}
+	return v
+}
+
+func (v *Version) String() string {
+	return v.raw
+}
+
+// Compare returns -1, 0 or 1 if a is older, the same, or newer than b.
+// Missing numbers are zeros ('1.4' is '1.4.0').
+// A nil version is older than any version.
+func Compare(a, b *Version) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	for i := 0; i < len(a.nums) || i < len(b.nums); i++ {
+		if c := cmp(at(a.nums, i), at(b.nums, i)); c != 0 {
+			return c
+		}
+	}
+	if c := cmp(a.pre, b.pre); c != 0 {
+		return c
+	}
+	return cmp(a.preNum, b.preNum)
+}
+
+// Less checks if a version is older than another
+func (v *Version) Less(o *Version) bool {
+	return Compare(v, o) < 0
+}
+
+func at(nums []int, i int) int {
+	if i < len(nums) {
+		return nums[i]
+	}
+	return 0
+}
+
+func cmp(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package version
+
+import (
+	"sort"
+	"testing"
+
+	. "github.com/VonC/godbg"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func mustParse(s string) *Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestVersion(t *testing.T) {
+
+	Convey("Versions are parsed", t, func() {
+		SetBuffers(nil)
+		for _, s := range []string{"1", "1.4.2", "v0.12.2", "8u40", "1.5rc1", "1.0-beta2", "1.2.3-rc.1+build.5", "2.3.5.8-dev", "1.0.0-alpha"} {
+			v, err := Parse(s)
+			So(err, ShouldBeNil)
+			So(v.String(), ShouldEqual, s)
+		}
+		for _, s := range []string{"", "x", "1.", "1.4 beta", "go1.4"} {
+			_, err := Parse(s)
+			So(err.Error(), ShouldEqual, "invalid version '"+s+"'")
+		}
+		So(NoOutput(), ShouldBeTrue)
+	})
+
+	Convey("Versions are found in names", t, func() {
+		SetBuffers(nil)
+		for name, v := range map[string]string{
+			"go1.4.2.windows-amd64.zip":                "1.4.2",
+			"go1.5rc1.windows-amd64.zip":               "1.5rc1",
+			"jdk-8u40-windows-x64.exe":                 "8u40",
+			"jdk1.8.0_40":                              "1.8.0_40",
+			"python-2.7.6.amd64.msi":                   "2.7.6",
+			"PortableGit-2.3.5.8-dev-preview-32bit.7z": "2.3.5.8-dev",
+			"node-v0.12.2-x64.msi":                     "0.12.2",
+			"npp.6.7.Installer.zip":                    "6.7",
+		} {
+			So(Find(name).String(), ShouldEqual, v)
+		}
+		So(Find("README"), ShouldBeNil)
+	})
+
+	Convey("Versions are ordered by numbers, then pre-release", t, func() {
+		SetBuffers(nil)
+		So(mustParse("1.10").Less(mustParse("1.9")), ShouldBeFalse)
+		So(mustParse("1.9").Less(mustParse("1.10")), ShouldBeTrue)
+		So(Compare(mustParse("1.4"), mustParse("1.4.0")), ShouldEqual, 0)
+		So(Compare(mustParse("8u40"), mustParse("8u5")), ShouldEqual, 1)
+		So(Compare(mustParse("7u80"), mustParse("8u5")), ShouldEqual, -1)
+		So(Compare(mustParse("1.8.0_40"), mustParse("1.8.0_5")), ShouldEqual, 1)
+		So(Compare(nil, mustParse("0")), ShouldEqual, -1)
+		So(Compare(mustParse("0"), nil), ShouldEqual, 1)
+		So(Compare(nil, nil), ShouldEqual, 0)
+
+		ordered := []string{"1.4beta1", "1.4rc1", "1.4rc2", "1.4", "1.4.1", "1.5-dev", "1.5.0-alpha.1", "1.5.0-alpha.2", "1.5b1", "1.5", "1.10"}
+		vs := []*Version{}
+		for i := len(ordered) - 1; i >= 0; i-- {
+			vs = append(vs, mustParse(ordered[i]))
+		}
+		sort.Slice(vs, func(i, j int) bool { return vs[i].Less(vs[j]) })
+		res := []string{}
+		for _, v := range vs {
+			res = append(res, v.String())
+		}
+		So(res, ShouldResemble, ordered)
+	})
+}